@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/LINBIT/linstor-gateway/pkg/audit"
+)
+
+type AuditService struct {
+	client *Client
+}
+
+// List fetches the recent entries of the audit log, oldest first.
+func (s *AuditService) List(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	var entries []audit.Entry
+
+	query := url.Values{}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if filter.User != "" {
+		query.Set("user", filter.User)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	path := "/api/v2/audit"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	_, err := s.client.doGET(ctx, path, &entries)
+	return entries, err
+}
@@ -27,11 +27,16 @@ type Client struct {
 	baseURL    *url.URL
 	log        interface{} // must be either Logger, TestLogger, or LeveledLogger
 	userAgent  string
+	// authorization is the value of the Authorization header.
+	authorization string
 
 	Iscsi  *ISCSIService
 	Nfs    *NFSService
 	NvmeOf *NvmeOfService
 	Status *StatusService
+	Export *ExportService
+	Node   *NodeService
+	Audit  *AuditService
 }
 
 type clientError string
@@ -92,6 +97,9 @@ func NewClient(options ...Option) (*Client, error) {
 	c.Nfs = &NFSService{c}
 	c.NvmeOf = &NvmeOfService{c}
 	c.Status = &StatusService{c}
+	c.Export = &ExportService{c}
+	c.Node = &NodeService{c}
+	c.Audit = &AuditService{c}
 	return c, nil
 }
 
@@ -144,6 +152,9 @@ func (c *Client) newRequest(method, path string, body interface{}) (*http.Reques
 		req.Header.Set("User-Agent", c.userAgent)
 	}
 	req.Header.Set("Accept", "application/json")
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
 
 	return req, nil
 }
@@ -157,6 +168,12 @@ func (c *Client) curlify(req *http.Request) (string, error) {
 }
 
 func (c *Client) logCurlify(req *http.Request) {
+	// keep credentials out of the log
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", "REDACTED")
+		defer req.Header.Set("Authorization", auth)
+	}
+
 	var msg string
 	if curl, err := c.curlify(req); err != nil {
 		msg = err.Error()
@@ -183,20 +200,8 @@ func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*htt
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-		msg := fmt.Sprintf("Status code not within 200 to 400, but %d (%s)",
-			resp.StatusCode, http.StatusText(resp.StatusCode))
-		c.logf(LevelDebug, "%s", msg)
-		if resp.StatusCode == 404 {
-			return nil, NotFoundError
-		}
-
-		var e rest.Error
-		err = json.NewDecoder(resp.Body).Decode(&e)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode error response: %w", err)
-		}
-		return nil, e
+	if err := c.checkResponse(resp); err != nil {
+		return nil, err
 	}
 
 	if v != nil {
@@ -205,6 +210,27 @@ func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*htt
 	return resp, err
 }
 
+// checkResponse returns the error reported by the server, if any.
+func (c *Client) checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("Status code not within 200 to 400, but %d (%s)",
+		resp.StatusCode, http.StatusText(resp.StatusCode))
+	c.logf(LevelDebug, "%s", msg)
+	if resp.StatusCode == 404 {
+		return NotFoundError
+	}
+
+	var e rest.Error
+	err := json.NewDecoder(resp.Body).Decode(&e)
+	if err != nil {
+		return fmt.Errorf("failed to decode error response: %w", err)
+	}
+	return e
+}
+
 func (c *Client) doGET(ctx context.Context, url string, ret interface{}) (*http.Response, error) {
 	req, err := c.newRequest("GET", url, nil)
 	if err != nil {
@@ -231,6 +257,15 @@ func (c *Client) doPUT(ctx context.Context, url string, body interface{}, ret in
 	return c.do(ctx, req, &ret)
 }
 
+func (c *Client) doPATCH(ctx context.Context, url string, body interface{}, ret interface{}) (*http.Response, error) {
+	req, err := c.newRequest("PATCH", url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(ctx, req, &ret)
+}
+
 func (c *Client) doDELETE(ctx context.Context, url string, body interface{}) (*http.Response, error) {
 	req, err := c.newRequest("DELETE", url, body)
 	if err != nil {
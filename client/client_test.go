@@ -3,14 +3,20 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/events"
 )
 
 func parseURL(str string) *url.URL {
@@ -165,3 +171,93 @@ func TestDo(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthorization(t *testing.T) {
+	t.Parallel()
+
+	cli, err := NewClient(BearerToken("secret"), Log(t))
+	require.NoError(t, err)
+	req, err := cli.newRequest("GET", "/test", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+
+	cli, err = NewClient(BasicAuth("alice", "secret"), Log(t))
+	require.NoError(t, err)
+	req, err = cli.newRequest("GET", "/test", nil)
+	require.NoError(t, err)
+	user, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "alice", user)
+	assert.Equal(t, "secret", password)
+}
+
+func TestCACertificates(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(testData{A: "tls"})
+	}))
+	defer server.Close()
+
+	base, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	// the certificate of the test server is not trusted by default
+	cli, err := NewClient(BaseURL(base), Log(t))
+	require.NoError(t, err)
+	req, err := cli.newRequest("GET", "/", nil)
+	require.NoError(t, err)
+	_, err = cli.do(context.Background(), req, nil)
+	require.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	require.NoError(t, err)
+
+	cli, err = NewClient(BaseURL(base), Log(t), CACertificates(caFile))
+	require.NoError(t, err)
+	req, err = cli.newRequest("GET", "/", nil)
+	require.NoError(t, err)
+	var got testData
+	_, err = cli.do(context.Background(), req, &got)
+	require.NoError(t, err)
+	assert.Equal(t, "tls", got.A)
+
+	_, err = NewClient(CACertificates(filepath.Join(t.TempDir(), "missing.crt")))
+	assert.Error(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/events", r.URL.Path)
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, ": keepalive\n\n")
+		_, _ = fmt.Fprint(w, "id: 1\nevent: resource-created\ndata: {\"id\":1,\"type\":\"resource-created\",\"kind\":\"nfs\",\"name\":\"export\"}\n\n")
+		_, _ = fmt.Fprint(w, "id: 2\nevent: resource-deleted\ndata: {\"id\":2,\"type\":\"resource-deleted\",\"kind\":\"nfs\",\"name\":\"export\"}\n\n")
+	}))
+	defer server.Close()
+
+	base, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cli, err := NewClient(BaseURL(base), Log(t))
+	require.NoError(t, err)
+
+	ch, err := cli.Watch(context.Background())
+	require.NoError(t, err)
+
+	var got []events.Event
+	for event := range ch {
+		got = append(got, event)
+	}
+
+	require.Len(t, got, 2)
+	assert.Equal(t, events.ResourceCreated, got[0].Type)
+	assert.Equal(t, "export", got[0].Name)
+	assert.Equal(t, uint64(2), got[1].ID)
+	assert.Equal(t, events.ResourceDeleted, got[1].Type)
+}
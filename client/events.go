@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/LINBIT/linstor-gateway/pkg/events"
+)
+
+// Watch subscribes to the changes of all gateway resources. The returned
+// channel is closed when ctx is cancelled or the connection to the server
+// is lost; check ctx.Err() to tell the two apart.
+func (c *Client) Watch(ctx context.Context) (<-chan events.Event, error) {
+	req, err := c.newRequest("GET", "/api/v2/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req = req.WithContext(ctx)
+
+	c.logCurlify(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		return nil, err
+	}
+
+	if err := c.checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	ch := make(chan events.Event)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		var data bytes.Buffer
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+
+			// an empty line ends the event, everything except "data"
+			// fields is also contained in the JSON payload
+			if len(line) > 0 {
+				if value, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+					if data.Len() > 0 {
+						data.WriteByte('\n')
+					}
+					data.Write(bytes.TrimPrefix(value, []byte(" ")))
+				}
+				continue
+			}
+
+			if data.Len() == 0 {
+				continue
+			}
+
+			var event events.Event
+			err := json.Unmarshal(data.Bytes(), &event)
+			data.Reset()
+			if err != nil {
+				c.logf(LevelWarn, "failed to decode event: %v", err)
+				continue
+			}
+
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			c.logf(LevelWarn, "event stream interrupted: %v", err)
+		}
+	}()
+
+	return ch, nil
+}
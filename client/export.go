@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+
+	"github.com/LINBIT/linstor-gateway/pkg/manifest"
+)
+
+type ExportService struct {
+	client *Client
+}
+
+// Get fetches an archive of all gateway resources. CHAP secrets of iSCSI
+// targets and DH-HMAC-CHAP keys of NVMe-oF targets are only included if
+// showSecrets is set.
+func (s *ExportService) Get(ctx context.Context, showSecrets bool) (*manifest.Archive, error) {
+	var archive *manifest.Archive
+	url := "/api/v2/export"
+	if showSecrets {
+		url += "?show_secrets=true"
+	}
+	_, err := s.client.doGET(ctx, url, &archive)
+	return archive, err
+}
@@ -25,12 +25,42 @@ func (s *ISCSIService) Create(ctx context.Context, config *iscsi.ResourceConfig)
 	return ret, err
 }
 
+// Get returns the configuration of the target. CHAP passwords are redacted,
+// use GetWithSecrets to include them.
 func (s *ISCSIService) Get(ctx context.Context, iqn iscsi.Iqn) (*iscsi.ResourceConfig, error) {
 	var config *iscsi.ResourceConfig
 	_, err := s.client.doGET(ctx, "/api/v2/iscsi/"+iqn.String(), &config)
 	return config, err
 }
 
+// GetWithSecrets returns the configuration of the target including the CHAP
+// passwords. Passwords of targets using external secrets are never known to
+// the server. If authentication is enabled, this requires the admin role.
+func (s *ISCSIService) GetWithSecrets(ctx context.Context, iqn iscsi.Iqn) (*iscsi.ResourceConfig, error) {
+	var config *iscsi.ResourceConfig
+	_, err := s.client.doGET(ctx, "/api/v2/iscsi/"+iqn.String()+"?show_secrets=true", &config)
+	return config, err
+}
+
+// Patch applies the changes described in patch to an existing target.
+func (s *ISCSIService) Patch(ctx context.Context, iqn iscsi.Iqn, patch *iscsi.ResourcePatch) (*iscsi.ResourceConfig, error) {
+	var ret *iscsi.ResourceConfig
+	_, err := s.client.doPATCH(ctx, "/api/v2/iscsi/"+iqn.String(), patch, &ret)
+	return ret, err
+}
+
+// Update changes an existing target to match config. If dryRun is set, the
+// changes are only computed, but not applied.
+func (s *ISCSIService) Update(ctx context.Context, config *iscsi.ResourceConfig, dryRun bool) (*iscsi.UpdateResult, error) {
+	var ret *iscsi.UpdateResult
+	url := "/api/v2/iscsi/" + config.IQN.String()
+	if dryRun {
+		url += "?dry_run=true"
+	}
+	_, err := s.client.doPUT(ctx, url, config, &ret)
+	return ret, err
+}
+
 func (s *ISCSIService) Delete(ctx context.Context, iqn iscsi.Iqn, resourceTimeout time.Duration) error {
 	url := "/api/v2/iscsi/" + iqn.String()
 	if resourceTimeout > 0 {
@@ -60,6 +90,17 @@ func (s *ISCSIService) Stop(ctx context.Context, iqn iscsi.Iqn, resourceTimeout
 	return ret, err
 }
 
+// Migrate moves a running target to node and waits until it runs there.
+func (s *ISCSIService) Migrate(ctx context.Context, iqn iscsi.Iqn, node string, resourceTimeout time.Duration) (*iscsi.ResourceConfig, error) {
+	var ret *iscsi.ResourceConfig
+	url := "/api/v2/iscsi/" + iqn.String() + "/migrate"
+	if resourceTimeout > 0 {
+		url += "?resource_timeout=" + resourceTimeout.String()
+	}
+	_, err := s.client.doPOST(ctx, url, common.Migration{Node: node}, &ret)
+	return ret, err
+}
+
 func (s *ISCSIService) GetLogicalUnit(ctx context.Context, iqn iscsi.Iqn, lun int) (*common.VolumeConfig, error) {
 	var config *common.VolumeConfig
 	_, err := s.client.doGET(ctx, fmt.Sprintf("/api/v2/iscsi/%s/%d", iqn.String(), lun), &config)
@@ -72,7 +113,100 @@ func (s *ISCSIService) AddLogicalUnit(ctx context.Context, iqn iscsi.Iqn, volume
 	return ret, err
 }
 
+func (s *ISCSIService) ResizeLogicalUnit(ctx context.Context, iqn iscsi.Iqn, lun int, sizeKiB uint64) (*common.Volume, error) {
+	var ret *common.Volume
+	_, err := s.client.doPATCH(ctx, fmt.Sprintf("/api/v2/iscsi/%s/%d", iqn.String(), lun), &common.VolumeConfig{Number: lun, SizeKiB: sizeKiB}, &ret)
+	return ret, err
+}
+
 func (s *ISCSIService) DeleteLogicalUnit(ctx context.Context, iqn iscsi.Iqn, lun int) error {
 	_, err := s.client.doDELETE(ctx, fmt.Sprintf("/api/v2/iscsi/%s/%d", iqn.String(), lun), nil)
 	return err
 }
+
+func (s *ISCSIService) ListSnapshots(ctx context.Context, iqn iscsi.Iqn) ([]common.Snapshot, error) {
+	var ret []common.Snapshot
+	_, err := s.client.doGET(ctx, "/api/v2/iscsi/"+iqn.String()+"/snapshots", &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) CreateSnapshot(ctx context.Context, iqn iscsi.Iqn, snapshot string) (*common.Snapshot, error) {
+	var ret *common.Snapshot
+	_, err := s.client.doPOST(ctx, "/api/v2/iscsi/"+iqn.String()+"/snapshots", &common.Snapshot{Name: snapshot}, &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) DeleteSnapshot(ctx context.Context, iqn iscsi.Iqn, snapshot string) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/iscsi/"+iqn.String()+"/snapshots/"+snapshot, nil)
+	return err
+}
+
+func (s *ISCSIService) RollbackSnapshot(ctx context.Context, iqn iscsi.Iqn, snapshot string, resourceTimeout time.Duration) (*iscsi.ResourceConfig, error) {
+	var ret *iscsi.ResourceConfig
+	url := "/api/v2/iscsi/" + iqn.String() + "/snapshots/" + snapshot + "/rollback"
+	if resourceTimeout > 0 {
+		url += "?resource_timeout=" + resourceTimeout.String()
+	}
+	_, err := s.client.doPOST(ctx, url, nil, &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) Clone(ctx context.Context, src iscsi.Iqn, snapshot string, config *iscsi.ResourceConfig) (*iscsi.ResourceConfig, error) {
+	var ret *iscsi.ResourceConfig
+	url := "/api/v2/iscsi/" + src.String() + "/clone"
+	if snapshot != "" {
+		url += "?snapshot=" + snapshot
+	}
+	_, err := s.client.doPOST(ctx, url, config, &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) SnapshotSchedule(ctx context.Context, iqn iscsi.Iqn) (*common.SnapshotSchedule, error) {
+	var ret *common.SnapshotSchedule
+	_, err := s.client.doGET(ctx, "/api/v2/iscsi/"+iqn.String()+"/schedule", &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) SetSnapshotSchedule(ctx context.Context, iqn iscsi.Iqn, schedule *common.SnapshotSchedule) (*common.SnapshotSchedule, error) {
+	var ret *common.SnapshotSchedule
+	_, err := s.client.doPUT(ctx, "/api/v2/iscsi/"+iqn.String()+"/schedule", schedule, &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) DeleteSnapshotSchedule(ctx context.Context, iqn iscsi.Iqn) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/iscsi/"+iqn.String()+"/schedule", nil)
+	return err
+}
+
+func (s *ISCSIService) Replication(ctx context.Context, iqn iscsi.Iqn) (*common.Replication, error) {
+	var ret *common.Replication
+	_, err := s.client.doGET(ctx, "/api/v2/iscsi/"+iqn.String()+"/replication", &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) SetReplication(ctx context.Context, iqn iscsi.Iqn, replication *common.Replication) (*common.Replication, error) {
+	var ret *common.Replication
+	_, err := s.client.doPUT(ctx, "/api/v2/iscsi/"+iqn.String()+"/replication", replication, &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) DeleteReplication(ctx context.Context, iqn iscsi.Iqn) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/iscsi/"+iqn.String()+"/replication", nil)
+	return err
+}
+
+func (s *ISCSIService) ShipSnapshot(ctx context.Context, iqn iscsi.Iqn) (*common.Snapshot, error) {
+	var ret *common.Snapshot
+	_, err := s.client.doPOST(ctx, "/api/v2/iscsi/"+iqn.String()+"/replication/ship", nil, &ret)
+	return ret, err
+}
+
+func (s *ISCSIService) PromoteDR(ctx context.Context, iqn iscsi.Iqn, snapshot string, config *iscsi.ResourceConfig) (*iscsi.ResourceConfig, error) {
+	var ret *iscsi.ResourceConfig
+	url := "/api/v2/iscsi/" + iqn.String() + "/promote-dr"
+	if snapshot != "" {
+		url += "?snapshot=" + snapshot
+	}
+	_, err := s.client.doPOST(ctx, url, config, &ret)
+	return ret, err
+}
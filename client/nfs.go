@@ -2,8 +2,10 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/LINBIT/linstor-gateway/pkg/common"
 	"github.com/LINBIT/linstor-gateway/pkg/nfs"
 )
 
@@ -29,6 +31,25 @@ func (s *NFSService) Get(ctx context.Context, name string) (*nfs.ResourceConfig,
 	return config, err
 }
 
+// Patch applies the changes described in patch to an existing export.
+func (s *NFSService) Patch(ctx context.Context, name string, patch *nfs.ResourcePatch) (*nfs.ResourceConfig, error) {
+	var ret *nfs.ResourceConfig
+	_, err := s.client.doPATCH(ctx, "/api/v2/nfs/"+name, patch, &ret)
+	return ret, err
+}
+
+// Update changes an existing export to match config. If dryRun is set, the
+// changes are only computed, but not applied.
+func (s *NFSService) Update(ctx context.Context, config *nfs.ResourceConfig, dryRun bool) (*nfs.UpdateResult, error) {
+	var ret *nfs.UpdateResult
+	url := "/api/v2/nfs/" + config.Name
+	if dryRun {
+		url += "?dry_run=true"
+	}
+	_, err := s.client.doPUT(ctx, url, config, &ret)
+	return ret, err
+}
+
 func (s *NFSService) Delete(ctx context.Context, name string, resourceTimeout time.Duration) error {
 	url := "/api/v2/nfs/" + name
 	if resourceTimeout > 0 {
@@ -57,3 +78,114 @@ func (s *NFSService) Stop(ctx context.Context, name string, resourceTimeout time
 	_, err := s.client.doPOST(ctx, url, nil, &ret)
 	return ret, err
 }
+
+// Migrate moves a running export to node and waits until it runs there.
+func (s *NFSService) Migrate(ctx context.Context, name string, node string, resourceTimeout time.Duration) (*nfs.ResourceConfig, error) {
+	var ret *nfs.ResourceConfig
+	url := "/api/v2/nfs/" + name + "/migrate"
+	if resourceTimeout > 0 {
+		url += "?resource_timeout=" + resourceTimeout.String()
+	}
+	_, err := s.client.doPOST(ctx, url, common.Migration{Node: node}, &ret)
+	return ret, err
+}
+
+func (s *NFSService) AddVolume(ctx context.Context, name string, volume *nfs.VolumeConfig) (*common.Volume, error) {
+	var ret *common.Volume
+	_, err := s.client.doPOST(ctx, "/api/v2/nfs/"+name+"/volumes", volume, &ret)
+	return ret, err
+}
+
+func (s *NFSService) ResizeVolume(ctx context.Context, name string, volume int, sizeKiB uint64) (*common.Volume, error) {
+	var ret *common.Volume
+	body := &nfs.VolumeConfig{VolumeConfig: common.VolumeConfig{Number: volume, SizeKiB: sizeKiB}}
+	_, err := s.client.doPUT(ctx, fmt.Sprintf("/api/v2/nfs/%s/%d", name, volume), body, &ret)
+	return ret, err
+}
+
+func (s *NFSService) ListSnapshots(ctx context.Context, name string) ([]common.Snapshot, error) {
+	var ret []common.Snapshot
+	_, err := s.client.doGET(ctx, "/api/v2/nfs/"+name+"/snapshots", &ret)
+	return ret, err
+}
+
+func (s *NFSService) CreateSnapshot(ctx context.Context, name string, snapshot string) (*common.Snapshot, error) {
+	var ret *common.Snapshot
+	_, err := s.client.doPOST(ctx, "/api/v2/nfs/"+name+"/snapshots", &common.Snapshot{Name: snapshot}, &ret)
+	return ret, err
+}
+
+func (s *NFSService) DeleteSnapshot(ctx context.Context, name string, snapshot string) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/nfs/"+name+"/snapshots/"+snapshot, nil)
+	return err
+}
+
+func (s *NFSService) RollbackSnapshot(ctx context.Context, name string, snapshot string, resourceTimeout time.Duration) (*nfs.ResourceConfig, error) {
+	var ret *nfs.ResourceConfig
+	url := "/api/v2/nfs/" + name + "/snapshots/" + snapshot + "/rollback"
+	if resourceTimeout > 0 {
+		url += "?resource_timeout=" + resourceTimeout.String()
+	}
+	_, err := s.client.doPOST(ctx, url, nil, &ret)
+	return ret, err
+}
+
+func (s *NFSService) Clone(ctx context.Context, src string, snapshot string, config *nfs.ResourceConfig) (*nfs.ResourceConfig, error) {
+	var ret *nfs.ResourceConfig
+	url := "/api/v2/nfs/" + src + "/clone"
+	if snapshot != "" {
+		url += "?snapshot=" + snapshot
+	}
+	_, err := s.client.doPOST(ctx, url, config, &ret)
+	return ret, err
+}
+
+func (s *NFSService) SnapshotSchedule(ctx context.Context, name string) (*common.SnapshotSchedule, error) {
+	var ret *common.SnapshotSchedule
+	_, err := s.client.doGET(ctx, "/api/v2/nfs/"+name+"/schedule", &ret)
+	return ret, err
+}
+
+func (s *NFSService) SetSnapshotSchedule(ctx context.Context, name string, schedule *common.SnapshotSchedule) (*common.SnapshotSchedule, error) {
+	var ret *common.SnapshotSchedule
+	_, err := s.client.doPUT(ctx, "/api/v2/nfs/"+name+"/schedule", schedule, &ret)
+	return ret, err
+}
+
+func (s *NFSService) DeleteSnapshotSchedule(ctx context.Context, name string) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/nfs/"+name+"/schedule", nil)
+	return err
+}
+
+func (s *NFSService) Replication(ctx context.Context, name string) (*common.Replication, error) {
+	var ret *common.Replication
+	_, err := s.client.doGET(ctx, "/api/v2/nfs/"+name+"/replication", &ret)
+	return ret, err
+}
+
+func (s *NFSService) SetReplication(ctx context.Context, name string, replication *common.Replication) (*common.Replication, error) {
+	var ret *common.Replication
+	_, err := s.client.doPUT(ctx, "/api/v2/nfs/"+name+"/replication", replication, &ret)
+	return ret, err
+}
+
+func (s *NFSService) DeleteReplication(ctx context.Context, name string) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/nfs/"+name+"/replication", nil)
+	return err
+}
+
+func (s *NFSService) ShipSnapshot(ctx context.Context, name string) (*common.Snapshot, error) {
+	var ret *common.Snapshot
+	_, err := s.client.doPOST(ctx, "/api/v2/nfs/"+name+"/replication/ship", nil, &ret)
+	return ret, err
+}
+
+func (s *NFSService) PromoteDR(ctx context.Context, name string, snapshot string, config *nfs.ResourceConfig) (*nfs.ResourceConfig, error) {
+	var ret *nfs.ResourceConfig
+	url := "/api/v2/nfs/" + name + "/promote-dr"
+	if snapshot != "" {
+		url += "?snapshot=" + snapshot
+	}
+	_, err := s.client.doPOST(ctx, url, config, &ret)
+	return ret, err
+}
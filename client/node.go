@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/LINBIT/linstor-gateway/pkg/maintenance"
+)
+
+type NodeService struct {
+	client *Client
+}
+
+// GetAll returns whether each node is evacuated.
+func (s *NodeService) GetAll(ctx context.Context) ([]maintenance.NodeStatus, error) {
+	var ret []maintenance.NodeStatus
+	_, err := s.client.doGET(ctx, "/api/v2/nodes", &ret)
+	return ret, err
+}
+
+// Evacuate moves all gateway resources off node and keeps them away from it
+// until Resume is called.
+func (s *NodeService) Evacuate(ctx context.Context, node string, resourceTimeout time.Duration) (*maintenance.Report, error) {
+	var ret *maintenance.Report
+	url := "/api/v2/nodes/" + node + "/evacuate"
+	if resourceTimeout > 0 {
+		url += "?resource_timeout=" + resourceTimeout.String()
+	}
+	_, err := s.client.doPOST(ctx, url, nil, &ret)
+	return ret, err
+}
+
+// Resume allows gateway resources to run on an evacuated node again.
+func (s *NodeService) Resume(ctx context.Context, node string) (*maintenance.Report, error) {
+	var ret *maintenance.Report
+	_, err := s.client.doPOST(ctx, "/api/v2/nodes/"+node+"/resume", nil, &ret)
+	return ret, err
+}
@@ -25,12 +25,35 @@ func (s *NvmeOfService) Create(ctx context.Context, config *nvmeof.ResourceConfi
 	return ret, err
 }
 
+// Get returns the configuration of the target. DH-HMAC-CHAP keys are redacted,
+// use GetWithSecrets to include them.
 func (s *NvmeOfService) Get(ctx context.Context, nqn nvmeof.Nqn) (*nvmeof.ResourceConfig, error) {
 	var config *nvmeof.ResourceConfig
 	_, err := s.client.doGET(ctx, "/api/v2/nvme-of/"+nqn.String(), &config)
 	return config, err
 }
 
+// GetWithSecrets returns the configuration of the target including the
+// DH-HMAC-CHAP keys. Keys of targets using external secrets are never known to
+// the server. If authentication is enabled, this requires the admin role.
+func (s *NvmeOfService) GetWithSecrets(ctx context.Context, nqn nvmeof.Nqn) (*nvmeof.ResourceConfig, error) {
+	var config *nvmeof.ResourceConfig
+	_, err := s.client.doGET(ctx, "/api/v2/nvme-of/"+nqn.String()+"?show_secrets=true", &config)
+	return config, err
+}
+
+// Update changes an existing target to match config. If dryRun is set, the
+// changes are only computed, but not applied.
+func (s *NvmeOfService) Update(ctx context.Context, config *nvmeof.ResourceConfig, dryRun bool) (*nvmeof.UpdateResult, error) {
+	var ret *nvmeof.UpdateResult
+	url := "/api/v2/nvme-of/" + config.NQN.String()
+	if dryRun {
+		url += "?dry_run=true"
+	}
+	_, err := s.client.doPUT(ctx, url, config, &ret)
+	return ret, err
+}
+
 func (s *NvmeOfService) Delete(ctx context.Context, nqn nvmeof.Nqn, resourceTimeout time.Duration) error {
 	url := "/api/v2/nvme-of/" + nqn.String()
 	if resourceTimeout > 0 {
@@ -60,6 +83,17 @@ func (s *NvmeOfService) Stop(ctx context.Context, nqn nvmeof.Nqn, resourceTimeou
 	return ret, err
 }
 
+// Migrate moves a running target to node and waits until it runs there.
+func (s *NvmeOfService) Migrate(ctx context.Context, nqn nvmeof.Nqn, node string, resourceTimeout time.Duration) (*nvmeof.ResourceConfig, error) {
+	var ret *nvmeof.ResourceConfig
+	url := "/api/v2/nvme-of/" + nqn.String() + "/migrate"
+	if resourceTimeout > 0 {
+		url += "?resource_timeout=" + resourceTimeout.String()
+	}
+	_, err := s.client.doPOST(ctx, url, common.Migration{Node: node}, &ret)
+	return ret, err
+}
+
 func (s *NvmeOfService) GetVolume(ctx context.Context, nqn nvmeof.Nqn, lun int) (*common.VolumeConfig, error) {
 	var config *common.VolumeConfig
 	_, err := s.client.doGET(ctx, fmt.Sprintf("/api/v2/nvme-of/%s/%d", nqn.String(), lun), &config)
@@ -72,7 +106,117 @@ func (s *NvmeOfService) AddVolume(ctx context.Context, nqn nvmeof.Nqn, volume *c
 	return ret, err
 }
 
+func (s *NvmeOfService) ResizeVolume(ctx context.Context, nqn nvmeof.Nqn, volume int, sizeKiB uint64) (*common.Volume, error) {
+	var ret *common.Volume
+	_, err := s.client.doPATCH(ctx, fmt.Sprintf("/api/v2/nvme-of/%s/%d", nqn.String(), volume), &common.VolumeConfig{Number: volume, SizeKiB: sizeKiB}, &ret)
+	return ret, err
+}
+
 func (s *NvmeOfService) DeleteVolume(ctx context.Context, nqn nvmeof.Nqn, volume int) error {
 	_, err := s.client.doDELETE(ctx, fmt.Sprintf("/api/v2/nvme-of/%s/%d", nqn.String(), volume), nil)
 	return err
 }
+
+func (s *NvmeOfService) SetAllowedHosts(ctx context.Context, nqn nvmeof.Nqn, hosts []nvmeof.HostNqn) (*nvmeof.ResourceConfig, error) {
+	var ret *nvmeof.ResourceConfig
+	_, err := s.client.doPUT(ctx, "/api/v2/nvme-of/"+nqn.String()+"/allowed-hosts", hosts, &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) SetHostKey(ctx context.Context, nqn nvmeof.Nqn, host nvmeof.HostNqn, key *nvmeof.HostKey) (*nvmeof.ResourceConfig, error) {
+	var ret *nvmeof.ResourceConfig
+	_, err := s.client.doPUT(ctx, "/api/v2/nvme-of/"+nqn.String()+"/host-keys/"+host.String(), key, &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) DeleteHostKey(ctx context.Context, nqn nvmeof.Nqn, host nvmeof.HostNqn) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/nvme-of/"+nqn.String()+"/host-keys/"+host.String(), nil)
+	return err
+}
+
+func (s *NvmeOfService) ListSnapshots(ctx context.Context, nqn nvmeof.Nqn) ([]common.Snapshot, error) {
+	var ret []common.Snapshot
+	_, err := s.client.doGET(ctx, "/api/v2/nvme-of/"+nqn.String()+"/snapshots", &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) CreateSnapshot(ctx context.Context, nqn nvmeof.Nqn, snapshot string) (*common.Snapshot, error) {
+	var ret *common.Snapshot
+	_, err := s.client.doPOST(ctx, "/api/v2/nvme-of/"+nqn.String()+"/snapshots", &common.Snapshot{Name: snapshot}, &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) DeleteSnapshot(ctx context.Context, nqn nvmeof.Nqn, snapshot string) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/nvme-of/"+nqn.String()+"/snapshots/"+snapshot, nil)
+	return err
+}
+
+func (s *NvmeOfService) RollbackSnapshot(ctx context.Context, nqn nvmeof.Nqn, snapshot string, resourceTimeout time.Duration) (*nvmeof.ResourceConfig, error) {
+	var ret *nvmeof.ResourceConfig
+	url := "/api/v2/nvme-of/" + nqn.String() + "/snapshots/" + snapshot + "/rollback"
+	if resourceTimeout > 0 {
+		url += "?resource_timeout=" + resourceTimeout.String()
+	}
+	_, err := s.client.doPOST(ctx, url, nil, &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) Clone(ctx context.Context, src nvmeof.Nqn, snapshot string, config *nvmeof.ResourceConfig) (*nvmeof.ResourceConfig, error) {
+	var ret *nvmeof.ResourceConfig
+	url := "/api/v2/nvme-of/" + src.String() + "/clone"
+	if snapshot != "" {
+		url += "?snapshot=" + snapshot
+	}
+	_, err := s.client.doPOST(ctx, url, config, &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) SnapshotSchedule(ctx context.Context, nqn nvmeof.Nqn) (*common.SnapshotSchedule, error) {
+	var ret *common.SnapshotSchedule
+	_, err := s.client.doGET(ctx, "/api/v2/nvme-of/"+nqn.String()+"/schedule", &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) SetSnapshotSchedule(ctx context.Context, nqn nvmeof.Nqn, schedule *common.SnapshotSchedule) (*common.SnapshotSchedule, error) {
+	var ret *common.SnapshotSchedule
+	_, err := s.client.doPUT(ctx, "/api/v2/nvme-of/"+nqn.String()+"/schedule", schedule, &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) DeleteSnapshotSchedule(ctx context.Context, nqn nvmeof.Nqn) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/nvme-of/"+nqn.String()+"/schedule", nil)
+	return err
+}
+
+func (s *NvmeOfService) Replication(ctx context.Context, nqn nvmeof.Nqn) (*common.Replication, error) {
+	var ret *common.Replication
+	_, err := s.client.doGET(ctx, "/api/v2/nvme-of/"+nqn.String()+"/replication", &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) SetReplication(ctx context.Context, nqn nvmeof.Nqn, replication *common.Replication) (*common.Replication, error) {
+	var ret *common.Replication
+	_, err := s.client.doPUT(ctx, "/api/v2/nvme-of/"+nqn.String()+"/replication", replication, &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) DeleteReplication(ctx context.Context, nqn nvmeof.Nqn) error {
+	_, err := s.client.doDELETE(ctx, "/api/v2/nvme-of/"+nqn.String()+"/replication", nil)
+	return err
+}
+
+func (s *NvmeOfService) ShipSnapshot(ctx context.Context, nqn nvmeof.Nqn) (*common.Snapshot, error) {
+	var ret *common.Snapshot
+	_, err := s.client.doPOST(ctx, "/api/v2/nvme-of/"+nqn.String()+"/replication/ship", nil, &ret)
+	return ret, err
+}
+
+func (s *NvmeOfService) PromoteDR(ctx context.Context, nqn nvmeof.Nqn, snapshot string, config *nvmeof.ResourceConfig) (*nvmeof.ResourceConfig, error) {
+	var ret *nvmeof.ResourceConfig
+	url := "/api/v2/nvme-of/" + nqn.String() + "/promote-dr"
+	if snapshot != "" {
+		url += "?snapshot=" + snapshot
+	}
+	_, err := s.client.doPOST(ctx, url, config, &ret)
+	return ret, err
+}
@@ -1,8 +1,14 @@
 package client
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
+	"os"
 )
 
 // Option configures a Client
@@ -23,6 +29,66 @@ func UserAgent(userAgent string) Option {
 	}
 }
 
+// BearerToken is a Client's option to authenticate with a static token.
+func BearerToken(token string) Option {
+	return func(c *Client) error {
+		c.authorization = "Bearer " + token
+		return nil
+	}
+}
+
+// BasicAuth is a Client's option to authenticate with a username and
+// password.
+func BasicAuth(username, password string) Option {
+	return func(c *Client) error {
+		c.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+		return nil
+	}
+}
+
+// TLSConfig is a Client's option to set the TLS configuration used for https
+// connections.
+func TLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) error {
+		c.transport().TLSClientConfig = cfg
+		return nil
+	}
+}
+
+// CACertificates is a Client's option to verify the server certificate
+// against the PEM bundle at path instead of the system certificates.
+func CACertificates(path string) Option {
+	return func(c *Client) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificates: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", path)
+		}
+
+		c.tlsConfig().RootCAs = pool
+		return nil
+	}
+}
+
+// ClientCertificate is a Client's option to authenticate with a client
+// certificate (mutual TLS).
+func ClientCertificate(certFile, keyFile string) Option {
+	return func(c *Client) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		cfg := c.tlsConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+		return nil
+	}
+}
+
 // Log is a client's option to set a Logger
 func Log(logger interface{}) Option {
 	return func(c *Client) error {
@@ -35,3 +101,24 @@ func Log(logger interface{}) Option {
 		return nil
 	}
 }
+
+// transport returns the transport of the http client, so that options can
+// change it without affecting http.DefaultTransport.
+func (c *Client) transport() *http.Transport {
+	t, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport).Clone()
+		c.httpClient.Transport = t
+	}
+	return t
+}
+
+// tlsConfig returns the TLS configuration of the transport, creating it if
+// needed.
+func (c *Client) tlsConfig() *tls.Config {
+	t := c.transport()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return t.TLSClientConfig
+}
@@ -0,0 +1,512 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+	"github.com/LINBIT/linstor-gateway/pkg/manifest"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+	"github.com/LINBIT/linstor-gateway/pkg/prompt"
+)
+
+type applyAction int
+
+const (
+	applyDelete applyAction = iota
+	applyUpdate
+	applyCreate
+	// applySkip marks a resource that differs from the manifest in a way
+	// apply cannot change.
+	applySkip
+)
+
+// applyStep is a single change that is needed to make the cluster match the
+// manifest.
+type applyStep struct {
+	action  applyAction
+	kind    string
+	id      string
+	diff    string
+	group   string
+	volumes []manifest.VolumeChange
+	restart bool
+	skipped string
+	run     func(ctx context.Context) error
+}
+
+func (s *applyStep) String() string {
+	switch s.action {
+	case applySkip:
+		return color.New(color.Faint).Sprintf("! %s %s skipped: %s", s.kind, s.id, s.skipped)
+	case applyDelete:
+		return color.RedString("- %s %s", s.kind, s.id)
+	case applyUpdate:
+		line := color.YellowString("~ %s %s", s.kind, s.id)
+		if s.restart {
+			line += " " + bold("(restart)")
+		}
+		return line
+	default:
+		return color.GreenString("+ %s %s", s.kind, s.id)
+	}
+}
+
+func applyCommand() *cobra.Command {
+	var file string
+	var prune, dryRun, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "apply -f FILE",
+		Short: "Makes the cluster match a manifest of resources",
+		Long: `Reads a manifest of iSCSI targets, NFS exports and NVMe-oF targets and
+creates or updates resources so that the cluster matches it.
+
+The manifest is YAML (or JSON), or TOML if the file name ends in ".toml".
+Its top level keys are "iscsi", "nfs" and "nvme-of", each containing a list
+of resources in the same format as the REST API uses.
+
+Resources that exist in the cluster but are not listed in the manifest are
+left alone, unless --prune is given, in which case they are deleted.
+
+Volumes of existing resources are added or grown as listed in the manifest.
+Resources whose volumes would have to shrink, be deleted, or move to another
+export path are skipped and reported.
+
+The planned changes are printed before anything is done.`,
+		Example: `linstor-gateway apply -f targets.yaml
+linstor-gateway apply -f targets.yaml --prune --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read manifest: %w", err)
+			}
+
+			m, err := manifest.Parse(data, manifest.FormatFromPath(file))
+			if err != nil {
+				return err
+			}
+
+			steps, err := planApply(ctx, m, true, prune)
+			if err != nil {
+				return err
+			}
+
+			if len(steps) == 0 {
+				fmt.Println("Nothing to do, the cluster matches the manifest")
+				return nil
+			}
+
+			fmt.Println("Planned changes:")
+			for _, step := range steps {
+				fmt.Println(step)
+				if step.group != "" {
+					fmt.Printf("    resource group: %s\n", step.group)
+				}
+				for _, change := range step.volumes {
+					if change.CurrentSizeKiB == 0 {
+						fmt.Printf("    add volume %d: %d KiB\n", change.Volume.Number, change.Volume.SizeKiB)
+					} else {
+						fmt.Printf("    resize volume %d: %d KiB -> %d KiB\n", change.Volume.Number, change.CurrentSizeKiB, change.Volume.SizeKiB)
+					}
+				}
+				if step.diff != "" {
+					fmt.Println(indent(prompt.ColorDiff(step.diff), "    "))
+				}
+			}
+
+			if dryRun {
+				return nil
+			}
+
+			if !yes {
+				if prune && hasDeletes(steps) {
+					fmt.Printf("%s: Deleted resources lose %s.\n",
+						color.YellowString("WARNING"), bold("all data stored on them"))
+				}
+				if !prompt.Confirm("Continue?") {
+					fmt.Println("Aborted")
+					return nil
+				}
+			}
+
+			skipped := 0
+			for _, step := range steps {
+				if step.action == applySkip {
+					skipped++
+					continue
+				}
+				err := step.run(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to apply %s %s: %w", step.kind, step.id, err)
+				}
+				fmt.Printf("Applied %s %s\n", step.kind, step.id)
+			}
+
+			if skipped > 0 {
+				return fmt.Errorf("%d resources were skipped and still differ from the manifest", skipped)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "Manifest to apply")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete resources that are not in the manifest")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the planned changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Apply without prompting for confirmation")
+	cmd.MarkFlagRequired("filename")
+
+	return cmd
+}
+
+// planApply computes the steps needed to make the cluster match m. Existing
+// resources are only updated if update is set. Deletions come first, so that
+// they free up IPs for the resources created later.
+func planApply(ctx context.Context, m *manifest.Manifest, update, prune bool) ([]*applyStep, error) {
+	var steps []*applyStep
+
+	// volume 0 is added by the server when creating a resource, so it must
+	// not be passed in, even if the manifest lists it
+	m.StripClusterPrivateVolumes()
+
+	iscsiSteps, err := planISCSI(ctx, m.ISCSI, update, prune)
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, iscsiSteps...)
+
+	nfsSteps, err := planNFS(ctx, m.NFS, update, prune)
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, nfsSteps...)
+
+	nvmeSteps, err := planNVMeoF(ctx, m.NVMeoF, update, prune)
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, nvmeSteps...)
+
+	ordered := make([]*applyStep, 0, len(steps))
+	for _, action := range []applyAction{applySkip, applyDelete, applyUpdate, applyCreate} {
+		for _, step := range steps {
+			if step.action == action {
+				ordered = append(ordered, step)
+			}
+		}
+	}
+
+	return ordered, nil
+}
+
+func planISCSI(ctx context.Context, desired []*iscsi.ResourceConfig, update, prune bool) ([]*applyStep, error) {
+	existing, err := cli.Iscsi.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list iSCSI targets: %w", err)
+	}
+
+	current := make(map[string]*iscsi.ResourceConfig)
+	for _, rsc := range existing {
+		current[rsc.IQN.String()] = rsc
+	}
+
+	var steps []*applyStep
+	listed := make(map[string]bool)
+	for _, rsc := range desired {
+		rsc := rsc
+		id := rsc.IQN.String()
+		listed[id] = true
+
+		if current[id] == nil {
+			steps = append(steps, &applyStep{action: applyCreate, kind: "iscsi", id: id, run: func(ctx context.Context) error {
+				_, err := cli.Iscsi.Create(ctx, rsc)
+				return err
+			}})
+			continue
+		}
+
+		if !update {
+			continue
+		}
+
+		changes, err := manifest.VolumeChanges(current[id].Volumes, rsc.Volumes)
+		if err != nil {
+			steps = append(steps, &applyStep{action: applySkip, kind: "iscsi", id: id, skipped: err.Error()})
+			continue
+		}
+
+		// the volumes are changed separately, the update keeps them as they are
+		next := *rsc
+		next.Volumes = nil
+		result, err := cli.Iscsi.Update(ctx, &next, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan update of iSCSI target %s: %w", id, err)
+		}
+		updated := result.Diff != "" || result.ResourceGroup != ""
+		if !updated && len(changes) == 0 {
+			continue
+		}
+		steps = append(steps, &applyStep{action: applyUpdate, kind: "iscsi", id: id, diff: result.Diff, group: result.ResourceGroup, volumes: changes, restart: result.Restart, run: func(ctx context.Context) error {
+			for i := range changes {
+				vol := &changes[i].Volume
+				var err error
+				if changes[i].CurrentSizeKiB == 0 {
+					_, err = cli.Iscsi.AddLogicalUnit(ctx, rsc.IQN, vol)
+				} else {
+					_, err = cli.Iscsi.ResizeLogicalUnit(ctx, rsc.IQN, vol.Number, vol.SizeKiB)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to change logical unit %d: %w", vol.Number, err)
+				}
+			}
+			if !updated {
+				return nil
+			}
+			_, err := cli.Iscsi.Update(ctx, &next, false)
+			return err
+		}})
+	}
+
+	if prune {
+		for _, rsc := range existing {
+			iqn := rsc.IQN
+			if listed[iqn.String()] {
+				continue
+			}
+			steps = append(steps, &applyStep{action: applyDelete, kind: "iscsi", id: iqn.String(), run: func(ctx context.Context) error {
+				return cli.Iscsi.Delete(ctx, iqn, 0)
+			}})
+		}
+	}
+
+	return steps, nil
+}
+
+func planNFS(ctx context.Context, desired []*nfs.ResourceConfig, update, prune bool) ([]*applyStep, error) {
+	existing, err := cli.Nfs.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NFS exports: %w", err)
+	}
+
+	current := make(map[string]*nfs.ResourceConfig)
+	for _, rsc := range existing {
+		current[rsc.Name] = rsc
+	}
+
+	var steps []*applyStep
+	listed := make(map[string]bool)
+	for _, rsc := range desired {
+		rsc := rsc
+		listed[rsc.Name] = true
+
+		if current[rsc.Name] == nil {
+			steps = append(steps, &applyStep{action: applyCreate, kind: "nfs", id: rsc.Name, run: func(ctx context.Context) error {
+				_, err := cli.Nfs.Create(ctx, rsc)
+				return err
+			}})
+			continue
+		}
+
+		if !update {
+			continue
+		}
+
+		changes, err := nfsVolumeChanges(current[rsc.Name], rsc)
+		if err != nil {
+			steps = append(steps, &applyStep{action: applySkip, kind: "nfs", id: rsc.Name, skipped: err.Error()})
+			continue
+		}
+
+		// the volumes are changed separately, the update keeps them as they are
+		next := *rsc
+		next.Volumes = nil
+		result, err := cli.Nfs.Update(ctx, &next, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan update of NFS export %s: %w", rsc.Name, err)
+		}
+		updated := result.Diff != "" || result.ResourceGroup != ""
+		if !updated && len(changes) == 0 {
+			continue
+		}
+		steps = append(steps, &applyStep{action: applyUpdate, kind: "nfs", id: rsc.Name, diff: result.Diff, group: result.ResourceGroup, volumes: changes, restart: result.Restart, run: func(ctx context.Context) error {
+			for i := range changes {
+				vol := &changes[i].Volume
+				var err error
+				if changes[i].CurrentSizeKiB == 0 {
+					_, err = cli.Nfs.AddVolume(ctx, rsc.Name, nfsVolume(rsc, vol.Number))
+				} else {
+					_, err = cli.Nfs.ResizeVolume(ctx, rsc.Name, vol.Number, vol.SizeKiB)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to change volume %d: %w", vol.Number, err)
+				}
+			}
+			if !updated {
+				return nil
+			}
+			_, err := cli.Nfs.Update(ctx, &next, false)
+			return err
+		}})
+	}
+
+	if prune {
+		for _, rsc := range existing {
+			name := rsc.Name
+			if listed[name] {
+				continue
+			}
+			steps = append(steps, &applyStep{action: applyDelete, kind: "nfs", id: name, run: func(ctx context.Context) error {
+				return cli.Nfs.Delete(ctx, name, 0)
+			}})
+		}
+	}
+
+	return steps, nil
+}
+
+func planNVMeoF(ctx context.Context, desired []*nvmeof.ResourceConfig, update, prune bool) ([]*applyStep, error) {
+	existing, err := cli.NvmeOf.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NVMe-oF targets: %w", err)
+	}
+
+	current := make(map[string]*nvmeof.ResourceConfig)
+	for i := range existing {
+		current[existing[i].NQN.String()] = &existing[i]
+	}
+
+	var steps []*applyStep
+	listed := make(map[string]bool)
+	for _, rsc := range desired {
+		rsc := rsc
+		id := rsc.NQN.String()
+		listed[id] = true
+
+		if current[id] == nil {
+			steps = append(steps, &applyStep{action: applyCreate, kind: "nvme-of", id: id, run: func(ctx context.Context) error {
+				_, err := cli.NvmeOf.Create(ctx, rsc)
+				return err
+			}})
+			continue
+		}
+
+		if !update {
+			continue
+		}
+
+		changes, err := manifest.VolumeChanges(current[id].Volumes, rsc.Volumes)
+		if err != nil {
+			steps = append(steps, &applyStep{action: applySkip, kind: "nvme-of", id: id, skipped: err.Error()})
+			continue
+		}
+
+		// the volumes are changed separately, the update keeps them as they are
+		next := *rsc
+		next.Volumes = nil
+		result, err := cli.NvmeOf.Update(ctx, &next, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan update of NVMe-oF target %s: %w", id, err)
+		}
+		updated := result.Diff != "" || result.ResourceGroup != ""
+		if !updated && len(changes) == 0 {
+			continue
+		}
+		steps = append(steps, &applyStep{action: applyUpdate, kind: "nvme-of", id: id, diff: result.Diff, group: result.ResourceGroup, volumes: changes, restart: result.Restart, run: func(ctx context.Context) error {
+			for i := range changes {
+				vol := &changes[i].Volume
+				var err error
+				if changes[i].CurrentSizeKiB == 0 {
+					_, err = cli.NvmeOf.AddVolume(ctx, rsc.NQN, vol)
+				} else {
+					_, err = cli.NvmeOf.ResizeVolume(ctx, rsc.NQN, vol.Number, vol.SizeKiB)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to change namespace %d: %w", vol.Number, err)
+				}
+			}
+			if !updated {
+				return nil
+			}
+			_, err := cli.NvmeOf.Update(ctx, &next, false)
+			return err
+		}})
+	}
+
+	if prune {
+		for _, rsc := range existing {
+			nqn := rsc.NQN
+			if listed[nqn.String()] {
+				continue
+			}
+			steps = append(steps, &applyStep{action: applyDelete, kind: "nvme-of", id: nqn.String(), run: func(ctx context.Context) error {
+				return cli.NvmeOf.Delete(ctx, nqn, 0)
+			}})
+		}
+	}
+
+	return steps, nil
+}
+
+// nfsVolumeChanges is manifest.VolumeChanges for NFS exports. The export path
+// of existing volumes cannot be changed either.
+func nfsVolumeChanges(current, desired *nfs.ResourceConfig) ([]manifest.VolumeChange, error) {
+	for i := range desired.Volumes {
+		vol := &desired.Volumes[i]
+		for j := range current.Volumes {
+			if current.Volumes[j].Number != vol.Number || vol.Number == 0 {
+				continue
+			}
+			if nfs.ExportPath(current, &current.Volumes[j]) != nfs.ExportPath(desired, vol) {
+				return nil, fmt.Errorf("the export path of volume %d cannot be changed", vol.Number)
+			}
+		}
+	}
+
+	return manifest.VolumeChanges(nfsCommonVolumes(current.Volumes), nfsCommonVolumes(desired.Volumes))
+}
+
+func nfsCommonVolumes(volumes []nfs.VolumeConfig) []common.VolumeConfig {
+	result := make([]common.VolumeConfig, len(volumes))
+	for i := range volumes {
+		result[i] = volumes[i].VolumeConfig
+	}
+	return result
+}
+
+// nfsVolume returns volume nr of rsc.
+func nfsVolume(rsc *nfs.ResourceConfig, nr int) *nfs.VolumeConfig {
+	for i := range rsc.Volumes {
+		if rsc.Volumes[i].Number == nr {
+			return &rsc.Volumes[i]
+		}
+	}
+	return nil
+}
+
+func hasDeletes(steps []*applyStep) bool {
+	for _, step := range steps {
+		if step.action == applyDelete {
+			return true
+		}
+	}
+	return false
+}
+
+func indent(s, prefix string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
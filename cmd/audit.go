@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/spf13/cobra"
+
+	"github.com/LINBIT/linstor-gateway/pkg/audit"
+)
+
+func auditCommand() *cobra.Command {
+	var since time.Duration
+	var user string
+	var limit int
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Shows who changed which resource",
+		Long: `Shows the recent entries of the audit log of the LINSTOR Gateway server.
+Every request that creates, changes, starts, stops or deletes a resource is
+recorded, together with the user that made it and the result. Requests that
+were rejected for missing or invalid credentials are recorded as well.
+
+Only the most recent entries are kept by the server. See the [server.audit]
+section of the configuration file for keeping the full log in a file or in
+the systemd journal.`,
+		Example: `linstor-gateway audit --since 24h
+linstor-gateway audit --user alice --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := audit.Filter{User: user, Limit: limit}
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+
+			entries, err := cli.Audit.List(context.Background(), filter)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(entries)
+			}
+
+			table := tablewriter.NewTable(os.Stdout,
+				tablewriter.WithConfig(tablewriter.NewConfigBuilder().
+					Header().Formatting().WithAutoFormat(tw.Off).Build().Build().
+					Build()),
+			)
+			table.Header(colorHeader("Time"), colorHeader("User"), colorHeader("Source"), colorHeader("Request"), colorHeader("Result"))
+
+			for _, entry := range entries {
+				userName := entry.User
+				if userName == "" {
+					userName = "-"
+				}
+
+				result := colorOk(strconv.Itoa(entry.Status))
+				if entry.Status >= 400 {
+					result = colorBad(strconv.Itoa(entry.Status))
+					if entry.Error != "" {
+						result += " " + entry.Error
+					}
+				}
+
+				_ = table.Append(entry.Time.Local().Format(time.DateTime), userName, entry.Remote, entry.Method+" "+entry.Path, result)
+			}
+
+			_ = table.Render()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&since, "since", 0, "Only show entries that are newer than this")
+	cmd.Flags().StringVar(&user, "user", "", "Only show entries of this user")
+	cmd.Flags().IntVar(&limit, "limit", 100, "Show at most this many entries, 0 for all")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the entries as JSON, including the request bodies")
+
+	return cmd
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/events"
+)
+
+func eventsCommand() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Follows the changes of all resources",
+		Long: `Prints changes of the iSCSI, NFS and NVMe-oF resources as they happen:
+resources being created or deleted, services being started or stopped,
+resources moving to another node, and changes of the resource state.
+
+The server checks for changes every few seconds, so short flaps between two
+checks are not reported.`,
+		Example: `linstor-gateway events
+linstor-gateway events --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+
+			ch, err := cli.Watch(ctx)
+			if err != nil {
+				return err
+			}
+
+			enc := json.NewEncoder(os.Stdout)
+			for event := range ch {
+				if asJSON {
+					if err := enc.Encode(event); err != nil {
+						return err
+					}
+					continue
+				}
+
+				fmt.Printf("%s  %-7s %s: %s\n", event.Time.Local().Format(time.DateTime), event.Kind, event.Name, describeEvent(event))
+			}
+
+			if ctx.Err() == nil {
+				return errors.New("connection to the server was lost")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the events as JSON, one per line")
+
+	return cmd
+}
+
+func describeEvent(event events.Event) string {
+	switch event.Type {
+	case events.ResourceCreated:
+		return colorOk("created")
+	case events.ResourceDeleted:
+		return colorBad("deleted")
+	case events.ServiceStarted:
+		if event.Status.Primary == "" {
+			return ColorServiceState(event.Status.Service, "started")
+		}
+		return ColorServiceState(event.Status.Service, "started") + " on " + event.Status.Primary
+	case events.ServiceStopped:
+		return ColorServiceState(event.Status.Service, "stopped")
+	case events.PrimaryMoved:
+		return fmt.Sprintf("moved from %s to %s", event.Previous.Primary, event.Status.Primary)
+	case events.StateChanged:
+		if event.Previous.State == event.Status.State {
+			return "volume states are now " + describeVolumes(event.Status)
+		}
+		return fmt.Sprintf("state changed from %s to %s",
+			ColorResourceState(event.Previous.State, event.Previous.State.String()),
+			ColorResourceState(event.Status.State, event.Status.State.String()))
+	}
+
+	return string(event.Type)
+}
+
+func describeVolumes(status *common.ResourceStatus) string {
+	var parts []string
+	for _, vol := range status.Volumes {
+		parts = append(parts, fmt.Sprintf("%d: %s", vol.Number, ColorResourceState(vol.State, vol.State.String())))
+	}
+	return strings.Join(parts, ", ")
+}
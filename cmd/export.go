@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/LINBIT/linstor-gateway/pkg/manifest"
+	"github.com/LINBIT/linstor-gateway/pkg/prompt"
+)
+
+func exportCommand() *cobra.Command {
+	var output string
+	var showSecrets bool
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports the configuration of all resources",
+		Long: `Exports the configuration of all iSCSI targets, NFS exports and NVMe-oF
+targets, together with their drbd-reactor configuration files, into a single
+archive.
+
+The archive can be used to recreate the resources on a new LINSTOR cluster
+with "linstor-gateway import". It only contains the configuration, not the
+data stored on the volumes.
+
+CHAP passwords of iSCSI targets and DH-HMAC-CHAP keys of NVMe-oF targets are
+left out unless --show-secrets is given.`,
+		Example: "linstor-gateway export -o gateway-backup.json",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			archive, err := cli.Export.Get(ctx, showSecrets)
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(archive, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode archive: %w", err)
+			}
+			data = append(data, '\n')
+
+			if output == "" || output == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+
+			err = os.WriteFile(output, data, 0o600)
+			if err != nil {
+				return fmt.Errorf("failed to write archive: %w", err)
+			}
+
+			fmt.Fprintf(os.Stderr, "Exported %d iSCSI targets, %d NFS exports and %d NVMe-oF targets to %s\n",
+				len(archive.ISCSI), len(archive.NFS), len(archive.NVMeoF), output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write the archive to (default stdout)")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Include CHAP passwords of iSCSI targets and DH-HMAC-CHAP keys of NVMe-oF targets")
+
+	return cmd
+}
+
+func importCommand() *cobra.Command {
+	var file string
+	var dryRun, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "import -f FILE",
+		Short: "Recreates resources from an exported archive",
+		Long: `Recreates the iSCSI targets, NFS exports and NVMe-oF targets from an
+archive written by "linstor-gateway export".
+
+Resources that already exist are left alone. New resources are created with
+empty volumes, the data has to be restored separately.`,
+		Example: "linstor-gateway import -f gateway-backup.json",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read archive: %w", err)
+			}
+
+			archive, err := manifest.ParseArchive(data)
+			if err != nil {
+				return err
+			}
+
+			if archive.Redacted {
+				fmt.Printf("%s: The archive was exported without secrets, iSCSI targets using CHAP cannot be recreated.\n",
+					color.YellowString("WARNING"))
+			}
+
+			creates, err := planApply(ctx, &archive.Manifest, false, false)
+			if err != nil {
+				return err
+			}
+
+			total := len(archive.ISCSI) + len(archive.NFS) + len(archive.NVMeoF)
+			if skipped := total - len(creates); skipped > 0 {
+				fmt.Printf("Skipping %d resources that already exist\n", skipped)
+			}
+
+			if len(creates) == 0 {
+				fmt.Println("Nothing to do, all resources exist")
+				return nil
+			}
+
+			fmt.Println("Planned changes:")
+			for _, step := range creates {
+				fmt.Println(step)
+			}
+
+			if dryRun {
+				return nil
+			}
+
+			if !yes && !prompt.Confirm("Continue?") {
+				fmt.Println("Aborted")
+				return nil
+			}
+
+			for _, step := range creates {
+				err := step.run(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to create %s %s: %w", step.kind, step.id, err)
+				}
+				fmt.Printf("Created %s %s\n", step.kind, step.id)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "Archive to import")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the planned changes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Import without prompting for confirmation")
+	cmd.MarkFlagRequired("filename")
+
+	return cmd
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -47,22 +48,34 @@ as well as drbd-reactor is a prerequisite to use this tool.`,
 
 	rootCmd.AddCommand(createISCSICommand())
 	rootCmd.AddCommand(deleteISCSICommand())
+	rootCmd.AddCommand(updateISCSICommand())
 	rootCmd.AddCommand(listISCSICommand())
 	rootCmd.AddCommand(startISCSICommand())
 	rootCmd.AddCommand(stopISCSICommand())
+	rootCmd.AddCommand(migrateISCSICommand())
 	rootCmd.AddCommand(addVolumeISCSICommand())
 	rootCmd.AddCommand(deleteVolumeISCSICommand())
+	rootCmd.AddCommand(resizeISCSICommand())
+	rootCmd.AddCommand(cloneISCSICommand())
 	rootCmd.AddCommand(upgradeISCSICommand())
+	rootCmd.AddCommand(snapshotCommands(iscsiSnapshotClient()))
+	rootCmd.AddCommand(scheduleCommands(iscsiSnapshotClient()))
+	rootCmd.AddCommand(replicationCommands(iscsiSnapshotClient()))
+	rootCmd.AddCommand(promoteDRISCSICommand())
 
 	return rootCmd
 }
 
 func createISCSICommand() *cobra.Command {
 	var username, password, group string
+	var mutualUsername, mutualPassword string
+	var initiatorCredentials map[string]string
+	var inlineSecrets bool
 	var serviceIps []common.IpCidr
 	var allowedInitiators []string
 	var grossSize bool
 	var implementation string
+	var preferredNodes, forbiddenNodes []string
 	var resourceTimeout time.Duration
 
 	cmd := &cobra.Command{
@@ -73,7 +86,30 @@ At first it creates a new resource within the LINSTOR system, using the
 specified resource group. The name of the linstor resources is derived
 from the IQN's World Wide Name, which must be unique.
 After that it creates a configuration for drbd-reactor to manage the
-high availability primitives.`,
+high availability primitives.
+
+The drbd-reactor configuration is readable by every LINSTOR user, so the
+CHAP passwords are not stored in it. Instead, they are read from root-only
+files on each node, which have to be created before the target, e.g.
+/etc/linstor-gateway/secrets/target_example:
+
+    OCF_RESKEY_incoming_password=...
+
+Mutual and per-initiator CHAP are applied by the iscsi-auth resource agent
+shipped with linstor-gateway, which reads its passwords from
+/etc/linstor-gateway/secrets/auth_example:
+
+    OCF_RESKEY_outgoing_password=...
+    OCF_RESKEY_initiator_passwords=PASSWORD1;PASSWORD2
+
+The initiator passwords are given in the order of --allowed-initiators.
+Per-initiator CHAP requires the "lio-t" implementation. Reading the files
+requires the following systemd drop-in on every node, installed as
+/etc/systemd/system/ocf.rs@.service.d/linstor-gateway-secrets.conf:
+
+` + common.SecretsDropIn + `
+Only the CHAP usernames are given on the command line then. To store the
+passwords in LINSTOR anyway, pass them together with --inline-secrets.`,
 		Example: `linstor-gateway iscsi create iqn.2019-08.com.linbit:example 192.168.122.181/24 2G`,
 		Args:    cobra.MinimumNArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -115,18 +151,47 @@ high availability primitives.`,
 				allowedInitiatorIqns = append(allowedInitiatorIqns, iqn)
 			}
 
-			_, err = cli.Iscsi.Create(ctx, &iscsi.ResourceConfig{
-				IQN:               iqn,
-				Username:          username,
-				Password:          password,
-				ServiceIPs:        serviceIps,
-				Volumes:           volumes,
-				AllowedInitiators: allowedInitiatorIqns,
-				ResourceGroup:     group,
-				GrossSize:         grossSize,
-				Implementation:    implementation,
-				ResourceTimeout:   resourceTimeout,
-			})
+			credentials := make(map[iscsi.Iqn]iscsi.CHAPCredentials)
+			initiatorPasswords := false
+			for rawIqn, rawCreds := range initiatorCredentials {
+				iqn, err := iscsi.NewIqn(rawIqn)
+				if err != nil {
+					return fmt.Errorf("invalid IQN '%s' for initiator credentials: %w", rawIqn, err)
+				}
+
+				user, pass, ok := strings.Cut(rawCreds, ":")
+				if !ok && inlineSecrets {
+					return fmt.Errorf("invalid credentials for initiator '%s': expected USERNAME:PASSWORD", rawIqn)
+				}
+				credentials[iqn] = iscsi.CHAPCredentials{Username: user, Password: pass}
+				initiatorPasswords = initiatorPasswords || pass != ""
+			}
+
+			rsc := &iscsi.ResourceConfig{
+				IQN:                  iqn,
+				Username:             username,
+				Password:             password,
+				MutualUsername:       mutualUsername,
+				MutualPassword:       mutualPassword,
+				ServiceIPs:           serviceIps,
+				Volumes:              volumes,
+				AllowedInitiators:    allowedInitiatorIqns,
+				InitiatorCredentials: credentials,
+				InlineSecrets:        inlineSecrets,
+				ResourceGroup:        group,
+				GrossSize:            grossSize,
+				Implementation:       implementation,
+				PreferredNodes:       preferredNodes,
+				ForbiddenNodes:       forbiddenNodes,
+				ResourceTimeout:      resourceTimeout,
+			}
+
+			// passwords used to be stored in LINSTOR without asking
+			if !inlineSecrets && (password != "" || mutualPassword != "" || initiatorPasswords) {
+				return fmt.Errorf("CHAP passwords are only stored in LINSTOR with --inline-secrets: pass --inline-secrets, or leave the passwords out and write them to %s and %s on every node (see --help)", rsc.SecretsPath(), rsc.AuthSecretsPath())
+			}
+
+			_, err = cli.Iscsi.Create(ctx, rsc)
 			if err != nil {
 				hintCheckHealth()
 				return err
@@ -139,7 +204,11 @@ high availability primitives.`,
 	}
 
 	cmd.Flags().StringVarP(&username, "username", "u", "", "Set the username to use for CHAP authentication")
-	cmd.Flags().StringVarP(&password, "password", "p", "", "Set the password to use for CHAP authentication")
+	cmd.Flags().StringVarP(&password, "password", "p", "", "Set the password to use for CHAP authentication, only with --inline-secrets")
+	cmd.Flags().StringVar(&mutualUsername, "mutual-username", "", "Set the username the target uses to authenticate itself to initiators (mutual CHAP)")
+	cmd.Flags().StringVar(&mutualPassword, "mutual-password", "", "Set the password the target uses to authenticate itself to initiators (mutual CHAP), only with --inline-secrets")
+	cmd.Flags().StringToStringVar(&initiatorCredentials, "initiator-credentials", nil, "Set CHAP usernames for individual allowed initiators, as IQN=USERNAME, or IQN=USERNAME:PASSWORD with --inline-secrets (lio-t only)")
+	cmd.Flags().BoolVar(&inlineSecrets, "inline-secrets", false, "Store the CHAP passwords in LINSTOR, where every LINSTOR user can read them, instead of a root-only file on each node (see above)")
 	cmd.Flags().StringVarP(&group, "deprecated-resource-group", "g", "DfltRscGrp", "Set the LINSTOR resource group")
 	_ = cmd.Flags().MarkHidden("deprecated-resource-group")
 	_ = cmd.Flags().MarkShorthandDeprecated("deprecated-resource-group", "use -r instead")
@@ -147,6 +216,8 @@ high availability primitives.`,
 	cmd.Flags().StringSliceVar(&allowedInitiators, "allowed-initiators", []string{}, "Restrict which initiator IQNs are allowed to connect to the target")
 	cmd.Flags().BoolVar(&grossSize, "gross", false, "Make all size options specify gross size, i.e. the actual space used on disk")
 	cmd.Flags().StringVar(&implementation, "implementation", "", `Set the iSCSI target implementation to use ("iet", "tgt", "lio", "lio-t", or "scst")`)
+	cmd.Flags().StringSliceVar(&preferredNodes, "preferred-nodes", nil, "Nodes to run the target on, in order of preference")
+	cmd.Flags().StringSliceVar(&forbiddenNodes, "forbidden-nodes", nil, "Nodes to never migrate the target to, and to only fail over to if no other node can")
 	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", iscsi.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
 
 	return cmd
@@ -310,6 +381,92 @@ func stopISCSICommand() *cobra.Command {
 	return cmd
 }
 
+func migrateISCSICommand() *cobra.Command {
+	var node string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "migrate IQN --to NODE",
+		Short: "Moves a running iSCSI target to another node",
+		Long: `Moves a running iSCSI target to another node, for example to free the current
+node for maintenance. The target is stopped on its current node and started on
+the given node, which needs an up to date replica of the target's volumes.
+Initiators see a short interruption, as with any failover.
+The given node stays the preferred node of the target until its configuration is
+changed the next time.`,
+		Example: "linstor-gateway iscsi migrate iqn.2019-08.com.linbit:example --to node2",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			iqn, err := iscsi.NewIqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			_, err = cli.Iscsi.Migrate(context.Background(), iqn, node, resourceTimeout)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Migrated target \"%s\" to node %s\n", iqn, node)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&node, "to", "", "Node to move the target to")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", iscsi.DefaultResourceTimeout, "Timeout for waiting for the resource to become available on the new node")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func updateISCSICommand() *cobra.Command {
+	var allowedInitiators []string
+
+	cmd := &cobra.Command{
+		Use:   "update IQN",
+		Short: "Changes the settings of an existing iSCSI target",
+		Long: `Changes the settings of an existing iSCSI target, without deleting and
+recreating it. Only the settings given as flags are changed.
+An empty --allowed-initiators allows any initiator to connect. CHAP credentials
+of initiators that are no longer allowed are removed.`,
+		Example: "linstor-gateway iscsi update iqn.2019-08.com.linbit:example --allowed-initiators iqn.1993-08.org.debian:01:hv1,iqn.1993-08.org.debian:01:hv2",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			iqn, err := iscsi.NewIqn(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid IQN '%s': %w", args[0], err)
+			}
+
+			patch := &iscsi.ResourcePatch{}
+			if cmd.Flags().Changed("allowed-initiators") {
+				initiators := []iscsi.Iqn{}
+				for _, i := range allowedInitiators {
+					initiator, err := iscsi.NewIqn(i)
+					if err != nil {
+						return fmt.Errorf("invalid IQN '%s' for allowed initiator: %w", i, err)
+					}
+					initiators = append(initiators, initiator)
+				}
+				patch.AllowedInitiators = &initiators
+			} else {
+				return errors.New("nothing to update")
+			}
+
+			_, err = cli.Iscsi.Patch(context.Background(), iqn, patch)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Updated target %q\n", iqn)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&allowedInitiators, "allowed-initiators", nil, "Replace the initiator IQNs that are allowed to connect to the target")
+
+	return cmd
+}
+
 func deleteISCSICommand() *cobra.Command {
 	var force bool
 	var resourceTimeout time.Duration
@@ -451,6 +608,161 @@ func deleteVolumeISCSICommand() *cobra.Command {
 	}
 }
 
+func resizeISCSICommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resize IQN LU_NR NEW_SIZE",
+		Short: "Grow a logical unit of an existing iSCSI target",
+		Long: `Grow a logical unit of an existing iSCSI target. The target can keep running
+while the logical unit is resized. With the "lio-t" implementation, the target
+reports the new size right away; with "scst", run "scstadmin -resync_dev" on
+the node running the target, or restart it. Initiators need to rescan the
+session to see the new size. Shrinking a logical unit is not supported.`,
+		Example: "linstor-gateway iscsi resize iqn.2019-08.com.linbit:example 1 4G",
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			iqn, err := iscsi.NewIqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			volNr, err := strconv.Atoi(args[1])
+			if err != nil {
+				return err
+			}
+
+			size, err := unit.MustNewUnit(unit.DefaultUnits).ValueFromString(args[2])
+			if err != nil {
+				return err
+			}
+
+			_, err = cli.Iscsi.ResizeLogicalUnit(context.Background(), iqn, volNr, uint64(size.Value/unit.K))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Resized volume %d of \"%s\"\n", volNr, iqn)
+			return nil
+		},
+	}
+}
+
+func cloneISCSICommand() *cobra.Command {
+	var snapshot string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "clone SRC_IQN NEW_IQN NEW_SERVICE_IPS",
+		Short: "Creates a new iSCSI target from a snapshot of an existing one",
+		Long: `Creates a new iSCSI target from a snapshot of an existing one.
+The new target gets the same logical units, allowed initiators and CHAP
+credentials as the source target, but its own IQN, service IPs and serial
+numbers.
+If no snapshot is given via --snapshot, a new snapshot of the source target
+is taken. That snapshot is kept, as some storage backends need it for as long
+as the clone exists.`,
+		Example: `linstor-gateway iscsi clone iqn.2019-08.com.linbit:example iqn.2019-08.com.linbit:test 192.168.122.182/24`,
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, err := iscsi.NewIqn(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid IQN '%s': %w", args[0], err)
+			}
+
+			iqn, err := iscsi.NewIqn(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid IQN '%s': %w", args[1], err)
+			}
+
+			var serviceIps []common.IpCidr
+			for _, ipString := range strings.Split(args[2], ",") {
+				ip, err := common.ServiceIPFromString(ipString)
+				if err != nil {
+					return fmt.Errorf("invalid service IP '%s': %w", ipString, err)
+				}
+				serviceIps = append(serviceIps, ip)
+			}
+
+			_, err = cli.Iscsi.Clone(context.Background(), src, snapshot, &iscsi.ResourceConfig{
+				IQN:             iqn,
+				ServiceIPs:      serviceIps,
+				ResourceTimeout: resourceTimeout,
+			})
+			if err != nil {
+				hintCheckHealth()
+				return err
+			}
+
+			fmt.Printf("Created iSCSI target '%s' from '%s'\n", iqn, src)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&snapshot, "snapshot", "s", "", "Clone from this snapshot instead of taking a new one")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", iscsi.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
+
+	return cmd
+}
+
+func promoteDRISCSICommand() *cobra.Command {
+	var snapshot, group string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "promote-dr IQN [SERVICE_IPS]",
+		Short: "Brings up a replicated iSCSI target on this cluster",
+		Long: `Brings up an iSCSI target that was replicated to this cluster from another
+one, using the latest shipped snapshot unless --snapshot is given.
+The target keeps its logical units, allowed initiators and CHAP usernames.
+The CHAP passwords are not replicated, they are read from the secrets file
+on the nodes of this cluster instead (see "iscsi create --help").
+If SERVICE_IPS are given, they replace the service IPs of the original
+target.
+Replication is not set up for the promoted target; use "iscsi replication
+set" to replicate it back once the other site is available again.`,
+		Example: `linstor-gateway iscsi promote-dr iqn.2019-08.com.linbit:example 10.20.0.181/24`,
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			iqn, err := iscsi.NewIqn(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid IQN '%s': %w", args[0], err)
+			}
+
+			var serviceIps []common.IpCidr
+			if len(args) > 1 {
+				for _, ipString := range strings.Split(args[1], ",") {
+					ip, err := common.ServiceIPFromString(ipString)
+					if err != nil {
+						return fmt.Errorf("invalid service IP '%s': %w", ipString, err)
+					}
+					serviceIps = append(serviceIps, ip)
+				}
+			}
+
+			_, err = cli.Iscsi.PromoteDR(context.Background(), iqn, snapshot, &iscsi.ResourceConfig{
+				IQN:             iqn,
+				ServiceIPs:      serviceIps,
+				ResourceGroup:   group,
+				ResourceTimeout: resourceTimeout,
+			})
+			if err != nil {
+				hintCheckHealth()
+				return err
+			}
+
+			fmt.Printf("Promoted iSCSI target '%s'\n", iqn)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&snapshot, "snapshot", "s", "", "Promote from this snapshot instead of the latest one")
+	cmd.Flags().StringVarP(&group, "resource-group", "r", "", "Set the LINSTOR resource group (default: that of the replica)")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", iscsi.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
+
+	return cmd
+}
+
 func upgradeISCSICommand() *cobra.Command {
 	var forceYes bool
 	var dryRun bool
@@ -482,3 +794,93 @@ func upgradeISCSICommand() *cobra.Command {
 
 	return cmd
 }
+
+func iscsiSnapshotClient() snapshotClient {
+	return snapshotClient{
+		command:                "iscsi",
+		kind:                   "iSCSI target",
+		idName:                 "IQN",
+		example:                "iqn.2019-08.com.linbit:example",
+		defaultResourceTimeout: iscsi.DefaultResourceTimeout,
+		create: func(ctx context.Context, id, snapshot string) (*common.Snapshot, error) {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.Iscsi.CreateSnapshot(ctx, iqn, snapshot)
+		},
+		list: func(ctx context.Context, id string) ([]common.Snapshot, error) {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.Iscsi.ListSnapshots(ctx, iqn)
+		},
+		delete: func(ctx context.Context, id, snapshot string) error {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return err
+			}
+			return cli.Iscsi.DeleteSnapshot(ctx, iqn, snapshot)
+		},
+		rollback: func(ctx context.Context, id, snapshot string, resourceTimeout time.Duration) error {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return err
+			}
+			_, err = cli.Iscsi.RollbackSnapshot(ctx, iqn, snapshot, resourceTimeout)
+			return err
+		},
+		getSchedule: func(ctx context.Context, id string) (*common.SnapshotSchedule, error) {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.Iscsi.SnapshotSchedule(ctx, iqn)
+		},
+		setSchedule: func(ctx context.Context, id string, schedule *common.SnapshotSchedule) error {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return err
+			}
+			_, err = cli.Iscsi.SetSnapshotSchedule(ctx, iqn, schedule)
+			return err
+		},
+		deleteSchedule: func(ctx context.Context, id string) error {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return err
+			}
+			return cli.Iscsi.DeleteSnapshotSchedule(ctx, iqn)
+		},
+		getReplication: func(ctx context.Context, id string) (*common.Replication, error) {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.Iscsi.Replication(ctx, iqn)
+		},
+		setReplication: func(ctx context.Context, id string, replication *common.Replication) error {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return err
+			}
+			_, err = cli.Iscsi.SetReplication(ctx, iqn, replication)
+			return err
+		},
+		deleteReplication: func(ctx context.Context, id string) error {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return err
+			}
+			return cli.Iscsi.DeleteReplication(ctx, iqn)
+		},
+		ship: func(ctx context.Context, id string) (*common.Snapshot, error) {
+			iqn, err := iscsi.NewIqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.Iscsi.ShipSnapshot(ctx, iqn)
+		},
+	}
+}
@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/LINBIT/linstor-gateway/pkg/common"
@@ -44,8 +46,17 @@ See "help nfs create" for more information`,
 
 	rootCmd.AddCommand(createNFSCommand())
 	rootCmd.AddCommand(deleteNFSCommand())
+	rootCmd.AddCommand(updateNFSCommand())
+	rootCmd.AddCommand(migrateNFSCommand())
 	rootCmd.AddCommand(listNFSCommand())
+	rootCmd.AddCommand(addVolumeNFSCommand())
+	rootCmd.AddCommand(resizeNFSCommand())
+	rootCmd.AddCommand(cloneNFSCommand())
 	rootCmd.AddCommand(upgradeNFSCommand())
+	rootCmd.AddCommand(snapshotCommands(nfsSnapshotClient()))
+	rootCmd.AddCommand(scheduleCommands(nfsSnapshotClient()))
+	rootCmd.AddCommand(replicationCommands(nfsSnapshotClient()))
+	rootCmd.AddCommand(promoteDRNFSCommand())
 
 	return rootCmd
 
@@ -66,6 +77,7 @@ func createNFSCommand() *cobra.Command {
 	grossSize := false
 	filesystem := "ext4"
 	implementation := nfs.DefaultImplementation
+	var preferredNodes, forbiddenNodes []string
 	var resourceTimeout time.Duration
 
 	cmd := &cobra.Command{
@@ -141,6 +153,8 @@ linstor-gateway nfs create multi 172.16.16.55/24 1G 2G --export-path /music --ex
 				GrossSize:       grossSize,
 				ResourceTimeout: resourceTimeout,
 				Implementation:  implementation,
+				PreferredNodes:  preferredNodes,
+				ForbiddenNodes:  forbiddenNodes,
 			}
 			_, err = cli.Nfs.Create(ctx, rsc)
 			if err != nil {
@@ -160,6 +174,8 @@ linstor-gateway nfs create multi 172.16.16.55/24 1G 2G --export-path /music --ex
 	cmd.Flags().StringVarP(&filesystem, "filesystem", "f", filesystem, "File system type to use (ext4 or xfs)")
 	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", nfs.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
 	cmd.Flags().StringVar(&implementation, "implementation", implementation, fmt.Sprintf("NFS server implementation to use (%q or %q)", nfs.ImplementationKernel, nfs.ImplementationGanesha))
+	cmd.Flags().StringSliceVar(&preferredNodes, "preferred-nodes", nil, "Nodes to run the export on, in order of preference")
+	cmd.Flags().StringSliceVar(&forbiddenNodes, "forbidden-nodes", nil, "Nodes to never migrate the export to, and to only fail over to if no other node can")
 
 	return cmd
 }
@@ -296,6 +312,297 @@ overview about the existing LINSTOR resources and service status.`,
 	}
 }
 
+func addVolumeNFSCommand() *cobra.Command {
+	var exportPath string
+	filesystem := "ext4"
+
+	cmd := &cobra.Command{
+		Use:   "add-volume NAME [VOLUME_NR] VOLUME_SIZE",
+		Short: "Add a new volume to an existing NFS export",
+		Long: `Add a new volume to an existing NFS export. The export needs to be started.
+The filesystem is created on the node where the export is currently active, so the
+command must be sent to the LINSTOR Gateway server running on that node
+(see --connect).
+If VOLUME_NR is omitted, the next available volume number is used automatically.`,
+		Example: "linstor-gateway nfs add-volume example 2G --export-path /projects",
+		Args:    cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			resourceName := args[0]
+
+			var volNr int
+			var sizeArg string
+			var err error
+
+			if len(args) == 3 {
+				volNr, err = strconv.Atoi(args[1])
+				if err != nil {
+					return err
+				}
+				sizeArg = args[2]
+			} else {
+				// Auto-increment: fetch existing export and find the next volume number
+				cfg, err := cli.Nfs.Get(ctx, resourceName)
+				if err != nil {
+					return fmt.Errorf("failed to get existing export: %w", err)
+				}
+				if cfg == nil {
+					return fmt.Errorf("export %q not found", resourceName)
+				}
+
+				maxVol := 0
+				for _, v := range cfg.Volumes {
+					if v.Number > maxVol {
+						maxVol = v.Number
+					}
+				}
+				volNr = maxVol + 1
+				sizeArg = args[1]
+			}
+
+			size, err := unit.MustNewUnit(unit.DefaultUnits).ValueFromString(sizeArg)
+			if err != nil {
+				return err
+			}
+
+			if exportPath == "" {
+				exportPath = fmt.Sprintf("/vol%d", volNr)
+			}
+
+			_, err = cli.Nfs.AddVolume(ctx, resourceName, &nfs.VolumeConfig{
+				ExportPath: exportPath,
+				VolumeConfig: common.VolumeConfig{
+					Number:              volNr,
+					SizeKiB:             uint64(size.Value / unit.K),
+					FileSystem:          filesystem,
+					FileSystemRootOwner: common.UserGroup{User: "nobody", Group: "nobody"},
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Added volume %d to %q\n", volNr, resourceName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&exportPath, "export-path", "p", "", fmt.Sprintf("Set the export path, relative to %s (default \"/vol<VOLUME_NR>\")", nfs.ExportBasePath))
+	cmd.Flags().StringVarP(&filesystem, "filesystem", "f", filesystem, "File system type to use (ext4 or xfs)")
+
+	return cmd
+}
+
+func updateNFSCommand() *cobra.Command {
+	var allowedIPs []string
+
+	cmd := &cobra.Command{
+		Use:   "update NAME",
+		Short: "Changes the settings of an existing NFS export",
+		Long: `Changes the settings of an existing NFS export, without deleting and
+recreating it. Only the settings given as flags are changed.
+An empty --allowed-ips allows all clients to access the export.`,
+		Example: "linstor-gateway nfs update restricted --allowed-ips 10.10.0.0/16,10.20.0.0/16",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resourceName := args[0]
+
+			patch := &nfs.ResourcePatch{}
+			if cmd.Flags().Changed("allowed-ips") {
+				cidrs := []common.IpCidr{}
+				for _, raw := range allowedIPs {
+					cidr, err := common.ServiceIPFromString(raw)
+					if err != nil {
+						return fmt.Errorf("invalid allowed IP '%s': %w", raw, err)
+					}
+					cidrs = append(cidrs, cidr)
+				}
+				patch.AllowedIPs = &cidrs
+			} else {
+				return errors.New("nothing to update")
+			}
+
+			_, err := cli.Nfs.Patch(context.Background(), resourceName, patch)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Updated export %q\n", resourceName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&allowedIPs, "allowed-ips", nil, "Replace the IP address masks of clients that are allowed access")
+
+	return cmd
+}
+
+func migrateNFSCommand() *cobra.Command {
+	var node string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "migrate NAME --to NODE",
+		Short: "Moves a running NFS export to another node",
+		Long: `Moves a running NFS export to another node, for example to free the current
+node for maintenance. The export is stopped on its current node and started on
+the given node, which needs an up to date replica of the export's volumes.
+Clients see a short interruption, as with any failover.
+The given node stays the preferred node of the export until its configuration is
+changed the next time.`,
+		Example: "linstor-gateway nfs migrate example --to node2",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resourceName := args[0]
+
+			_, err := cli.Nfs.Migrate(context.Background(), resourceName, node, resourceTimeout)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Migrated export %q to node %s\n", resourceName, node)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&node, "to", "", "Node to move the export to")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", nfs.DefaultResourceTimeout, "Timeout for waiting for the resource to become available on the new node")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func resizeNFSCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resize NAME VOLUME_NR NEW_SIZE",
+		Short: "Grow a volume of an existing NFS export",
+		Long: `Grow a volume of an existing NFS export, including the filesystem on it.
+The export needs to be started, and stays available while it is resized.
+The filesystem is grown on the node where it is currently mounted, so the
+command must be sent to the LINSTOR Gateway server running on that node
+(see --connect).
+Shrinking a volume is not supported.`,
+		Example: "linstor-gateway nfs resize example 1 4G",
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resourceName := args[0]
+
+			volNr, err := strconv.Atoi(args[1])
+			if err != nil {
+				return err
+			}
+
+			size, err := unit.MustNewUnit(unit.DefaultUnits).ValueFromString(args[2])
+			if err != nil {
+				return err
+			}
+
+			_, err = cli.Nfs.ResizeVolume(context.Background(), resourceName, volNr, uint64(size.Value/unit.K))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Resized volume %d of %q\n", volNr, resourceName)
+			return nil
+		},
+	}
+}
+
+func cloneNFSCommand() *cobra.Command {
+	var snapshot string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "clone SRC_NAME NEW_NAME NEW_SERVICE_IP",
+		Short: "Creates a new NFS export from a snapshot of an existing one",
+		Long: `Creates a new NFS export from a snapshot of an existing one.
+The new export gets the same volumes, export paths and allowed IPs as the
+source export, but its own name and service IP.
+If no snapshot is given via --snapshot, a new snapshot of the source export
+is taken. That snapshot is kept, as some storage backends need it for as long
+as the clone exists.
+
+With the kernel NFS implementation, only one NFS resource can exist in a
+cluster, so only exports using --implementation=ganesha can be cloned.`,
+		Example: `linstor-gateway nfs clone example test 192.168.211.123/24`,
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serviceIP, err := common.ServiceIPFromString(args[2])
+			if err != nil {
+				return err
+			}
+
+			_, err = cli.Nfs.Clone(context.Background(), args[0], snapshot, &nfs.ResourceConfig{
+				Name:            args[1],
+				ServiceIP:       serviceIP,
+				ResourceTimeout: resourceTimeout,
+			})
+			if err != nil {
+				hintCheckHealth()
+				return err
+			}
+
+			fmt.Printf("Created export '%s' from '%s'\n", args[1], args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&snapshot, "snapshot", "s", "", "Clone from this snapshot instead of taking a new one")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", nfs.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
+
+	return cmd
+}
+
+func promoteDRNFSCommand() *cobra.Command {
+	var snapshot, resourceGroup string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "promote-dr NAME [SERVICE_IP]",
+		Short: "Brings up a replicated NFS export on this cluster",
+		Long: `Brings up an NFS export that was replicated to this cluster from another
+one, using the latest shipped snapshot unless --snapshot is given.
+The export keeps its volumes, export paths and allowed IPs. If SERVICE_IP is
+given, it replaces the service IP of the original export.
+Replication is not set up for the promoted export; use "nfs replication set"
+to replicate it back once the other site is available again.`,
+		Example: `linstor-gateway nfs promote-dr example 10.20.0.122/24`,
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var serviceIP common.IpCidr
+			if len(args) > 1 {
+				var err error
+				serviceIP, err = common.ServiceIPFromString(args[1])
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err := cli.Nfs.PromoteDR(context.Background(), args[0], snapshot, &nfs.ResourceConfig{
+				Name:            args[0],
+				ServiceIP:       serviceIP,
+				ResourceGroup:   resourceGroup,
+				ResourceTimeout: resourceTimeout,
+			})
+			if err != nil {
+				hintCheckHealth()
+				return err
+			}
+
+			fmt.Printf("Promoted export '%s'\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&snapshot, "snapshot", "s", "", "Promote from this snapshot instead of the latest one")
+	cmd.Flags().StringVarP(&resourceGroup, "resource-group", "r", "", "Set the LINSTOR resource group (default: that of the replica)")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", nfs.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
+
+	return cmd
+}
+
 func upgradeNFSCommand() *cobra.Command {
 	var forceYes bool
 	var dryRun bool
@@ -323,3 +630,49 @@ func upgradeNFSCommand() *cobra.Command {
 
 	return cmd
 }
+
+func nfsSnapshotClient() snapshotClient {
+	return snapshotClient{
+		command:                "nfs",
+		kind:                   "NFS export",
+		idName:                 "NAME",
+		example:                "example",
+		defaultResourceTimeout: nfs.DefaultResourceTimeout,
+		create: func(ctx context.Context, name, snapshot string) (*common.Snapshot, error) {
+			return cli.Nfs.CreateSnapshot(ctx, name, snapshot)
+		},
+		list: func(ctx context.Context, name string) ([]common.Snapshot, error) {
+			return cli.Nfs.ListSnapshots(ctx, name)
+		},
+		delete: func(ctx context.Context, name, snapshot string) error {
+			return cli.Nfs.DeleteSnapshot(ctx, name, snapshot)
+		},
+		rollback: func(ctx context.Context, name, snapshot string, resourceTimeout time.Duration) error {
+			_, err := cli.Nfs.RollbackSnapshot(ctx, name, snapshot, resourceTimeout)
+			return err
+		},
+		getSchedule: func(ctx context.Context, name string) (*common.SnapshotSchedule, error) {
+			return cli.Nfs.SnapshotSchedule(ctx, name)
+		},
+		setSchedule: func(ctx context.Context, name string, schedule *common.SnapshotSchedule) error {
+			_, err := cli.Nfs.SetSnapshotSchedule(ctx, name, schedule)
+			return err
+		},
+		deleteSchedule: func(ctx context.Context, name string) error {
+			return cli.Nfs.DeleteSnapshotSchedule(ctx, name)
+		},
+		getReplication: func(ctx context.Context, name string) (*common.Replication, error) {
+			return cli.Nfs.Replication(ctx, name)
+		},
+		setReplication: func(ctx context.Context, name string, replication *common.Replication) error {
+			_, err := cli.Nfs.SetReplication(ctx, name, replication)
+			return err
+		},
+		deleteReplication: func(ctx context.Context, name string) error {
+			return cli.Nfs.DeleteReplication(ctx, name)
+		},
+		ship: func(ctx context.Context, name string) (*common.Snapshot, error) {
+			return cli.Nfs.ShipSnapshot(ctx, name)
+		},
+	}
+}
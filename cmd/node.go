@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/spf13/cobra"
+
+	"github.com/LINBIT/linstor-gateway/pkg/maintenance"
+)
+
+func nodeCommands() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "node",
+		Short: "Manages nodes for maintenance",
+		Long: `linstor-gateway node prepares nodes for maintenance, such as kernel updates,
+by moving all gateway resources off them.`,
+		Args: cobra.NoArgs,
+	}
+
+	rootCmd.DisableAutoGenTag = true
+
+	rootCmd.AddCommand(listNodesCommand())
+	rootCmd.AddCommand(evacuateNodeCommand())
+	rootCmd.AddCommand(resumeNodeCommand())
+
+	return rootCmd
+}
+
+func listNodesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   "Lists nodes and whether they are evacuated",
+		Long:    `Lists the LINSTOR nodes and whether they were evacuated by "linstor-gateway node evacuate".`,
+		Example: "linstor-gateway node list",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodes, err := cli.Node.GetAll(context.Background())
+			if err != nil {
+				return err
+			}
+
+			table := tablewriter.NewTable(os.Stdout,
+				tablewriter.WithConfig(tablewriter.NewConfigBuilder().
+					Header().Formatting().WithAutoFormat(tw.Off).Build().Build().
+					Build()),
+			)
+			table.Header(colorHeader("Node"), colorHeader("State"))
+			for _, node := range nodes {
+				state := colorOk("available")
+				if node.Evacuated {
+					state = colorDegraded("evacuated")
+				}
+				_ = table.Append(node.Node, state)
+			}
+			_ = table.Render()
+
+			return nil
+		},
+	}
+}
+
+func evacuateNodeCommand() *cobra.Command {
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "evacuate NODE",
+		Short: "Moves all gateway resources off a node",
+		Long: `Moves every iSCSI target, NFS export and NVMe-oF target running on the node
+to another node with a healthy replica. Afterwards, the gateway resources are
+deactivated on the node, so that drbd-reactor cannot promote them there until
+"linstor-gateway node resume" is run.
+
+Until then, resources created later are deactivated on the node as well, and
+no resource can be migrated to it. "linstor-gateway node list" shows which
+nodes are evacuated.
+
+If any resource running on the node has no healthy replica on another node,
+nothing is changed.`,
+		Example: "linstor-gateway node evacuate node1",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node := args[0]
+
+			report, err := cli.Node.Evacuate(context.Background(), node, resourceTimeout)
+			if err != nil {
+				return err
+			}
+
+			printReport(report)
+
+			if report.Failed() {
+				return errors.New("some resources could not be evacuated, run the command again after fixing the errors")
+			}
+
+			fmt.Printf("Evacuated node %s\n", node)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", maintenance.DefaultResourceTimeout, "Timeout for waiting for each resource to become available on its new node")
+
+	return cmd
+}
+
+func resumeNodeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume NODE",
+		Short: "Allows gateway resources on an evacuated node again",
+		Long: `Activates the gateway resources on a node evacuated by "linstor-gateway
+node evacuate" again, so that drbd-reactor may promote them there.
+Resources are not moved back automatically, use "migrate" for that.`,
+		Example: "linstor-gateway node resume node1",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			node := args[0]
+
+			report, err := cli.Node.Resume(context.Background(), node)
+			if err != nil {
+				return err
+			}
+
+			printReport(report)
+
+			if report.Failed() {
+				return errors.New("some resources could not be activated, run the command again after fixing the errors")
+			}
+
+			fmt.Printf("Resumed node %s\n", node)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func printReport(report *maintenance.Report) {
+	for _, res := range report.Resources {
+		if res.Error != "" {
+			fmt.Printf("%s %s: %s\n", colorBad("✗"), res.Resource, res.Error)
+			continue
+		}
+
+		var done []string
+		if res.MigratedTo != "" {
+			done = append(done, "moved to "+res.MigratedTo)
+		}
+		if res.Deactivated {
+			done = append(done, "deactivated")
+		}
+		if res.Activated {
+			done = append(done, "activated")
+		}
+		if len(done) == 0 {
+			done = append(done, color.New(color.Faint).Sprint("nothing to do"))
+		}
+
+		fmt.Printf("%s %s: %s\n", colorOk("✓"), res.Resource, strings.Join(done, ", "))
+	}
+}
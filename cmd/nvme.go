@@ -43,9 +43,19 @@ func nvmeCommands() *cobra.Command {
 	rootCmd.AddCommand(deleteNVMECommand())
 	rootCmd.AddCommand(startNVMECommand())
 	rootCmd.AddCommand(stopNVMECommand())
+	rootCmd.AddCommand(migrateNVMECommand())
 	rootCmd.AddCommand(addVolumeNVMECommand())
 	rootCmd.AddCommand(deleteVolumeNVMECommand())
+	rootCmd.AddCommand(resizeNVMECommand())
+	rootCmd.AddCommand(cloneNVMECommand())
+	rootCmd.AddCommand(setAllowedHostsNVMECommand())
+	rootCmd.AddCommand(setHostKeyNVMECommand())
+	rootCmd.AddCommand(deleteHostKeyNVMECommand())
 	rootCmd.AddCommand(upgradeNVMECommand())
+	rootCmd.AddCommand(snapshotCommands(nvmeSnapshotClient()))
+	rootCmd.AddCommand(scheduleCommands(nvmeSnapshotClient()))
+	rootCmd.AddCommand(replicationCommands(nvmeSnapshotClient()))
+	rootCmd.AddCommand(promoteDRNVMECommand())
 
 	return rootCmd
 }
@@ -71,6 +81,10 @@ func listNVMECommand() *cobra.Command {
 
 			var degradedResources, badResources []string
 			for _, cfg := range cfgs {
+				serviceIpStrings := make([]string, len(cfg.ServiceIPs))
+				for i := range cfg.ServiceIPs {
+					serviceIpStrings[i] = cfg.ServiceIPs[i].String()
+				}
 				for i, vol := range cfg.Status.Volumes {
 					if i == 0 {
 						log.Debugf("not displaying cluster private volume: %+v", vol)
@@ -82,7 +96,7 @@ func listNVMECommand() *cobra.Command {
 					}
 					_ = table.Append(
 						cfg.NQN.String(),
-						cfg.ServiceIP.String(),
+						strings.Join(serviceIpStrings, ", "),
 						ColorServiceState(cfg.Status.Service, serviceStatus),
 						strconv.Itoa(vol.Number),
 						ColorResourceState(vol.State, vol.State.String()),
@@ -98,7 +112,7 @@ func listNVMECommand() *cobra.Command {
 				if len(cfg.Status.Volumes) == 0 {
 					_ = table.Append(
 						cfg.NQN.String(),
-						cfg.ServiceIP.String(),
+						strings.Join(serviceIpStrings, ", "),
 						ColorServiceState(cfg.Status.Service, cfg.Status.Service.String()),
 						"",
 						ColorResourceState(common.ResourceStateBad, common.ResourceStateBad.String()),
@@ -130,12 +144,37 @@ func listNVMECommand() *cobra.Command {
 func createNVMECommand() *cobra.Command {
 	resourceGroup := "DfltRscGrp"
 	grossSize := false
+	var transport string
+	var port int
+	var allowedHosts []string
+	var preferredNodes, forbiddenNodes []string
 	var resourceTimeout time.Duration
+	var inlineSecrets bool
 
 	cmd := &cobra.Command{
-		Use:     "create NQN SERVICE_IP VOLUME_SIZE [VOLUME_SIZE]...",
-		Short:   "Create a new NVMe-oF target",
-		Long:    `Create a new NVMe-oF target. The NQN consists of <vendor>:nvme:<subsystem>.`,
+		Use:   "create NQN SERVICE_IPS VOLUME_SIZE [VOLUME_SIZE]...",
+		Short: "Create a new NVMe-oF target",
+		Long: `Create a new NVMe-oF target. The NQN consists of <vendor>:nvme:<subsystem>.
+SERVICE_IPS is a comma-separated list of addresses; the target listens on
+each of them, so that hosts can use them as separate multipath paths.
+
+The drbd-reactor configuration is readable by every LINSTOR user, so the
+DH-HMAC-CHAP keys of the allowed hosts are not stored in it. Instead, they
+are read from a root-only file on each node, e.g.
+/etc/linstor-gateway/secrets/auth_example:
+
+    OCF_RESKEY_dhchap_keys=KEY1;KEY2
+    OCF_RESKEY_dhchap_ctrl_keys=CTRL_KEY1;CTRL_KEY2
+
+The keys are given in the order of --allowed-hosts, and are applied by the
+nvmet-auth resource agent shipped with linstor-gateway. Changes to the file
+take effect when the target is restarted. Reading the file requires the
+following systemd drop-in on every node, installed as
+/etc/systemd/system/ocf.rs@.service.d/linstor-gateway-secrets.conf:
+
+` + common.SecretsDropIn + `
+To store the keys in LINSTOR instead and manage them with "set-host-key",
+create the target with --inline-secrets.`,
 		Example: `linstor-gateway nvme create linbit:nvme:example`,
 		Args:    cobra.MinimumNArgs(3),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -144,9 +183,13 @@ func createNVMECommand() *cobra.Command {
 				return err
 			}
 
-			serviceIP, err := common.ServiceIPFromString(args[1])
-			if err != nil {
-				return err
+			var serviceIPs []common.IpCidr
+			for _, ipString := range strings.Split(args[1], ",") {
+				ip, err := common.ServiceIPFromString(ipString)
+				if err != nil {
+					return fmt.Errorf("invalid service IP '%s': %w", ipString, err)
+				}
+				serviceIPs = append(serviceIPs, ip)
 			}
 
 			var volumes []common.VolumeConfig
@@ -161,13 +204,24 @@ func createNVMECommand() *cobra.Command {
 					SizeKiB: uint64(val.Value / unit.K)})
 			}
 
+			hosts, err := parseHostNqns(allowedHosts)
+			if err != nil {
+				return err
+			}
+
 			_, err = cli.NvmeOf.Create(context.Background(), &nvmeof.ResourceConfig{
 				NQN:             nqn,
-				ServiceIP:       serviceIP,
+				ServiceIPs:      serviceIPs,
+				Transport:       transport,
+				Port:            port,
+				AllowedHosts:    hosts,
 				ResourceGroup:   resourceGroup,
 				Volumes:         volumes,
 				GrossSize:       grossSize,
+				PreferredNodes:  preferredNodes,
+				ForbiddenNodes:  forbiddenNodes,
 				ResourceTimeout: resourceTimeout,
+				InlineSecrets:   inlineSecrets,
 			})
 			if err != nil {
 				hintCheckHealth()
@@ -181,7 +235,13 @@ func createNVMECommand() *cobra.Command {
 	}
 	cmd.Flags().StringVarP(&resourceGroup, "resource-group", "r", resourceGroup, "resource group to use.")
 	cmd.Flags().BoolVar(&grossSize, "gross", false, "Make all size options specify gross size, i.e. the actual space used on disk")
+	cmd.Flags().StringVar(&transport, "transport", nvmeof.DefaultTransport, `Set the NVMe-oF transport to use ("tcp" or "rdma")`)
+	cmd.Flags().IntVar(&port, "port", nvmeof.DefaultPort, "Set the port the target listens on")
+	cmd.Flags().StringSliceVar(&allowedHosts, "allowed-hosts", []string{}, "Restrict which host NQNs are allowed to connect to the target")
+	cmd.Flags().StringSliceVar(&preferredNodes, "preferred-nodes", nil, "Nodes to run the target on, in order of preference")
+	cmd.Flags().StringSliceVar(&forbiddenNodes, "forbidden-nodes", nil, "Nodes to never migrate the target to, and to only fail over to if no other node can")
 	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", nvmeof.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
+	cmd.Flags().BoolVar(&inlineSecrets, "inline-secrets", false, "Store the DH-HMAC-CHAP keys in LINSTOR, where every LINSTOR user can read them, instead of a root-only file on each node (see above)")
 
 	return cmd
 }
@@ -314,6 +374,47 @@ func stopNVMECommand() *cobra.Command {
 	return cmd
 }
 
+func migrateNVMECommand() *cobra.Command {
+	var node string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "migrate NQN --to NODE",
+		Short: "Moves a running NVMe-oF target to another node",
+		Long: `Moves a running NVMe-oF target to another node, for example to free the
+current node for maintenance. The target is stopped on its current node and
+started on the given node, which needs an up to date replica of the target's
+volumes. Hosts see a short interruption, as with any failover.
+The given node stays the preferred node of the target until its configuration is
+changed the next time.`,
+		Example: "linstor-gateway nvme migrate nqn.2021-08.com.linbit:nvme:example --to node2",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nqn, err := nvmeof.NewNqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			_, err = cli.NvmeOf.Migrate(context.Background(), nqn, node, resourceTimeout)
+			if err == client.NotFoundError {
+				return noTarget(nqn)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Migrated target \"%s\" to node %s\n", nqn, node)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&node, "to", "", "Node to move the target to")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", nvmeof.DefaultResourceTimeout, "Timeout for waiting for the resource to become available on the new node")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
 func addVolumeNVMECommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "add-volume NQN VOLUME_NR VOLUME_SIZE",
@@ -381,6 +482,294 @@ func deleteVolumeNVMECommand() *cobra.Command {
 	}
 }
 
+func resizeNVMECommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resize NQN VOLUME_NR NEW_SIZE",
+		Short: "Grow a volume of an existing NVMe-oF target",
+		Long: `Grow a volume of an existing NVMe-oF target. The target can keep running
+while the volume is resized; connected hosts are notified about the new size.
+If the target is running, the request has to be sent to the LINSTOR Gateway
+server on the node where it is active. Shrinking a volume is not supported.`,
+		Example: "linstor-gateway nvme resize linbit:nvme:example 1 4G",
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nqn, err := nvmeof.NewNqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			volNr, err := strconv.Atoi(args[1])
+			if err != nil {
+				return err
+			}
+
+			size, err := unit.MustNewUnit(unit.DefaultUnits).ValueFromString(args[2])
+			if err != nil {
+				return err
+			}
+
+			_, err = cli.NvmeOf.ResizeVolume(context.Background(), nqn, volNr, uint64(size.Value/unit.K))
+			if err != nil {
+				if err == client.NotFoundError {
+					return noTarget(nqn)
+				}
+				return err
+			}
+
+			fmt.Printf("Resized volume %d of \"%s\"\n", volNr, nqn)
+			return nil
+		},
+	}
+}
+
+func setAllowedHostsNVMECommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-allowed-hosts NQN [HOST_NQN]...",
+		Short: "Set which hosts may connect to an NVMe-oF target",
+		Long: `Set which hosts may connect to an existing NVMe-oF target. The given host
+NQNs replace the current list. If no host NQN is given, any host may connect.`,
+		Example: "linstor-gateway nvme set-allowed-hosts linbit:nvme:example nqn.2014-08.org.nvmexpress:uuid:4c4c4544-0051-3510-8057-b7c04f325a32",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nqn, err := nvmeof.NewNqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			hosts, err := parseHostNqns(args[1:])
+			if err != nil {
+				return err
+			}
+
+			_, err = cli.NvmeOf.SetAllowedHosts(context.Background(), nqn, hosts)
+			if err != nil {
+				if err == client.NotFoundError {
+					return noTarget(nqn)
+				}
+				return err
+			}
+
+			if len(hosts) == 0 {
+				fmt.Printf("Allowed any host to connect to \"%s\"\n", nqn)
+			} else {
+				fmt.Printf("Set allowed hosts of \"%s\"\n", nqn)
+			}
+			return nil
+		},
+	}
+}
+
+func setHostKeyNVMECommand() *cobra.Command {
+	var controllerKey string
+
+	cmd := &cobra.Command{
+		Use:   "set-host-key NQN HOST_NQN KEY",
+		Short: "Set the DH-HMAC-CHAP key of a host",
+		Long: `Set the DH-HMAC-CHAP key a host has to authenticate with when connecting
+to an NVMe-oF target. Any previous key of the host is replaced, so this is
+also used to rotate keys.
+The host must be one of the allowed hosts of the target. Keys can be
+generated with "nvme gen-dhchap-key".
+If --controller-key is given, the target also authenticates itself to the
+host with that key (bidirectional authentication).
+This only works for targets created with --inline-secrets. Otherwise, the
+keys are read from the secrets file on each node, which has to be changed
+there instead, see "nvme create --help".`,
+		Example: "linstor-gateway nvme set-host-key linbit:nvme:example nqn.2014-08.org.nvmexpress:uuid:4c4c4544-0051-3510-8057-b7c04f325a32 DHHC-1:00:ia5fOr0Ns4fqc/N3tuvPSBnDiFC2MgoVhSXLatQ1/o5Fjw4M:",
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nqn, err := nvmeof.NewNqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			host, err := nvmeof.NewHostNqn(args[1])
+			if err != nil {
+				return err
+			}
+
+			_, err = cli.NvmeOf.SetHostKey(context.Background(), nqn, host, &nvmeof.HostKey{
+				Key:           args[2],
+				ControllerKey: controllerKey,
+			})
+			if err != nil {
+				if err == client.NotFoundError {
+					return noTarget(nqn)
+				}
+				return err
+			}
+
+			fmt.Printf("Set key of host \"%s\" on \"%s\"\n", host, nqn)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&controllerKey, "controller-key", "", "Key the target authenticates itself with")
+
+	return cmd
+}
+
+func deleteHostKeyNVMECommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete-host-key NQN HOST_NQN",
+		Short: "Remove the DH-HMAC-CHAP key of a host",
+		Long: `Remove the DH-HMAC-CHAP key of a host, so that it connects without authentication.
+Like set-host-key, this only works for targets created with --inline-secrets.`,
+		Example: "linstor-gateway nvme delete-host-key linbit:nvme:example nqn.2014-08.org.nvmexpress:uuid:4c4c4544-0051-3510-8057-b7c04f325a32",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nqn, err := nvmeof.NewNqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			host, err := nvmeof.NewHostNqn(args[1])
+			if err != nil {
+				return err
+			}
+
+			err = cli.NvmeOf.DeleteHostKey(context.Background(), nqn, host)
+			if err != nil {
+				if err == client.NotFoundError {
+					return noTarget(nqn)
+				}
+				return err
+			}
+
+			fmt.Printf("Removed key of host \"%s\" from \"%s\"\n", host, nqn)
+			return nil
+		},
+	}
+}
+
+func parseHostNqns(raw []string) ([]nvmeof.HostNqn, error) {
+	var hosts []nvmeof.HostNqn
+	for _, h := range raw {
+		host, err := nvmeof.NewHostNqn(h)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+func cloneNVMECommand() *cobra.Command {
+	var snapshot string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "clone SRC_NQN NEW_NQN NEW_SERVICE_IPS",
+		Short: "Create a new NVMe-oF target from a snapshot of an existing one",
+		Long: `Create a new NVMe-oF target from a snapshot of an existing one.
+The new target gets the same namespaces as the source target, but its own
+NQN, service IPs, serial number and namespace UUIDs.
+If no snapshot is given via --snapshot, a new snapshot of the source target
+is taken. That snapshot is kept, as some storage backends need it for as long
+as the clone exists.`,
+		Example: `linstor-gateway nvme clone linbit:nvme:example linbit:nvme:test 192.168.122.182/24`,
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, err := nvmeof.NewNqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			nqn, err := nvmeof.NewNqn(args[1])
+			if err != nil {
+				return err
+			}
+
+			var serviceIPs []common.IpCidr
+			for _, ipString := range strings.Split(args[2], ",") {
+				ip, err := common.ServiceIPFromString(ipString)
+				if err != nil {
+					return fmt.Errorf("invalid service IP '%s': %w", ipString, err)
+				}
+				serviceIPs = append(serviceIPs, ip)
+			}
+
+			_, err = cli.NvmeOf.Clone(context.Background(), src, snapshot, &nvmeof.ResourceConfig{
+				NQN:             nqn,
+				ServiceIPs:      serviceIPs,
+				ResourceTimeout: resourceTimeout,
+			})
+			if err != nil {
+				hintCheckHealth()
+				return err
+			}
+
+			fmt.Printf("Created target \"%s\" from \"%s\"\n", nqn, src)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&snapshot, "snapshot", "s", "", "Clone from this snapshot instead of taking a new one")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", nvmeof.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
+
+	return cmd
+}
+
+func promoteDRNVMECommand() *cobra.Command {
+	var snapshot, resourceGroup string
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "promote-dr NQN [SERVICE_IPS]",
+		Short: "Bring up a replicated NVMe-oF target on this cluster",
+		Long: `Bring up an NVMe-oF target that was replicated to this cluster from another
+one, using the latest shipped snapshot unless --snapshot is given.
+The target keeps its namespaces and allowed hosts. The DH-HMAC-CHAP keys
+are not replicated, they are read from the secrets file on the nodes of
+this cluster instead (see "nvme create --help").
+If SERVICE_IPS are given, they replace the service IPs of the original
+target.
+Replication is not set up for the promoted target; use "nvme replication
+set" to replicate it back once the other site is available again.`,
+		Example: `linstor-gateway nvme promote-dr linbit:nvme:example 10.20.0.181/24`,
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nqn, err := nvmeof.NewNqn(args[0])
+			if err != nil {
+				return err
+			}
+
+			var serviceIPs []common.IpCidr
+			if len(args) > 1 {
+				for _, ipString := range strings.Split(args[1], ",") {
+					ip, err := common.ServiceIPFromString(ipString)
+					if err != nil {
+						return fmt.Errorf("invalid service IP '%s': %w", ipString, err)
+					}
+					serviceIPs = append(serviceIPs, ip)
+				}
+			}
+
+			_, err = cli.NvmeOf.PromoteDR(context.Background(), nqn, snapshot, &nvmeof.ResourceConfig{
+				NQN:             nqn,
+				ServiceIPs:      serviceIPs,
+				ResourceGroup:   resourceGroup,
+				ResourceTimeout: resourceTimeout,
+			})
+			if err != nil {
+				hintCheckHealth()
+				return err
+			}
+
+			fmt.Printf("Promoted target \"%s\"\n", nqn)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&snapshot, "snapshot", "s", "", "Promote from this snapshot instead of the latest one")
+	cmd.Flags().StringVarP(&resourceGroup, "resource-group", "r", "", "resource group to use (default: that of the replica)")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", nvmeof.DefaultResourceTimeout, "Timeout for waiting for the resource to become available")
+
+	return cmd
+}
+
 func upgradeNVMECommand() *cobra.Command {
 	var forceYes bool
 	var dryRun bool
@@ -413,6 +802,96 @@ func upgradeNVMECommand() *cobra.Command {
 	return cmd
 }
 
+func nvmeSnapshotClient() snapshotClient {
+	return snapshotClient{
+		command:                "nvme",
+		kind:                   "NVMe-oF target",
+		idName:                 "NQN",
+		example:                "linbit:nvme:example",
+		defaultResourceTimeout: nvmeof.DefaultResourceTimeout,
+		create: func(ctx context.Context, id, snapshot string) (*common.Snapshot, error) {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.NvmeOf.CreateSnapshot(ctx, nqn, snapshot)
+		},
+		list: func(ctx context.Context, id string) ([]common.Snapshot, error) {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.NvmeOf.ListSnapshots(ctx, nqn)
+		},
+		delete: func(ctx context.Context, id, snapshot string) error {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return err
+			}
+			return cli.NvmeOf.DeleteSnapshot(ctx, nqn, snapshot)
+		},
+		rollback: func(ctx context.Context, id, snapshot string, resourceTimeout time.Duration) error {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return err
+			}
+			_, err = cli.NvmeOf.RollbackSnapshot(ctx, nqn, snapshot, resourceTimeout)
+			return err
+		},
+		getSchedule: func(ctx context.Context, id string) (*common.SnapshotSchedule, error) {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.NvmeOf.SnapshotSchedule(ctx, nqn)
+		},
+		setSchedule: func(ctx context.Context, id string, schedule *common.SnapshotSchedule) error {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return err
+			}
+			_, err = cli.NvmeOf.SetSnapshotSchedule(ctx, nqn, schedule)
+			return err
+		},
+		deleteSchedule: func(ctx context.Context, id string) error {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return err
+			}
+			return cli.NvmeOf.DeleteSnapshotSchedule(ctx, nqn)
+		},
+		getReplication: func(ctx context.Context, id string) (*common.Replication, error) {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.NvmeOf.Replication(ctx, nqn)
+		},
+		setReplication: func(ctx context.Context, id string, replication *common.Replication) error {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return err
+			}
+			_, err = cli.NvmeOf.SetReplication(ctx, nqn, replication)
+			return err
+		},
+		deleteReplication: func(ctx context.Context, id string) error {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return err
+			}
+			return cli.NvmeOf.DeleteReplication(ctx, nqn)
+		},
+		ship: func(ctx context.Context, id string) (*common.Snapshot, error) {
+			nqn, err := nvmeof.NewNqn(id)
+			if err != nil {
+				return nil, err
+			}
+			return cli.NvmeOf.ShipSnapshot(ctx, nqn)
+		},
+	}
+}
+
 type noTarget nvmeof.Nqn
 
 func (n noTarget) Error() string {
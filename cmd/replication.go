@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func replicationCommands(c snapshotClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replication",
+		Short: fmt.Sprintf("Manages replication of an %s to a remote cluster", c.kind),
+		Long: fmt.Sprintf(`Manages replication of an %s to a remote LINSTOR cluster for
+disaster recovery.
+Snapshots are shipped by "linstor-gateway server" to the LINSTOR remote on
+a schedule. On the remote cluster, they are stored in a resource named
+"dr-<resource>". There, "linstor-gateway %s promote-dr" brings up the
+%s from the latest shipped snapshot.`, c.kind, c.command, c.kind),
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(setReplicationCommand(c))
+	cmd.AddCommand(showReplicationCommand(c))
+	cmd.AddCommand(deleteReplicationCommand(c))
+	cmd.AddCommand(shipCommand(c))
+
+	return cmd
+}
+
+func setReplicationCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:   fmt.Sprintf("set %s REMOTE CRON", c.idName),
+		Short: fmt.Sprintf("Sets up replication of an %s", c.kind),
+		Long: fmt.Sprintf(`Sets up replication of an %s, replacing any existing settings.
+REMOTE is the name of a LINSTOR remote of type "linstor", as created with
+"linstor remote create linstor". CRON is a cron expression with the five
+fields minute, hour, day of month, month and day of week, or one of @hourly,
+@daily and @weekly.`, c.kind),
+		Example: fmt.Sprintf(`linstor-gateway %s replication set %s site-b "*/15 * * * *"`, c.command, c.example),
+		Args:    cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.setReplication(context.Background(), args[0], &common.Replication{
+				Remote: args[1],
+				Cron:   args[2],
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Set up replication of %q to %q\n", args[0], args[1])
+			return nil
+		},
+	}
+}
+
+func showReplicationCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:     fmt.Sprintf("show %s", c.idName),
+		Short:   fmt.Sprintf("Shows the replication settings of an %s", c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s replication show %s", c.command, c.example),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			replication, err := c.getReplication(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s %s\n", bold("Remote:  "), replication.Remote)
+			fmt.Printf("%s %s\n", bold("Schedule:"), replication.Cron)
+			return nil
+		},
+	}
+}
+
+func deleteReplicationCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:   fmt.Sprintf("delete %s", c.idName),
+		Short: fmt.Sprintf("Stops replication of an %s", c.kind),
+		Long: fmt.Sprintf(`Stops replication of an %s.
+Snapshots that were already shipped are kept on the remote cluster.`, c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s replication delete %s", c.command, c.example),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.deleteReplication(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Stopped replication of %q\n", args[0])
+			return nil
+		},
+	}
+}
+
+func shipCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:   fmt.Sprintf("ship %s", c.idName),
+		Short: fmt.Sprintf("Ships a snapshot of an %s to the remote cluster now", c.kind),
+		Long: fmt.Sprintf(`Takes a snapshot of an %s and ships it to the remote cluster now,
+independent of the schedule. Shipping happens in the background.`, c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s replication ship %s", c.command, c.example),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snap, err := c.ship(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+
+			if snap.Name == "" {
+				fmt.Printf("A snapshot of %q is already being shipped\n", args[0])
+				return nil
+			}
+
+			fmt.Printf("Shipping snapshot %q of %q\n", snap.Name, args[0])
+			return nil
+		},
+	}
+}
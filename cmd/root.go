@@ -19,6 +19,10 @@ var (
 	cfgFile  string
 	loglevel string
 	host     string
+	token    string
+	caCert   string
+	cert     string
+	key      string
 	cli      *client.Client
 )
 
@@ -32,7 +36,9 @@ func contains(haystack []string, needle string) bool {
 	return false
 }
 
-func parseBaseURL(urlString string) (*url.URL, error) {
+// parseBaseURL completes the address of the server with the default port,
+// and with defaultScheme if it has no scheme.
+func parseBaseURL(urlString string, defaultScheme string) (*url.URL, error) {
 	// Check scheme
 	urlSplit := strings.Split(urlString, "://")
 
@@ -40,13 +46,16 @@ func parseBaseURL(urlString string) (*url.URL, error) {
 		if urlSplit[0] == "" {
 			urlSplit[0] = client.DefaultHost
 		}
-		urlSplit = []string{client.DefaultScheme, urlSplit[0]}
+		urlSplit = []string{defaultScheme, urlSplit[0]}
 	}
 
 	if len(urlSplit) != 2 {
 		return nil, fmt.Errorf("URL with multiple scheme separators. parts: %v", urlSplit)
 	}
 	scheme, endpoint := urlSplit[0], urlSplit[1]
+	if scheme != "http" && scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q (expected \"http\" or \"https\")", scheme)
+	}
 
 	// Check port
 	endpointSplit := strings.Split(endpoint, ":")
@@ -81,15 +90,35 @@ func rootCommand() *cobra.Command {
 			}
 			log.SetLevel(level)
 
-			base, err := parseBaseURL(host)
+			scheme := client.DefaultScheme
+			if caCert != "" || cert != "" {
+				scheme = "https"
+			}
+			base, err := parseBaseURL(host, scheme)
 			if err != nil {
 				return err
 			}
-			cli, err = client.NewClient(
+			options := []client.Option{
 				client.BaseURL(base),
 				client.Log(log.StandardLogger()),
 				client.UserAgent(version.UserAgent()),
-			)
+			}
+			if caCert != "" {
+				options = append(options, client.CACertificates(caCert))
+			}
+			if cert != "" || key != "" {
+				if cert == "" || key == "" {
+					return fmt.Errorf("--client-cert and --client-key must be given together")
+				}
+				options = append(options, client.ClientCertificate(cert, key))
+			}
+			if token == "" {
+				token = os.Getenv("LINSTOR_GATEWAY_TOKEN")
+			}
+			if token != "" {
+				options = append(options, client.BearerToken(token))
+			}
+			cli, err = client.NewClient(options...)
 			if err != nil {
 				return fmt.Errorf("failed to connect to LINSTOR Gateway server: %w", err)
 			}
@@ -99,14 +128,24 @@ func rootCommand() *cobra.Command {
 	rootCmd.AddCommand(iscsiCommands())
 	rootCmd.AddCommand(nfsCommands())
 	rootCmd.AddCommand(nvmeCommands())
+	rootCmd.AddCommand(applyCommand())
+	rootCmd.AddCommand(exportCommand())
+	rootCmd.AddCommand(importCommand())
+	rootCmd.AddCommand(nodeCommands())
+	rootCmd.AddCommand(auditCommand())
+	rootCmd.AddCommand(eventsCommand())
 	rootCmd.AddCommand(serverCommand())
 	rootCmd.AddCommand(versionCommand())
 	rootCmd.AddCommand(completionCommand(rootCmd))
 	rootCmd.AddCommand(docsCommand(rootCmd))
 	rootCmd.AddCommand(checkHealthCommand())
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "/etc/linstor-gateway/linstor-gateway.toml", "Config file to load")
-	defaultConnect := fmt.Sprintf("%s://%s:%d", client.DefaultScheme, client.DefaultHost, client.DefaultPort)
-	rootCmd.PersistentFlags().StringVarP(&host, "connect", "c", defaultConnect, "LINSTOR Gateway server to connect to")
+	defaultConnect := fmt.Sprintf("%s:%d", client.DefaultHost, client.DefaultPort)
+	rootCmd.PersistentFlags().StringVarP(&host, "connect", "c", defaultConnect, "LINSTOR Gateway server to connect to (uses https by default if --ca-cert or --client-cert is given)")
+	rootCmd.PersistentFlags().StringVar(&caCert, "ca-cert", "", "PEM bundle of the certificate authorities to verify the server certificate with (default: system certificates)")
+	rootCmd.PersistentFlags().StringVar(&cert, "client-cert", "", "Client certificate to authenticate to the LINSTOR Gateway server with (mutual TLS)")
+	rootCmd.PersistentFlags().StringVar(&key, "client-key", "", "Private key of the client certificate")
+	rootCmd.PersistentFlags().StringVar(&token, "token", "", "Token to authenticate to the LINSTOR Gateway server with (default from $LINSTOR_GATEWAY_TOKEN)")
 	rootCmd.PersistentFlags().StringVar(&loglevel, "loglevel", log.InfoLevel.String(), "Set the log level (as defined by logrus)")
 	return rootCmd
 }
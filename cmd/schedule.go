@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func scheduleCommands(c snapshotClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: fmt.Sprintf("Manages scheduled snapshots of an %s", c.kind),
+		Long: fmt.Sprintf(`Manages scheduled snapshots of an %s.
+Scheduled snapshots are taken by "linstor-gateway server". The schedule is
+stored in LINSTOR, so it keeps working when the %s fails over to another
+node, as long as a server is running on at least one node.`, c.kind, c.kind),
+		Args: cobra.NoArgs,
+	}
+
+	cmd.AddCommand(setScheduleCommand(c))
+	cmd.AddCommand(showScheduleCommand(c))
+	cmd.AddCommand(deleteScheduleCommand(c))
+
+	return cmd
+}
+
+func setScheduleCommand(c snapshotClient) *cobra.Command {
+	var schedule common.SnapshotSchedule
+
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("set %s CRON", c.idName),
+		Short: fmt.Sprintf("Sets the snapshot schedule of an %s", c.kind),
+		Long: fmt.Sprintf(`Sets the snapshot schedule of an %s, replacing any existing one.
+CRON is a cron expression with the five fields minute, hour, day of month,
+month and day of week, or one of @hourly, @daily and @weekly.
+After each scheduled snapshot, older scheduled snapshots are deleted: for
+each of the last --keep-hourly hours, --keep-daily days and --keep-weekly
+weeks, only the latest snapshot is kept. Snapshots that were created manually
+are never deleted.`, c.kind),
+		Example: fmt.Sprintf(`linstor-gateway %s schedule set %s @hourly --keep-hourly 24 --keep-daily 7 --keep-weekly 4
+linstor-gateway %s schedule set %s "30 2 * * 1-5" --keep-daily 5`, c.command, c.example, c.command, c.example),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schedule.Cron = args[1]
+
+			err := c.setSchedule(context.Background(), args[0], &schedule)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Set snapshot schedule of %q\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&schedule.KeepHourly, "keep-hourly", 0, "Number of hours for which to keep the latest snapshot")
+	cmd.Flags().IntVar(&schedule.KeepDaily, "keep-daily", 0, "Number of days for which to keep the latest snapshot")
+	cmd.Flags().IntVar(&schedule.KeepWeekly, "keep-weekly", 0, "Number of weeks for which to keep the latest snapshot")
+
+	return cmd
+}
+
+func showScheduleCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:     fmt.Sprintf("show %s", c.idName),
+		Short:   fmt.Sprintf("Shows the snapshot schedule of an %s", c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s schedule show %s", c.command, c.example),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schedule, err := c.getSchedule(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s %s\n", bold("Schedule:   "), schedule.Cron)
+			fmt.Printf("%s %d\n", bold("Keep hourly:"), schedule.KeepHourly)
+			fmt.Printf("%s %d\n", bold("Keep daily: "), schedule.KeepDaily)
+			fmt.Printf("%s %d\n", bold("Keep weekly:"), schedule.KeepWeekly)
+			return nil
+		},
+	}
+}
+
+func deleteScheduleCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:   fmt.Sprintf("delete %s", c.idName),
+		Short: fmt.Sprintf("Removes the snapshot schedule of an %s", c.kind),
+		Long: fmt.Sprintf(`Removes the snapshot schedule of an %s.
+Snapshots that were already taken are kept.`, c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s schedule delete %s", c.command, c.example),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := c.deleteSchedule(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Removed snapshot schedule of %q\n", args[0])
+			return nil
+		},
+	}
+}
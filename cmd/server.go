@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/LINBIT/linstor-gateway/client"
+	"github.com/LINBIT/linstor-gateway/pkg/audit"
 	"github.com/LINBIT/linstor-gateway/pkg/rest"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -19,6 +21,35 @@ func serverCommand() *cobra.Command {
 An up to date version of the REST-API documentation can be found here:
 https://app.swaggerhub.com/apis-docs/Linstor/linstor-gateway
 
+Access to the API can be restricted with bearer tokens and HTTP basic auth
+in the config file. Each token or user gets one of the roles "read-only",
+"operator" (may also create, change, start and stop resources) or "admin"
+(may also delete resources and manage nodes):
+
+  [server.auth]
+  htpasswd = "/etc/linstor-gateway/htpasswd"
+  default_role = "read-only"
+
+  [server.auth.user_roles]
+  alice = "admin"
+
+  [[server.auth.tokens]]
+  name = "monitoring"
+  token = "..."
+  role = "read-only"
+
+Only bcrypt hashes are supported in the htpasswd file (htpasswd -B).
+Without tokens or htpasswd file, the API is not protected.
+
+To serve the API over HTTPS, set "tls_cert" and "tls_key" in the [server]
+section. With "tls_client_ca", clients must also present a certificate
+signed by that CA, and "client_cert_roles" in [server.auth] maps the
+certificate common names to roles.
+
+All requests that change something are recorded in an audit log, which can
+be queried with "linstor-gateway audit". Set "file" or "journald = true" in
+the [server.audit] section to keep it permanently.
+
 For example:
 linstor-gateway server --addr=":8337"`,
 		Args: cobra.NoArgs,
@@ -34,7 +65,25 @@ linstor-gateway server --addr=":8337"`,
 				}
 			}
 
-			rest.ListenAndServe(addr, controllers, corsOrigins)
+			var authConfig rest.AuthConfig
+			err := viper.UnmarshalKey("server.auth", &authConfig)
+			if err != nil {
+				log.Fatalf("Invalid [server.auth] section in config file: %v", err)
+			}
+
+			var tlsConfig rest.TLSConfig
+			err = viper.UnmarshalKey("server", &tlsConfig)
+			if err != nil {
+				log.Fatalf("Invalid [server] section in config file: %v", err)
+			}
+
+			var auditConfig audit.Config
+			err = viper.UnmarshalKey("server.audit", &auditConfig)
+			if err != nil {
+				log.Fatalf("Invalid [server.audit] section in config file: %v", err)
+			}
+
+			rest.ListenAndServe(addr, controllers, corsOrigins, authConfig, tlsConfig, auditConfig)
 		},
 	}
 
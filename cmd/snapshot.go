@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/spf13/cobra"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/prompt"
+)
+
+// snapshotClient describes how to manage snapshots of one kind of gateway
+// resource, so that the snapshot commands can be shared between them.
+type snapshotClient struct {
+	// command is the name of the parent command, e.g. "iscsi".
+	command string
+	// kind is the human-readable name of the resource type, e.g. "iSCSI target".
+	kind string
+	// idName is the name of the resource identifier in usage strings, e.g. "IQN".
+	idName string
+	// example is a valid resource identifier used in examples.
+	example                string
+	defaultResourceTimeout time.Duration
+
+	create   func(ctx context.Context, id, snapshot string) (*common.Snapshot, error)
+	list     func(ctx context.Context, id string) ([]common.Snapshot, error)
+	delete   func(ctx context.Context, id, snapshot string) error
+	rollback func(ctx context.Context, id, snapshot string, resourceTimeout time.Duration) error
+
+	getSchedule    func(ctx context.Context, id string) (*common.SnapshotSchedule, error)
+	setSchedule    func(ctx context.Context, id string, schedule *common.SnapshotSchedule) error
+	deleteSchedule func(ctx context.Context, id string) error
+
+	getReplication    func(ctx context.Context, id string) (*common.Replication, error)
+	setReplication    func(ctx context.Context, id string, replication *common.Replication) error
+	deleteReplication func(ctx context.Context, id string) error
+	ship              func(ctx context.Context, id string) (*common.Snapshot, error)
+}
+
+func snapshotCommands(c snapshotClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: fmt.Sprintf("Manages snapshots of an %s", c.kind),
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(createSnapshotCommand(c))
+	cmd.AddCommand(listSnapshotCommand(c))
+	cmd.AddCommand(deleteSnapshotCommand(c))
+	cmd.AddCommand(rollbackSnapshotCommand(c))
+
+	return cmd
+}
+
+func createSnapshotCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:   fmt.Sprintf("create %s [SNAPSHOT]", c.idName),
+		Short: fmt.Sprintf("Takes a snapshot of an %s", c.kind),
+		Long: fmt.Sprintf(`Takes a snapshot of all volumes of an %s.
+If no snapshot name is given, a name is generated from the current time.`, c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s snapshot create %s before-upgrade", c.command, c.example),
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var name string
+			if len(args) > 1 {
+				name = args[1]
+			}
+
+			snap, err := c.create(context.Background(), args[0], name)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Created snapshot %q of %q\n", snap.Name, args[0])
+			return nil
+		},
+	}
+}
+
+func listSnapshotCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:     fmt.Sprintf("list %s", c.idName),
+		Short:   fmt.Sprintf("Lists the snapshots of an %s", c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s snapshot list %s", c.command, c.example),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snaps, err := c.list(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+
+			table := tablewriter.NewTable(os.Stdout,
+				tablewriter.WithConfig(tablewriter.NewConfigBuilder().
+					Header().Formatting().WithAutoFormat(tw.Off).Build().Build().
+					Build()),
+			)
+			table.Header(colorHeader("Snapshot"), colorHeader("Created"), colorHeader("Nodes"))
+
+			for _, snap := range snaps {
+				var created string
+				if !snap.CreatedAt.IsZero() {
+					created = snap.CreatedAt.Local().Format(time.DateTime)
+				}
+
+				_ = table.Append(snap.Name, created, strings.Join(snap.Nodes, ", "))
+			}
+
+			_ = table.Render()
+
+			return nil
+		},
+	}
+}
+
+func deleteSnapshotCommand(c snapshotClient) *cobra.Command {
+	return &cobra.Command{
+		Use:     fmt.Sprintf("delete %s SNAPSHOT...", c.idName),
+		Short:   fmt.Sprintf("Deletes snapshots of an %s", c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s snapshot delete %s before-upgrade", c.command, c.example),
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var allErrs multiError
+			for _, name := range args[1:] {
+				err := c.delete(context.Background(), args[0], name)
+				if err != nil {
+					allErrs = append(allErrs, err)
+					continue
+				}
+
+				fmt.Printf("Deleted snapshot %q of %q\n", name, args[0])
+			}
+
+			return allErrs.Err()
+		},
+	}
+}
+
+func rollbackSnapshotCommand(c snapshotClient) *cobra.Command {
+	var force bool
+	var resourceTimeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("rollback %s SNAPSHOT", c.idName),
+		Short: fmt.Sprintf("Resets an %s to the state of a snapshot", c.kind),
+		Long: fmt.Sprintf(`Resets all volumes of an %s to the state of a snapshot.
+The %s is stopped for the rollback and started again afterwards.
+All data written since the snapshot was taken is lost.`, c.kind, c.kind),
+		Example: fmt.Sprintf("linstor-gateway %s snapshot rollback %s before-upgrade", c.command, c.example),
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, name := args[0], args[1]
+
+			var yes bool
+			if force {
+				yes = true
+			} else {
+				fmt.Printf("%s: Rolling back %q to snapshot %q %s.\n",
+					color.YellowString("WARNING"), id, name,
+					bold("discards all data written since the snapshot was taken"))
+				yes = prompt.Confirm("Continue?")
+			}
+
+			if !yes {
+				fmt.Println("Aborted")
+				return nil
+			}
+
+			err := c.rollback(context.Background(), id, name, resourceTimeout)
+			if err != nil {
+				hintCheckHealth()
+				return err
+			}
+
+			fmt.Printf("Rolled back %q to snapshot %q\n", id, name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Roll back without prompting for confirmation")
+	cmd.Flags().DurationVar(&resourceTimeout, "resource-timeout", c.defaultResourceTimeout, "Timeout for waiting for the resource to become available")
+
+	return cmd
+}
@@ -33,6 +33,8 @@ make
 install -D -m 755 %{_builddir}/%{name}-%{tarball_version}/%{name} %{buildroot}/%{_sbindir}/%{name}
 install -D -m 644 %{name}.service %{buildroot}%{_unitdir}/%{name}.service
 install -D -m 644 %{name}.xml %{buildroot}%{_firewalldir}/services/%{name}.xml
+install -D -m 755 ocf/iscsi-auth %{buildroot}%{_prefix}/lib/ocf/resource.d/%{name}/iscsi-auth
+install -D -m 755 ocf/nvmet-auth %{buildroot}%{_prefix}/lib/ocf/resource.d/%{name}/nvmet-auth
 
 %post
 %systemd_post %{name}.service
@@ -55,6 +57,9 @@ find %{_datadir}/doc/drbd-reactor* \
 	%dir %{_firewalldir}
 	%dir %{_firewalldir}/services
 	%{_firewalldir}/services/%{name}.xml
+	%dir %{_prefix}/lib/ocf/resource.d/%{name}
+	%{_prefix}/lib/ocf/resource.d/%{name}/iscsi-auth
+	%{_prefix}/lib/ocf/resource.d/%{name}/nvmet-auth
 
 %changelog
 * Thu Feb 05 2026 Christoph Böhmwalder <christoph.boehmwalder@linbit.com> - 2.1.0-1
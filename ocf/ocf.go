@@ -0,0 +1,55 @@
+// Package ocf contains the OCF resource agents shipped with LINSTOR Gateway.
+// They configure what the agents from the resource-agents package cannot,
+// and are installed to Dir on every node.
+package ocf
+
+import (
+	"bytes"
+	"embed"
+	"encoding/xml"
+	"fmt"
+)
+
+const (
+	// Vendor is the OCF provider of the shipped agents, as used in the
+	// "ocf:<vendor>:<agent>" agent types of promoter configs.
+	Vendor = "linstor-gateway"
+	// Dir is the directory the shipped agents are installed to.
+	Dir = "/usr/lib/ocf/resource.d/" + Vendor
+)
+
+//go:embed iscsi-auth nvmet-auth
+var agents embed.FS
+
+type metadata struct {
+	Parameters []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"parameters>parameter"`
+}
+
+// Parameters returns the names of the parameters the shipped agent supports,
+// as declared in its meta-data.
+func Parameters(agent string) ([]string, error) {
+	script, err := agents.ReadFile(agent)
+	if err != nil {
+		return nil, fmt.Errorf("unknown agent %s: %w", agent, err)
+	}
+
+	start := bytes.Index(script, []byte("<?xml"))
+	end := bytes.Index(script, []byte("</resource-agent>"))
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("agent %s has no meta-data", agent)
+	}
+
+	var md metadata
+	err = xml.Unmarshal(script[start:end+len("</resource-agent>")], &md)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse meta-data of agent %s: %w", agent, err)
+	}
+
+	var result []string
+	for _, p := range md.Parameters {
+		result = append(result, p.Name)
+	}
+	return result, nil
+}
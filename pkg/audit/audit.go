@@ -0,0 +1,292 @@
+// Package audit records the changes made through the REST API, so that it
+// can be traced who created, changed or deleted a resource.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/journal"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultKeep is the number of entries that are kept in memory for queries.
+const DefaultKeep = 1000
+
+// maxBodySize is the size up to which request bodies are recorded.
+const maxBodySize = 64 * 1024
+
+// Entry is a single audited API call.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// User and Role are empty if authentication is disabled.
+	User   string `json:"user,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Remote string `json:"remote"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Operation is the route that handled the call, e.g.
+	// "DELETE /api/v2/iscsi/{iqn}".
+	Operation string `json:"operation"`
+	// Body is the request body with secrets redacted.
+	Body     json.RawMessage `json:"body,omitempty"`
+	Status   int             `json:"status"`
+	Error    string          `json:"error,omitempty"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// Config is the "[server.audit]" section of the config file.
+type Config struct {
+	// File is the path of a file the entries are appended to as JSON
+	// lines.
+	File string `mapstructure:"file"`
+	// Journald sends the entries to the systemd journal.
+	Journald bool `mapstructure:"journald"`
+	// Keep is the number of recent entries that can be queried through
+	// the API.
+	Keep int `mapstructure:"keep"`
+}
+
+// Log writes audit entries to the configured destinations and keeps the most
+// recent ones in memory.
+type Log struct {
+	mu       sync.Mutex
+	file     *os.File
+	journald bool
+	keep     int
+	recent   []Entry
+}
+
+// New opens the audit log. If a file is configured, the most recent entries
+// are read back from it.
+func New(cfg Config) (*Log, error) {
+	l := &Log{journald: cfg.Journald, keep: cfg.Keep}
+	if l.keep <= 0 {
+		l.keep = DefaultKeep
+	}
+
+	if cfg.Journald && !journal.Enabled() {
+		return nil, errors.New("journald audit log requested, but journald is not available")
+	}
+
+	if cfg.File != "" {
+		err := l.load(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+
+		l.file, err = os.OpenFile(cfg.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+	}
+
+	return l, nil
+}
+
+// load reads the most recent entries from an existing audit log file.
+func (l *Log) load(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 4*maxBodySize)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.WithError(err).Warn("skipping invalid line in audit log")
+			continue
+		}
+		l.remember(entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Log) remember(entry Entry) {
+	l.recent = append(l.recent, entry)
+	if len(l.recent) > l.keep {
+		l.recent = l.recent[len(l.recent)-l.keep:]
+	}
+}
+
+// Record writes an entry to the audit log. Errors are logged, but do not
+// stop the entry from being kept in memory.
+func (l *Log) Record(entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.remember(entry)
+
+	if l.file != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			_, err = l.file.Write(append(line, '\n'))
+		}
+		if err != nil {
+			log.WithError(err).Error("failed to write audit log")
+		}
+	}
+
+	if l.journald {
+		err := journal.Send(entry.message(), journal.PriInfo, entry.fields())
+		if err != nil {
+			log.WithError(err).Error("failed to send audit log to journald")
+		}
+	}
+}
+
+func (e *Entry) message() string {
+	user := e.User
+	if user == "" {
+		user = "anonymous"
+	}
+
+	return fmt.Sprintf("%s %s by %s from %s: %d", e.Method, e.Path, user, e.Remote, e.Status)
+}
+
+func (e *Entry) fields() map[string]string {
+	fields := map[string]string{
+		"SYSLOG_IDENTIFIER": "linstor-gateway-audit",
+		"AUDIT_USER":        e.User,
+		"AUDIT_ROLE":        e.Role,
+		"AUDIT_REMOTE":      e.Remote,
+		"AUDIT_METHOD":      e.Method,
+		"AUDIT_PATH":        e.Path,
+		"AUDIT_OPERATION":   e.Operation,
+		"AUDIT_STATUS":      strconv.Itoa(e.Status),
+		"AUDIT_DURATION":    e.Duration.String(),
+	}
+	if len(e.Body) > 0 {
+		fields["AUDIT_BODY"] = string(e.Body)
+	}
+	if e.Error != "" {
+		fields["AUDIT_ERROR"] = e.Error
+	}
+	return fields
+}
+
+// Filter selects entries from the audit log.
+type Filter struct {
+	// Since excludes entries before this time, if set.
+	Since time.Time
+	// User only includes entries of this user, if set.
+	User string
+	// Limit is the maximum number of entries, counted from the most recent
+	// one. 0 means no limit.
+	Limit int
+}
+
+// Recent returns the recent entries matching the filter, oldest first.
+func (l *Log) Recent(filter Filter) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make([]Entry, 0)
+	for i := len(l.recent) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+
+		entry := l.recent[i]
+		if !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+			break
+		}
+		if filter.User != "" && entry.User != filter.User {
+			continue
+		}
+
+		result = append(result, entry)
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result
+}
+
+// Close closes the audit log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	return l.file.Close()
+}
+
+// secretKeys are the JSON keys whose values are never written to the audit
+// log.
+var secretKeys = map[string]bool{
+	"password":        true,
+	"mutual_password": true,
+	"key":             true,
+	"controller_key":  true,
+	"token":           true,
+}
+
+// RedactBody returns the request body with the values of all secrets
+// replaced. Bodies that are not JSON or too large are replaced with a note.
+func RedactBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if len(body) > maxBodySize {
+		return noteBody(fmt.Sprintf("%d bytes, not recorded", len(body)))
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return noteBody(fmt.Sprintf("%d bytes, not JSON", len(body)))
+	}
+
+	redacted, err := json.Marshal(redact(v))
+	if err != nil {
+		return noteBody(fmt.Sprintf("failed to encode: %v", err))
+	}
+
+	return redacted
+}
+
+func noteBody(note string) json.RawMessage {
+	b, _ := json.Marshal(note)
+	return b
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if s, ok := val.(string); ok && secretKeys[k] && s != "" {
+				v[k] = "REDACTED"
+				continue
+			}
+			v[k] = redact(val)
+		}
+	case []any:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,91 @@
+package audit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/audit"
+)
+
+func TestRedactBody(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		body     string
+		expected string
+	}{{
+		name:     "empty",
+		body:     "",
+		expected: "",
+	}, {
+		name:     "no secrets",
+		body:     `{"iqn":"iqn.2019-08.com.linbit:example","volumes":[{"number":1}]}`,
+		expected: `{"iqn":"iqn.2019-08.com.linbit:example","volumes":[{"number":1}]}`,
+	}, {
+		name:     "chap secrets",
+		body:     `{"username":"user","password":"secret","mutual_password":"other","initiator_credentials":{"iqn.2019-08.com.linbit:init":{"username":"u","password":"p"}}}`,
+		expected: `{"initiator_credentials":{"iqn.2019-08.com.linbit:init":{"password":"REDACTED","username":"u"}},"mutual_password":"REDACTED","password":"REDACTED","username":"user"}`,
+	}, {
+		name:     "host keys",
+		body:     `{"key":"DHHC-1:00:abc:","controller_key":""}`,
+		expected: `{"controller_key":"","key":"REDACTED"}`,
+	}, {
+		name:     "not json",
+		body:     `password=secret`,
+		expected: `"15 bytes, not JSON"`,
+	}}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tcase.expected, string(audit.RedactBody([]byte(tcase.body))))
+		})
+	}
+}
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	l, err := audit.New(audit.Config{File: path, Keep: 3})
+	require.NoError(t, err)
+
+	for i, user := range []string{"alice", "bob", "alice", "bob"} {
+		l.Record(audit.Entry{Time: start.Add(time.Duration(i) * time.Minute), User: user, Method: "DELETE", Status: 200})
+	}
+
+	// only the last three entries are kept
+	recent := l.Recent(audit.Filter{})
+	require.Len(t, recent, 3)
+	assert.Equal(t, "bob", recent[0].User)
+	assert.Equal(t, start.Add(3*time.Minute), recent[2].Time)
+
+	assert.Len(t, l.Recent(audit.Filter{User: "alice"}), 1)
+	assert.Len(t, l.Recent(audit.Filter{Since: start.Add(2 * time.Minute)}), 2)
+
+	limited := l.Recent(audit.Filter{Limit: 1})
+	require.Len(t, limited, 1)
+	assert.Equal(t, start.Add(3*time.Minute), limited[0].Time)
+
+	require.NoError(t, l.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"user":"alice"`)
+
+	// entries are read back after a restart
+	l, err = audit.New(audit.Config{File: path, Keep: 3})
+	require.NoError(t, err)
+	defer l.Close()
+	assert.Equal(t, recent, l.Recent(audit.Filter{}))
+}
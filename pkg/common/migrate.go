@@ -0,0 +1,166 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/LINBIT/golinstor/client"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+// restoreTimeout bounds the requests that try to bring a resource back up
+// after a migration failed, when the context of the migration may already be
+// done.
+const restoreTimeout = 30 * time.Second
+
+// Migration is the request to move a resource to another node.
+type Migration struct {
+	Node string `json:"node"`
+}
+
+// ResourceHealthyOn reports whether node has a replica of the resource with
+// all volumes up to date, so that the resource can be promoted there.
+func ResourceHealthyOn(resources []client.ResourceWithVolumes, node string) bool {
+	for _, resource := range resources {
+		if resource.NodeName != node {
+			continue
+		}
+
+		if len(resource.Volumes) == 0 {
+			return false
+		}
+
+		for _, vol := range resource.Volumes {
+			if vol.State.DiskState != "UpToDate" {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// MigrateResource moves the running promoter of cfg to node. The promoter is
+// stopped on the current node and started again with node as its preferred
+// node, so that drbd-reactor on node gets to promote the resource first.
+// The new preference is kept: restoring the original order would make
+// drbd-reactor restart the promoter and move the resource back. The node
+// preferences stored in the metadata of cfg are not changed, so the original
+// order comes back the next time the config is rewritten, e.g. by an update.
+//
+// Resources are never migrated to one of their forbidden nodes, or to an
+// evacuated node.
+//
+// The id is the one the config is stored under, as in reactor.ConfigPath.
+// ctx should carry a timeout, MigrateResource waits until the resource is in
+// use again.
+func MigrateResource(ctx context.Context, cli *client.Client, cfg *reactor.PromoterConfig, id, node string) error {
+	name, _ := cfg.FirstResource()
+
+	if slices.Contains(cfg.Metadata.ForbiddenNodes, node) {
+		return ValidationError(fmt.Sprintf("node %s is a forbidden node of resource %s", node, name))
+	}
+
+	target, err := cli.Nodes.Get(ctx, node)
+	if errors.Is(err, client.NotFoundError) {
+		return ValidationError(fmt.Sprintf("node %s does not exist", node))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch node %s: %w", node, err)
+	}
+
+	if NodeEvacuated(target) {
+		return ValidationError(fmt.Sprintf("node %s is evacuated, run \"linstor-gateway node resume %s\" first", node, node))
+	}
+
+	resources, err := cli.Resources.GetResourceView(ctx, &client.ListOpts{Resource: []string{name}})
+	if err != nil {
+		return fmt.Errorf("failed to fetch resources: %w", err)
+	}
+
+	current, err := getInUseNode(ctx, cli, name)
+	if err != nil {
+		return fmt.Errorf("failed to get InUse node for resource %s: %w", name, err)
+	}
+
+	if current == "" {
+		return ValidationError(fmt.Sprintf("resource %s is not running, start it instead", name))
+	}
+
+	if current == node {
+		return nil
+	}
+
+	if !ResourceHealthyOn(resources, node) {
+		return ValidationError(fmt.Sprintf("node %s has no healthy replica of resource %s", node, name))
+	}
+
+	preferred := *cfg
+	preferred.Resources = make(map[string]reactor.PromoterResourceConfig, len(cfg.Resources))
+	for rd, rscCfg := range cfg.Resources {
+		nodes := []string{node}
+		for _, n := range rscCfg.PreferredNodes {
+			if n != node {
+				nodes = append(nodes, n)
+			}
+		}
+		rscCfg.PreferredNodes = nodes
+		preferred.Resources[rd] = rscCfg
+	}
+
+	err = reactor.EnsureConfig(ctx, cli, &preferred, id)
+	if err != nil {
+		return fmt.Errorf("failed to set preferred node: %w", err)
+	}
+
+	path := reactor.ConfigPath(id)
+	err = reactor.DetachConfig(ctx, cli, &preferred, path)
+	if err != nil {
+		return fmt.Errorf("failed to detach reactor configuration: %w", err)
+	}
+
+	err = WaitUntilResourceCondition(ctx, cli, name, NoResourcesInUse)
+	if err != nil {
+		// try not to leave the resource stopped; use a fresh context, the
+		// original one may have timed out
+		restoreCtx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+		defer cancel()
+		if attachErr := reactor.AttachConfig(restoreCtx, cli, &preferred, path); attachErr != nil {
+			log.WithError(attachErr).Warn("failed to re-attach reactor configuration")
+		}
+		return fmt.Errorf("error waiting for resource to become unused on node %s: %w", current, err)
+	}
+
+	err = reactor.AttachConfig(ctx, cli, &preferred, path)
+	if err != nil {
+		return fmt.Errorf("failed to attach reactor configuration: %w", err)
+	}
+
+	err = WaitUntilResourceCondition(ctx, cli, name, AnyResourcesInUse)
+	if err != nil {
+		return fmt.Errorf("error waiting for resource to become used: %w", err)
+	}
+
+	err = AssertResourceInUseStable(ctx, cli, name)
+	if err != nil {
+		return fmt.Errorf("error waiting for resource to become stable: %w", err)
+	}
+
+	now, err := getInUseNode(ctx, cli, name)
+	if err != nil {
+		return fmt.Errorf("failed to get InUse node for resource %s: %w", name, err)
+	}
+
+	if now != node {
+		return fmt.Errorf("resource %s was started on node %s instead of %s", name, now, node)
+	}
+
+	return nil
+}
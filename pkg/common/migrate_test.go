@@ -0,0 +1,35 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func TestResourceHealthyOn(t *testing.T) {
+	t.Parallel()
+
+	resource := func(node string, states ...string) client.ResourceWithVolumes {
+		r := client.ResourceWithVolumes{Resource: client.Resource{NodeName: node}}
+		for _, state := range states {
+			r.Volumes = append(r.Volumes, client.Volume{State: client.VolumeState{DiskState: state}})
+		}
+		return r
+	}
+
+	resources := []client.ResourceWithVolumes{
+		resource("node1", "UpToDate", "UpToDate"),
+		resource("node2", "UpToDate", "Inconsistent"),
+		resource("node3", "Diskless", "Diskless"),
+		resource("node4"),
+	}
+
+	assert.True(t, common.ResourceHealthyOn(resources, "node1"))
+	assert.False(t, common.ResourceHealthyOn(resources, "node2"))
+	assert.False(t, common.ResourceHealthyOn(resources, "node3"))
+	assert.False(t, common.ResourceHealthyOn(resources, "node4"))
+	assert.False(t, common.ResourceHealthyOn(resources, "node5"))
+}
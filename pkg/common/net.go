@@ -34,6 +34,10 @@ func (s *IpCidr) Set(raw string) error {
 }
 
 func (s IpCidr) MarshalJSON() ([]byte, error) {
+	// an unset address is encoded as empty string, so that it can be decoded again
+	if s.IPNet.IP == nil {
+		return json.Marshal("")
+	}
 	return json.Marshal(s.IPNet.String())
 }
 
@@ -44,6 +48,11 @@ func (s *IpCidr) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	if str == "" {
+		s.IPNet = net.IPNet{}
+		return nil
+	}
+
 	ser, err := ServiceIPFromString(str)
 	if err != nil {
 		return err
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/LINBIT/golinstor/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNotActiveHere is returned by operations that have to run on the node
+// where the resource is active, when that is not the node this server is
+// running on.
+var ErrNotActiveHere = errors.New("resource is not active on this node")
+
+// IsLocalNode checks if the given LINSTOR node name refers to the node we
+// are running on. LINSTOR node names are expected to match the hostname.
+func IsLocalNode(node string) bool {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.WithError(err).Warn("failed to determine hostname")
+		return false
+	}
+
+	return strings.EqualFold(hostname, node)
+}
+
+// EvacuatedProp is the LINSTOR node property that marks a node as evacuated.
+// Gateway resources are not started on evacuated nodes until they are
+// resumed.
+const EvacuatedProp = "Aux/linstor-gateway/evacuated"
+
+// NodeEvacuated reports whether node is marked as evacuated.
+func NodeEvacuated(node client.Node) bool {
+	return node.Props[EvacuatedProp] == "true"
+}
+
+// EvacuatedNodes returns the names of all nodes marked as evacuated.
+func EvacuatedNodes(ctx context.Context, cli *client.Client) ([]string, error) {
+	nodes, err := cli.Nodes.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+
+	var result []string
+	for _, node := range nodes {
+		if NodeEvacuated(node) {
+			result = append(result, node.Name)
+		}
+	}
+	return result, nil
+}
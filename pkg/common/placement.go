@@ -0,0 +1,63 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/LINBIT/golinstor/client"
+)
+
+// ValidNodePreferences checks that the preferred and forbidden nodes of a
+// resource do not contradict each other.
+func ValidNodePreferences(preferred, forbidden []string) error {
+	seen := make(map[string]bool)
+	for _, node := range preferred {
+		if node == "" {
+			return ValidationError("preferred node names must not be empty")
+		}
+		if seen[node] {
+			return ValidationError(fmt.Sprintf("node %s is listed as preferred more than once", node))
+		}
+		seen[node] = true
+	}
+
+	for _, node := range forbidden {
+		if node == "" {
+			return ValidationError("forbidden node names must not be empty")
+		}
+		if seen[node] {
+			return ValidationError(fmt.Sprintf("node %s cannot be both preferred and forbidden", node))
+		}
+	}
+
+	return nil
+}
+
+// PromoterNodeOrder returns the "preferred-nodes" setting for drbd-reactor.
+//
+// drbd-reactor tries the listed nodes in order, and only falls back to other
+// nodes if none of them can run the resource. There is no way to forbid a node
+// outright, so if there are forbidden nodes, all other nodes of the deployment
+// are appended to the list, which leaves the forbidden nodes as the last
+// resort on a failover. Migrations and evacuations never pick forbidden
+// nodes, see MigrateResource.
+func PromoterNodeOrder(preferred, forbidden []string, deployment []client.ResourceWithVolumes) []string {
+	if len(forbidden) == 0 {
+		return preferred
+	}
+
+	result := slices.Clone(preferred)
+
+	var rest []string
+	for _, resource := range deployment {
+		node := resource.NodeName
+		if slices.Contains(result, node) || slices.Contains(rest, node) || slices.Contains(forbidden, node) {
+			continue
+		}
+		rest = append(rest, node)
+	}
+	sort.Strings(rest)
+
+	return append(result, rest...)
+}
@@ -0,0 +1,64 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func TestValidNodePreferences(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, common.ValidNodePreferences(nil, nil))
+	assert.NoError(t, common.ValidNodePreferences([]string{"node1", "node2"}, []string{"node3"}))
+	assert.Error(t, common.ValidNodePreferences([]string{"node1", ""}, nil))
+	assert.Error(t, common.ValidNodePreferences(nil, []string{""}))
+	assert.Error(t, common.ValidNodePreferences([]string{"node1", "node1"}, nil))
+	assert.Error(t, common.ValidNodePreferences([]string{"node1"}, []string{"node1"}))
+}
+
+func TestPromoterNodeOrder(t *testing.T) {
+	t.Parallel()
+
+	deployment := []client.ResourceWithVolumes{
+		{Resource: client.Resource{NodeName: "node4"}},
+		{Resource: client.Resource{NodeName: "node2"}},
+		{Resource: client.Resource{NodeName: "node3"}},
+		{Resource: client.Resource{NodeName: "node1"}},
+	}
+
+	testcases := []struct {
+		name      string
+		preferred []string
+		forbidden []string
+		expected  []string
+	}{{
+		name:     "no preferences",
+		expected: nil,
+	}, {
+		name:      "only preferred",
+		preferred: []string{"node3", "node1"},
+		expected:  []string{"node3", "node1"},
+	}, {
+		name:      "forbidden",
+		forbidden: []string{"node2"},
+		expected:  []string{"node1", "node3", "node4"},
+	}, {
+		name:      "preferred and forbidden",
+		preferred: []string{"node3"},
+		forbidden: []string{"node2"},
+		expected:  []string{"node3", "node1", "node4"},
+	}}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tcase.expected, common.PromoterNodeOrder(tcase.preferred, tcase.forbidden, deployment))
+		})
+	}
+}
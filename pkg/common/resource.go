@@ -19,6 +19,10 @@ func (u *UserGroup) String() string {
 	return fmt.Sprintf("%s:%s", u.User, u.Group)
 }
 
+// ErrVolumeNotFound is returned when an operation refers to a volume that the
+// resource does not have.
+var ErrVolumeNotFound = errors.New("volume not found")
+
 type VolumeConfig struct {
 	Number              int       `json:"number"`
 	SizeKiB             uint64    `json:"size_kib"`
@@ -26,6 +30,30 @@ type VolumeConfig struct {
 	FileSystemRootOwner UserGroup `json:"file_system_root_owner,omitempty"`
 }
 
+// ValidResize checks that volume volNr of volumes can be resized to
+// newSizeKiB, and returns it. Volume 0 is reserved and volumes cannot
+// shrink. An error wrapping ErrVolumeNotFound is returned if there is no
+// such volume.
+func ValidResize(volumes []VolumeConfig, volNr int, newSizeKiB uint64) (*VolumeConfig, error) {
+	if volNr == 0 {
+		return nil, ValidationError("cannot resize volume 0; it is the reserved cluster-private/system volume")
+	}
+
+	for i := range volumes {
+		if volumes[i].Number != volNr {
+			continue
+		}
+
+		if newSizeKiB < volumes[i].SizeKiB {
+			return nil, ValidationError(fmt.Sprintf("cannot shrink volume from %d KiB to %d KiB", volumes[i].SizeKiB, newSizeKiB))
+		}
+
+		return &volumes[i], nil
+	}
+
+	return nil, fmt.Errorf("volume %d: %w", volNr, ErrVolumeNotFound)
+}
+
 type ResourceStatus struct {
 	State   ResourceState `json:"state"`
 	Service ServiceState  `json:"service"`
@@ -39,6 +67,50 @@ type Volume struct {
 	Status VolumeState  `json:"status"`
 }
 
+// Snapshot is a point-in-time copy of all volumes of a resource.
+type Snapshot struct {
+	Name      string         `json:"name"`
+	CreatedAt time.Time      `json:"created_at,omitempty"`
+	Nodes     []string       `json:"nodes,omitempty"`
+	Volumes   []VolumeConfig `json:"volumes,omitempty"`
+}
+
+// UserVolumes returns the volumes of the snapshot, without the cluster private
+// volume.
+func (s *Snapshot) UserVolumes() []VolumeConfig {
+	var result []VolumeConfig
+	for _, vol := range s.Volumes {
+		if vol.Number != 0 {
+			result = append(result, vol)
+		}
+	}
+	return result
+}
+
+// SnapshotSchedule describes when snapshots of a resource are taken
+// automatically, and how many of them are kept.
+type SnapshotSchedule struct {
+	// Cron is a cron expression with the five fields minute, hour, day of
+	// month, month and day of week, or one of @hourly, @daily and @weekly.
+	Cron string `json:"cron"`
+	// KeepHourly is the number of hours for which the latest snapshot is kept.
+	KeepHourly int `json:"keep_hourly,omitempty"`
+	// KeepDaily is the number of days for which the latest snapshot is kept.
+	KeepDaily int `json:"keep_daily,omitempty"`
+	// KeepWeekly is the number of weeks for which the latest snapshot is kept.
+	KeepWeekly int `json:"keep_weekly,omitempty"`
+}
+
+// Replication describes how a resource is replicated to a remote LINSTOR
+// cluster for disaster recovery.
+type Replication struct {
+	// Remote is the name of the LINSTOR remote that snapshots are shipped to.
+	Remote string `json:"remote"`
+	// Cron specifies when snapshots are shipped, in the same format as
+	// SnapshotSchedule.Cron.
+	Cron string `json:"cron"`
+}
+
 type VolumeState struct {
 	Number int           `json:"number"`
 	State  ResourceState `json:"state"`
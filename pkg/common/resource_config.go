@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"maps"
 	"net"
 	"path/filepath"
 	"strings"
@@ -43,6 +44,23 @@ func ClusterPrivateVolume() VolumeConfig {
 	}
 }
 
+// SameUserVolumes reports whether a and b describe the same user volumes,
+// i.e. the same volume numbers with the same sizes. The cluster private
+// volume and the order of the volumes are ignored.
+func SameUserVolumes(a, b []VolumeConfig) bool {
+	sizes := func(volumes []VolumeConfig) map[int]uint64 {
+		result := make(map[int]uint64)
+		for _, vol := range volumes {
+			if vol.Number != 0 {
+				result[vol.Number] = vol.SizeKiB
+			}
+		}
+		return result
+	}
+
+	return maps.Equal(sizes(a), sizes(b))
+}
+
 func ClusterPrivateVolumeAgent(deployedVol client.Volume, resource string) *reactor.ResourceAgent {
 	return &reactor.ResourceAgent{
 		Type: "ocf:heartbeat:Filesystem",
@@ -56,6 +74,12 @@ func ClusterPrivateVolumeAgent(deployedVol client.Volume, resource string) *reac
 	}
 }
 
+// CheckIPCollision checks if config already uses checkIP. Every resource
+// brings up its service IPs with its own IPaddr2 agent on the node it is
+// promoted on, so an address cannot be shared between resources, even if
+// they listen on different ports: the address would be active on two nodes
+// if the resources run on different nodes, and stopping one resource would
+// remove it from under the other one.
 func CheckIPCollision(config reactor.PromoterConfig, checkIP net.IP) error {
 	name, rscCfg := config.FirstResource()
 	if rscCfg == nil {
@@ -73,7 +97,7 @@ func CheckIPCollision(config reactor.PromoterConfig, checkIP net.IP) error {
 				}
 				log.Debugf("checking IP %s", ip)
 				if ip.Equal(checkIP) {
-					return fmt.Errorf("IP address %s already in use by config %s",
+					return fmt.Errorf("IP address %s already in use by config %s; service IPs cannot be shared between resources, even on different ports",
 						ip.String(), name)
 				}
 			}
@@ -0,0 +1,78 @@
+package common_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+func TestCheckIPCollision(t *testing.T) {
+	t.Parallel()
+
+	config := func(agents ...reactor.StartEntry) reactor.PromoterConfig {
+		return reactor.PromoterConfig{
+			Resources: map[string]reactor.PromoterResourceConfig{
+				"existing": {Start: agents},
+			},
+		}
+	}
+
+	ipAgent := &reactor.ResourceAgent{Type: "ocf:heartbeat:IPaddr2", Name: "service_ip0", Attributes: map[string]string{"ip": "192.168.127.1", "cidr_netmask": "24"}}
+
+	testcases := []struct {
+		name        string
+		config      reactor.PromoterConfig
+		ip          string
+		expectError bool
+	}{
+		{
+			name:   "different ip",
+			config: config(ipAgent),
+			ip:     "192.168.127.2",
+		},
+		{
+			name:        "same ip",
+			config:      config(ipAgent),
+			ip:          "192.168.127.1",
+			expectError: true,
+		},
+		{
+			// the other resource can be promoted on a different node, which
+			// would bring up the same address there
+			name: "same ip on a different port",
+			config: config(
+				&reactor.ResourceAgent{Type: "ocf:heartbeat:portblock", Name: "pblock0", Attributes: map[string]string{"ip": "192.168.127.1", "portno": "3260", "action": "block"}},
+				ipAgent,
+			),
+			ip:          "192.168.127.1",
+			expectError: true,
+		},
+		{
+			name: "same ip on a different nvmet port",
+			config: config(
+				ipAgent,
+				&reactor.ResourceAgent{Type: "ocf:heartbeat:nvmet-port", Name: "port0", Attributes: map[string]string{"addr": "192.168.127.1", "type": "rdma", "svcid": "4421"}},
+			),
+			ip:          "192.168.127.1",
+			expectError: true,
+		},
+	}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := common.CheckIPCollision(tcase.config, net.ParseIP(tcase.ip))
+			if tcase.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package common_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func TestValidResize(t *testing.T) {
+	t.Parallel()
+
+	volumes := []common.VolumeConfig{
+		{Number: 0, SizeKiB: 64},
+		{Number: 1, SizeKiB: 1024},
+	}
+
+	testcases := []struct {
+		name           string
+		volume         int
+		size           uint64
+		expectNotFound bool
+		expectInvalid  bool
+	}{
+		{
+			name:   "grow",
+			volume: 1,
+			size:   2048,
+		},
+		{
+			name:   "same size",
+			volume: 1,
+			size:   1024,
+		},
+		{
+			name:          "shrink",
+			volume:        1,
+			size:          512,
+			expectInvalid: true,
+		},
+		{
+			name:          "volume 0",
+			volume:        0,
+			size:          2048,
+			expectInvalid: true,
+		},
+		{
+			name:           "unknown volume",
+			volume:         2,
+			size:           2048,
+			expectNotFound: true,
+		},
+	}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			vol, err := common.ValidResize(volumes, tcase.volume, tcase.size)
+
+			var validationErr common.ValidationError
+			assert.Equal(t, tcase.expectInvalid, errors.As(err, &validationErr))
+			assert.Equal(t, tcase.expectNotFound, errors.Is(err, common.ErrVolumeNotFound))
+			if tcase.expectInvalid || tcase.expectNotFound {
+				assert.Nil(t, vol)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tcase.volume, vol.Number)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package common
+
+import "github.com/LINBIT/linstor-gateway/pkg/reactor"
+
+// SecretsDir is the directory holding the secrets of resources that keep
+// them out of LINSTOR. It is expected to be readable by root only. Each file
+// is named after the resource agent instance that reads it, i.e.
+// "<agent name>_<resource>".
+const SecretsDir = "/etc/linstor-gateway/secrets"
+
+// SecretsDropIn is the systemd drop-in that makes the resource agents started
+// by drbd-reactor load their secrets from SecretsDir. It has to be installed
+// as /etc/systemd/system/ocf.rs@.service.d/linstor-gateway-secrets.conf on
+// every node.
+const SecretsDropIn = `[Service]
+EnvironmentFile=-` + SecretsDir + `/%i
+`
+
+// SecretAttributes are the resource agent attributes in the promoter configs
+// that hold secrets: the CHAP passwords of iSCSI targets and the DH-HMAC-CHAP
+// keys of NVMe-oF targets.
+var SecretAttributes = []string{
+	"incoming_password", "outgoing_password", "initiator_passwords",
+	"dhchap_keys", "dhchap_ctrl_keys",
+}
+
+// RedactSecrets removes the SecretAttributes from all resource agents in cfg.
+// It reports whether anything was removed.
+func RedactSecrets(cfg *reactor.PromoterConfig) bool {
+	redacted := false
+	for _, rscCfg := range cfg.Resources {
+		for _, entry := range rscCfg.Start {
+			agent, ok := entry.(*reactor.ResourceAgent)
+			if !ok {
+				continue
+			}
+
+			for _, key := range SecretAttributes {
+				if _, ok := agent.Attributes[key]; ok {
+					delete(agent.Attributes, key)
+					redacted = true
+				}
+			}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,35 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	t.Parallel()
+
+	target := &reactor.ResourceAgent{Type: "ocf:heartbeat:iSCSITarget", Name: "target", Attributes: map[string]string{
+		"incoming_username": "user",
+		"incoming_password": "secret",
+	}}
+	auth := &reactor.ResourceAgent{Type: "ocf:linstor-gateway:nvmet-auth", Name: "auth", Attributes: map[string]string{
+		"allowed_hosts":    "nqn.2014-08.org.nvmexpress:uuid:host",
+		"dhchap_keys":      "DHHC-1:00:key:",
+		"dhchap_ctrl_keys": "DHHC-1:00:ctrlkey:",
+	}}
+	cfg := &reactor.PromoterConfig{
+		Resources: map[string]reactor.PromoterResourceConfig{
+			"example": {Start: []reactor.StartEntry{target, &reactor.SystemdService{Name: "service"}, auth}},
+		},
+	}
+
+	assert.True(t, common.RedactSecrets(cfg))
+	assert.Equal(t, map[string]string{"incoming_username": "user"}, target.Attributes)
+	assert.Equal(t, map[string]string{"allowed_hosts": "nqn.2014-08.org.nvmexpress:uuid:host"}, auth.Attributes)
+
+	assert.False(t, common.RedactSecrets(cfg))
+}
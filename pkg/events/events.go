@@ -0,0 +1,281 @@
+// Package events detects changes in the state of gateway resources, so that
+// clients can be notified instead of polling for them.
+package events
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+// DefaultInterval is the time between two checks of the resource states.
+const DefaultInterval = 5 * time.Second
+
+// subscriberBuffer is the number of events a subscriber may lag behind
+// before it is dropped.
+const subscriberBuffer = 64
+
+type Type string
+
+const (
+	ResourceCreated Type = "resource-created"
+	ResourceDeleted Type = "resource-deleted"
+	ServiceStarted  Type = "service-started"
+	ServiceStopped  Type = "service-stopped"
+	PrimaryMoved    Type = "primary-moved"
+	// StateChanged is sent when the state of the LINSTOR resource or one
+	// of its volumes changes, e.g. from OK to Degraded.
+	StateChanged Type = "state-changed"
+)
+
+// Key identifies a gateway resource.
+type Key struct {
+	// Kind is the type of the resource: "iscsi", "nfs" or "nvme-of".
+	Kind string `json:"kind"`
+	// Name is the IQN, NFS resource name or NQN of the resource.
+	Name string `json:"name"`
+}
+
+// Event is a change of a gateway resource.
+type Event struct {
+	// ID increases with every event sent by the server.
+	ID   uint64    `json:"id"`
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	Key
+	// Status is the new status of the resource, or nil if it was deleted.
+	Status *common.ResourceStatus `json:"status,omitempty"`
+	// Previous is the status before the change, or nil if the resource was
+	// created.
+	Previous *common.ResourceStatus `json:"previous,omitempty"`
+}
+
+// Diff returns the events that lead from the old to the new states. The
+// events are sorted by resource.
+func Diff(old, new map[Key]common.ResourceStatus, now time.Time) []Event {
+	var result []Event
+
+	event := func(t Type, key Key, status, previous *common.ResourceStatus) {
+		result = append(result, Event{Type: t, Time: now, Key: key, Status: status, Previous: previous})
+	}
+
+	for key, newStatus := range new {
+		newStatus := newStatus
+		oldStatus, ok := old[key]
+		if !ok {
+			event(ResourceCreated, key, &newStatus, nil)
+			continue
+		}
+
+		if oldStatus.Service != newStatus.Service {
+			if newStatus.Service == common.ServiceStateStarted {
+				event(ServiceStarted, key, &newStatus, &oldStatus)
+			} else {
+				event(ServiceStopped, key, &newStatus, &oldStatus)
+			}
+		}
+
+		// a stopped resource has no primary, which is already reported as
+		// service-stopped
+		if oldStatus.Primary != newStatus.Primary && oldStatus.Primary != "" && newStatus.Primary != "" {
+			event(PrimaryMoved, key, &newStatus, &oldStatus)
+		}
+
+		if oldStatus.State != newStatus.State || !volumeStatesEqual(oldStatus.Volumes, newStatus.Volumes) {
+			event(StateChanged, key, &newStatus, &oldStatus)
+		}
+	}
+
+	for key, oldStatus := range old {
+		oldStatus := oldStatus
+		if _, ok := new[key]; !ok {
+			event(ResourceDeleted, key, nil, &oldStatus)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+func volumeStatesEqual(a, b []common.VolumeState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Source returns the current status of all gateway resources.
+type Source func(ctx context.Context) (map[Key]common.ResourceStatus, error)
+
+// Watcher periodically checks the status of all gateway resources and sends
+// the changes to all subscribers. The resources are only checked while
+// there are subscribers, or when Current is called.
+type Watcher struct {
+	source   Source
+	interval time.Duration
+
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	nextID      uint64
+	// wake is signalled when the first subscriber arrives.
+	wake chan struct{}
+
+	// stateMu guards the last state that was checked, which is shared with
+	// the callers of Current.
+	stateMu   sync.Mutex
+	state     map[Key]common.ResourceStatus
+	stateTime time.Time
+}
+
+func NewWatcher(source Source, interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Watcher{
+		source:      source,
+		interval:    interval,
+		subscribers: make(map[chan Event]struct{}),
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Subscribe returns a channel that receives all future events. The channel
+// is closed when cancel is called, or when the subscriber does not keep up
+// with the events.
+func (w *Watcher) Subscribe() (events <-chan Event, cancel func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	w.mu.Lock()
+	w.subscribers[ch] = struct{}{}
+	if len(w.subscribers) == 1 {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+	w.mu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if _, ok := w.subscribers[ch]; ok {
+			delete(w.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (w *Watcher) hasSubscribers() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.subscribers) > 0
+}
+
+func (w *Watcher) publish(events []Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range events {
+		w.nextID++
+		events[i].ID = w.nextID
+
+		for ch := range w.subscribers {
+			select {
+			case ch <- events[i]:
+			default:
+				log.Warn("dropping event subscriber that does not keep up")
+				delete(w.subscribers, ch)
+				close(ch)
+			}
+		}
+	}
+}
+
+// Run checks the resources until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// last is nil while nobody is subscribed, so that the first check after
+	// a subscription only takes a baseline instead of reporting everything
+	// as created.
+	var last map[Key]common.ResourceStatus
+
+	for {
+		if !w.hasSubscribers() {
+			last = nil
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.wake:
+			}
+		}
+
+		current, err := w.source(ctx)
+		if err != nil {
+			log.WithError(err).Warn("failed to check resource states")
+		} else {
+			w.store(current)
+			if last != nil {
+				w.publish(Diff(last, current, time.Now()))
+			}
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Current returns the status of all gateway resources. The last checked
+// state is reused if it is not older than the check interval, so that
+// frequent callers do not each list all resources. The returned map must not
+// be modified.
+func (w *Watcher) Current(ctx context.Context) (map[Key]common.ResourceStatus, error) {
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+
+	if w.state != nil && time.Since(w.stateTime) < w.interval {
+		return w.state, nil
+	}
+
+	current, err := w.source(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w.state = current
+	w.stateTime = time.Now()
+
+	return current, nil
+}
+
+func (w *Watcher) store(state map[Key]common.ResourceStatus) {
+	w.stateMu.Lock()
+	defer w.stateMu.Unlock()
+
+	w.state = state
+	w.stateTime = time.Now()
+}
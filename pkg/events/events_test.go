@@ -0,0 +1,176 @@
+package events_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/events"
+)
+
+var (
+	target = events.Key{Kind: "iscsi", Name: "iqn.2019-08.com.linbit:target"}
+	export = events.Key{Kind: "nfs", Name: "export"}
+)
+
+func started(primary string, state common.ResourceState) common.ResourceStatus {
+	return common.ResourceStatus{
+		State:   state,
+		Service: common.ServiceStateStarted,
+		Primary: primary,
+		Volumes: []common.VolumeState{{Number: 1, State: state}},
+	}
+}
+
+func types(evs []events.Event) []events.Type {
+	var result []events.Type
+	for _, ev := range evs {
+		result = append(result, ev.Type)
+	}
+	return result
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	stopped := common.ResourceStatus{State: common.ResourceStateOK, Service: common.ServiceStateStopped}
+
+	cases := []struct {
+		name     string
+		old, new map[events.Key]common.ResourceStatus
+		want     []events.Type
+	}{{
+		name: "unchanged",
+		old:  map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)},
+		new:  map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)},
+	}, {
+		name: "created and deleted",
+		old:  map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)},
+		new:  map[events.Key]common.ResourceStatus{export: started("node1", common.ResourceStateOK)},
+		want: []events.Type{events.ResourceDeleted, events.ResourceCreated},
+	}, {
+		name: "stopped",
+		old:  map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)},
+		new:  map[events.Key]common.ResourceStatus{target: stopped},
+		want: []events.Type{events.ServiceStopped, events.StateChanged},
+	}, {
+		name: "started",
+		old:  map[events.Key]common.ResourceStatus{target: stopped},
+		new:  map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)},
+		want: []events.Type{events.ServiceStarted, events.StateChanged},
+	}, {
+		name: "moved",
+		old:  map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)},
+		new:  map[events.Key]common.ResourceStatus{target: started("node2", common.ResourceStateOK)},
+		want: []events.Type{events.PrimaryMoved},
+	}, {
+		name: "degraded",
+		old:  map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)},
+		new:  map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateDegraded)},
+		want: []events.Type{events.StateChanged},
+	}}
+
+	for _, tcase := range cases {
+		tcase := tcase
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tcase.want, types(events.Diff(tcase.old, tcase.new, time.Now())))
+		})
+	}
+}
+
+func TestDiffStatus(t *testing.T) {
+	t.Parallel()
+
+	old := map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)}
+	new := map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateBad)}
+
+	evs := events.Diff(old, new, time.Now())
+	require.Len(t, evs, 1)
+	assert.Equal(t, target, evs[0].Key)
+	assert.Equal(t, common.ResourceStateOK, evs[0].Previous.State)
+	assert.Equal(t, common.ResourceStateBad, evs[0].Status.State)
+
+	evs = events.Diff(old, nil, time.Now())
+	require.Len(t, evs, 1)
+	assert.Nil(t, evs[0].Status)
+	assert.Equal(t, "node1", evs[0].Previous.Primary)
+}
+
+// fakeSource returns the states that were set last.
+type fakeSource struct {
+	sync.Mutex
+	states map[events.Key]common.ResourceStatus
+	calls  int
+}
+
+func (f *fakeSource) set(states map[events.Key]common.ResourceStatus) {
+	f.Lock()
+	defer f.Unlock()
+	f.states = states
+}
+
+func (f *fakeSource) get(ctx context.Context) (map[events.Key]common.ResourceStatus, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.calls++
+	return f.states, nil
+}
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	source := &fakeSource{states: map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)}}
+	w := events.NewWatcher(source.get, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	ch, unsubscribe := w.Subscribe()
+
+	// give the watcher time to take its baseline, which must not be reported
+	time.Sleep(50 * time.Millisecond)
+	source.set(map[events.Key]common.ResourceStatus{target: started("node2", common.ResourceStateOK)})
+
+	select {
+	case ev := <-ch:
+		assert.Equal(t, events.PrimaryMoved, ev.Type)
+		assert.Equal(t, target, ev.Key)
+		assert.Equal(t, uint64(1), ev.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	unsubscribe()
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestWatcherCurrent(t *testing.T) {
+	t.Parallel()
+
+	source := &fakeSource{states: map[events.Key]common.ResourceStatus{target: started("node1", common.ResourceStateOK)}}
+	w := events.NewWatcher(source.get, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		current, err := w.Current(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "node1", current[target].Primary)
+	}
+	assert.Equal(t, 1, source.calls)
+
+	time.Sleep(100 * time.Millisecond)
+	source.set(map[events.Key]common.ResourceStatus{target: started("node2", common.ResourceStateOK)})
+
+	current, err := w.Current(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "node2", current[target].Primary)
+	assert.Equal(t, 2, source.calls)
+}
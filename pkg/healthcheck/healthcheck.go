@@ -3,10 +3,12 @@ package healthcheck
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 
 	"github.com/fatih/color"
 
 	"github.com/LINBIT/linstor-gateway/client"
+	"github.com/LINBIT/linstor-gateway/ocf"
 )
 
 var bold = color.New(color.Bold).SprintfFunc()
@@ -103,6 +105,15 @@ func checkAgent(iscsiBackends []string) error {
 	}
 
 	var iscsiChecks []checker
+	if len(iscsiBackends) > 0 {
+		iscsiChecks = append(iscsiChecks,
+			&checkFileExists{
+				filename:    filepath.Join(ocf.Dir, "iscsi-auth"),
+				packageName: "linstor-gateway",
+				hint:        "The iscsi-auth resource agent applies mutual and per-initiator CHAP. It is installed together with linstor-gateway.",
+			},
+		)
+	}
 	backendsMap := toMap(iscsiBackends)
 	for backend := range backendsMap {
 		switch backend {
@@ -130,6 +141,11 @@ func checkAgent(iscsiBackends []string) error {
 			packageName: "resource-agents",
 			hint:        "The nvmet-* resource agents are only shipped with resource-agents 4.9.0 or later. See https://github.com/ClusterLabs/resource-agents for instructions on how to manually install a newer version.",
 		},
+		&checkFileExists{
+			filename:    filepath.Join(ocf.Dir, "nvmet-auth"),
+			packageName: "linstor-gateway",
+			hint:        "The nvmet-auth resource agent applies the DH-HMAC-CHAP keys of allowed hosts. It is installed together with linstor-gateway.",
+		},
 		&checkInPath{binary: "nvmetcli", packageName: "nvmetcli", hint: "nvmetcli is not (yet) packaged on all distributions. See https://git.infradead.org/users/hch/nvmetcli.git for instructions on how to manually install it."},
 		&checkKernelModuleLoaded{"nvmet", "nvmetcli"},
 	)
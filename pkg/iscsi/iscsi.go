@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/google/go-cmp/cmp"
 	"path/filepath"
+	"slices"
 	"sort"
 	"time"
 
@@ -90,6 +91,70 @@ func (i *ISCSI) getExistingDeployment(ctx context.Context, rsc *ResourceConfig,
 // described in rsc. It automatically prepends a "cluster private volume" to the
 // list of volumes, so volume numbers must start at 1.
 func (i *ISCSI) Create(ctx context.Context, rsc *ResourceConfig) (*ResourceConfig, error) {
+	return i.create(ctx, rsc, nil)
+}
+
+// Clone creates a new iSCSI target from a snapshot of the target src. rsc
+// describes the new target: its IQN and service IPs must be set, all other
+// settings are copied from src unless given. The volumes of the new target
+// are restored from the snapshot, so any volumes in rsc are ignored.
+// If snapshot is empty, a new snapshot of src is taken first. Such a snapshot
+// is not deleted afterwards, as some storage backends need it for as long as
+// the clone exists.
+func (i *ISCSI) Clone(ctx context.Context, src Iqn, snapshot string, rsc *ResourceConfig) (*ResourceConfig, error) {
+	if rsc.IQN.WWN() == src.WWN() {
+		return nil, common.ValidationError("clone must have a different name than its source")
+	}
+
+	srcCfg, err := i.Get(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	if srcCfg == nil {
+		return nil, nil
+	}
+
+	var snap *common.Snapshot
+	if snapshot == "" {
+		snap, err = i.CreateSnapshot(ctx, src, "")
+	} else {
+		snap, err = i.cli.Snapshot(ctx, src.WWN(), snapshot)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rsc.AllowedInitiators == nil {
+		rsc.AllowedInitiators = srcCfg.AllowedInitiators
+	}
+	if rsc.ResourceGroup == "" {
+		rsc.ResourceGroup = srcCfg.ResourceGroup
+	}
+	if rsc.Username == "" && rsc.Password == "" {
+		rsc.Username = srcCfg.Username
+		rsc.Password = srcCfg.Password
+		rsc.InlineSecrets = srcCfg.InlineSecrets
+	}
+	if rsc.MutualUsername == "" && rsc.MutualPassword == "" {
+		rsc.MutualUsername = srcCfg.MutualUsername
+		rsc.MutualPassword = srcCfg.MutualPassword
+	}
+	if rsc.InitiatorCredentials == nil {
+		rsc.InitiatorCredentials = srcCfg.InitiatorCredentials
+	}
+	if rsc.Implementation == "" {
+		rsc.Implementation = srcCfg.Implementation
+	}
+
+	rsc.Volumes = snap.UserVolumes()
+
+	return i.create(ctx, rsc, &linstorcontrol.SnapshotSource{Resource: src.WWN(), Snapshot: snap.Name})
+}
+
+// create creates an iSCSI target as described in rsc. If from is not nil, the
+// volumes are restored from that snapshot.
+func (i *ISCSI) create(ctx context.Context, rsc *ResourceConfig, from *linstorcontrol.SnapshotSource) (*ResourceConfig, error) {
 	rsc.FillDefaults()
 
 	// prepend cluster private volume; it should always be the first volume and have number 0
@@ -133,6 +198,7 @@ func (i *ISCSI) Create(ctx context.Context, rsc *ResourceConfig) (*ResourceConfi
 		ResourceGroup: rsc.ResourceGroup,
 		Volumes:       rsc.Volumes,
 		GrossSize:     rsc.GrossSize,
+		FromSnapshot:  from,
 	}, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create linstor resource: %w", err)
@@ -249,6 +315,36 @@ func (i *ISCSI) Stop(ctx context.Context, iqn Iqn, resourceTimeout time.Duration
 	return i.Get(ctx, iqn)
 }
 
+// Migrate moves a running target to the given node, for example to free the
+// current node for maintenance. It returns once the target is running on the
+// new node again.
+// If the target does not exist, nil is returned.
+func (i *ISCSI) Migrate(ctx context.Context, iqn Iqn, node string, resourceTimeout time.Duration) (*ResourceConfig, error) {
+	if resourceTimeout == 0 {
+		resourceTimeout = DefaultResourceTimeout
+	}
+
+	id := fmt.Sprintf(IDFormat, iqn.WWN())
+	cfg, _, err := reactor.FindConfig(ctx, i.cli.Client, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the resource configuration: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, resourceTimeout)
+	defer cancel()
+
+	err = common.MigrateResource(waitCtx, i.cli.Client, cfg, id, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate target: %w", err)
+	}
+
+	return i.Get(ctx, iqn)
+}
+
 func (i *ISCSI) List(ctx context.Context) ([]*ResourceConfig, error) {
 	cfgs, paths, err := reactor.ListConfigs(ctx, i.cli.Client)
 	if err != nil {
@@ -383,6 +479,50 @@ func (i *ISCSI) AddVolume(ctx context.Context, iqn Iqn, volCfg *common.VolumeCon
 	return deployedCfg, nil
 }
 
+// ResizeVolume grows the logical unit lun of the target to newSizeKiB. Only
+// the LINSTOR volume definition is resized; DRBD grows the device online, so
+// this works while the target is running. The block backstore of LIO reads
+// the size of the device on every READ CAPACITY, so the target reports the
+// new size right away. SCST caches the size until the target is restarted
+// or "scstadmin -resync_dev" is run on the primary. In both cases the
+// initiators need to rescan the session themselves to pick up the new size.
+func (i *ISCSI) ResizeVolume(ctx context.Context, iqn Iqn, lun int, newSizeKiB uint64) (*ResourceConfig, error) {
+	cfg, _, err := reactor.FindConfig(ctx, i.cli.Client, fmt.Sprintf(IDFormat, iqn.WWN()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, _, volumeDefinitions, _, err := cfg.DeployedResources(ctx, i.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	deployedCfg, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+
+	vol, err := common.ValidResize(deployedCfg.Volumes, lun, newSizeKiB)
+	if err != nil {
+		return nil, fmt.Errorf("target %q: %w", iqn, err)
+	}
+
+	if newSizeKiB > vol.SizeKiB {
+		err = i.cli.ResourceDefinitions.ModifyVolumeDefinition(ctx, iqn.WWN(), lun, client.VolumeDefinitionModify{
+			SizeKib: newSizeKiB,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resize volume definition: %w", err)
+		}
+	}
+
+	return i.Get(ctx, iqn)
+}
+
 func (i *ISCSI) DeleteVolume(ctx context.Context, iqn Iqn, lun int) (*ResourceConfig, error) {
 	cfg, path, err := reactor.FindConfig(ctx, i.cli.Client, fmt.Sprintf(IDFormat, iqn.WWN()))
 	if err != nil {
@@ -461,3 +601,248 @@ func (i *ISCSI) DeleteVolume(ctx context.Context, iqn Iqn, lun int) (*ResourceCo
 
 	return i.Get(ctx, iqn)
 }
+
+// Patch applies the changes described in patch to an existing target. The
+// promoter config is rewritten, so the target does not have to be deleted and
+// recreated. drbd-reactor restarts the services of a promoter whose config
+// changed, so initiators see a short interruption on the node the target is
+// active on. CHAP credentials of initiators that are no longer allowed are
+// removed.
+// If the target does not exist, nil is returned.
+func (i *ISCSI) Patch(ctx context.Context, iqn Iqn, patch *ResourcePatch) (*ResourceConfig, error) {
+	return i.modify(ctx, iqn, func(rsc *ResourceConfig) error {
+		if patch.AllowedInitiators != nil {
+			rsc.AllowedInitiators = *patch.AllowedInitiators
+			for initiator := range rsc.InitiatorCredentials {
+				if !slices.Contains(rsc.AllowedInitiators, initiator) {
+					delete(rsc.InitiatorCredentials, initiator)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// modify applies fn to the configuration of an existing target and updates
+// the promoter config accordingly. It returns nil if the target does not
+// exist.
+func (i *ISCSI) modify(ctx context.Context, iqn Iqn, fn func(rsc *ResourceConfig) error) (*ResourceConfig, error) {
+	cfg, path, err := reactor.FindConfig(ctx, i.cli.Client, fmt.Sprintf(IDFormat, iqn.WWN()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, resourceGroup, volumeDefinitions, resources, err := cfg.DeployedResources(ctx, i.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	deployedCfg, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+
+	err = fn(deployedCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = deployedCfg.Valid()
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	cfg, err = deployedCfg.ToPromoter(resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert resource to promoter configuration: %w", err)
+	}
+
+	err = reactor.EnsureConfig(ctx, i.cli.Client, cfg, deployedCfg.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update config: %w", err)
+	}
+
+	deployedCfg.Status = linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+
+	return deployedCfg, nil
+}
+
+// CreateSnapshot takes a snapshot of all volumes of the target. If name is
+// empty, a name is generated from the current time.
+func (i *ISCSI) CreateSnapshot(ctx context.Context, iqn Iqn, name string) (*common.Snapshot, error) {
+	return i.cli.CreateSnapshot(ctx, iqn.WWN(), name)
+}
+
+// ListSnapshots lists all snapshots of the target, oldest first.
+func (i *ISCSI) ListSnapshots(ctx context.Context, iqn Iqn) ([]common.Snapshot, error) {
+	return i.cli.Snapshots(ctx, iqn.WWN())
+}
+
+// DeleteSnapshot deletes the named snapshot of the target.
+func (i *ISCSI) DeleteSnapshot(ctx context.Context, iqn Iqn, name string) error {
+	return i.cli.DeleteSnapshot(ctx, iqn.WWN(), name)
+}
+
+// RollbackSnapshot resets the target to the state of the named snapshot.
+// The target is stopped for the rollback. If it was running before, it is
+// started again afterwards.
+func (i *ISCSI) RollbackSnapshot(ctx context.Context, iqn Iqn, name string, resourceTimeout time.Duration) (*ResourceConfig, error) {
+	cfg, err := i.Get(ctx, iqn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	// make sure the snapshot exists before the target is stopped
+	_, err = i.cli.Snapshot(ctx, iqn.WWN(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	wasRunning := cfg.Status.Service == common.ServiceStateStarted
+
+	cfg, err = i.Stop(ctx, iqn, resourceTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop target: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	rollbackErr := i.cli.RollbackSnapshot(ctx, iqn.WWN(), name)
+	if !wasRunning {
+		if rollbackErr != nil {
+			return nil, rollbackErr
+		}
+		return i.Get(ctx, iqn)
+	}
+
+	// start again even if the rollback failed, so that the target is not left stopped
+	cfg, err = i.Start(ctx, iqn, resourceTimeout)
+	if rollbackErr != nil {
+		return nil, rollbackErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start target: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// SnapshotSchedule returns the snapshot schedule of the target, or nil if none
+// is set.
+func (i *ISCSI) SnapshotSchedule(ctx context.Context, iqn Iqn) (*common.SnapshotSchedule, error) {
+	return i.cli.SnapshotSchedule(ctx, iqn.WWN())
+}
+
+// SetSnapshotSchedule sets or replaces the snapshot schedule of the target.
+func (i *ISCSI) SetSnapshotSchedule(ctx context.Context, iqn Iqn, schedule common.SnapshotSchedule) error {
+	return i.cli.SetSnapshotSchedule(ctx, iqn.WWN(), schedule)
+}
+
+// DeleteSnapshotSchedule removes the snapshot schedule of the target.
+func (i *ISCSI) DeleteSnapshotSchedule(ctx context.Context, iqn Iqn) error {
+	return i.cli.DeleteSnapshotSchedule(ctx, iqn.WWN())
+}
+
+// Replication returns the replication settings of the target, or nil if it
+// is not replicated.
+func (i *ISCSI) Replication(ctx context.Context, iqn Iqn) (*common.Replication, error) {
+	return i.cli.Replication(ctx, iqn.WWN())
+}
+
+// SetReplication sets or replaces the replication settings of the target.
+func (i *ISCSI) SetReplication(ctx context.Context, iqn Iqn, replication common.Replication) error {
+	return i.cli.SetReplication(ctx, iqn.WWN(), replication)
+}
+
+// DeleteReplication stops replicating the target.
+func (i *ISCSI) DeleteReplication(ctx context.Context, iqn Iqn) error {
+	return i.cli.DeleteReplication(ctx, iqn.WWN())
+}
+
+// ShipSnapshot immediately ships a snapshot of the target to the remote it
+// is replicated to. It returns the name of the shipped snapshot.
+func (i *ISCSI) ShipSnapshot(ctx context.Context, iqn Iqn) (string, error) {
+	replication, err := i.cli.Replication(ctx, iqn.WWN())
+	if err != nil {
+		return "", err
+	}
+
+	if replication == nil {
+		return "", common.ValidationError("target is not replicated")
+	}
+
+	return i.cli.ShipSnapshot(ctx, iqn.WWN(), replication.Remote)
+}
+
+// PromoteDR brings up a target that was replicated to this cluster from
+// another one. If snapshot is empty, the latest shipped snapshot is used.
+// The target keeps its IQN, logical units, allowed initiators and CHAP
+// usernames. As the remote site usually uses a different network, the
+// service IPs and resource group can be overridden via rsc. Replication
+// settings are not carried over to the promoted target.
+// The CHAP passwords are not replicated. They are read from the secrets file
+// on the nodes of this cluster, unless rsc has InlineSecrets set and
+// contains them.
+// If no replica of the target exists, nil is returned.
+func (i *ISCSI) PromoteDR(ctx context.Context, iqn Iqn, snapshot string, rsc *ResourceConfig) (*ResourceConfig, error) {
+	replica, err := i.cli.FindDRReplica(ctx, iqn.WWN(), snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if replica == nil {
+		return nil, nil
+	}
+
+	replicated, err := FromPromoter(replica.Config, &replica.Definition, replica.VolumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse replicated configuration: %w", err)
+	}
+
+	if replicated.IQN.WWN() != iqn.WWN() {
+		return nil, fmt.Errorf("replica contains configuration for %s instead of %s", replicated.IQN, iqn)
+	}
+
+	if len(rsc.ServiceIPs) > 0 {
+		replicated.ServiceIPs = rsc.ServiceIPs
+	}
+	if rsc.ResourceGroup != "" {
+		replicated.ResourceGroup = rsc.ResourceGroup
+	}
+	if rsc.InlineSecrets {
+		replicated.InlineSecrets = true
+		replicated.Password = rsc.Password
+		replicated.MutualPassword = rsc.MutualPassword
+		for initiator, creds := range replicated.InitiatorCredentials {
+			creds.Password = rsc.InitiatorCredentials[initiator].Password
+			replicated.InitiatorCredentials[initiator] = creds
+		}
+	}
+	replicated.ResourceTimeout = rsc.ResourceTimeout
+	replicated.Volumes = replica.Snapshot.UserVolumes()
+
+	result, err := i.create(ctx, replicated, &linstorcontrol.SnapshotSource{
+		Resource: replica.Definition.Name,
+		Snapshot: replica.Snapshot.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.cli.DeleteReplication(ctx, iqn.WWN())
+	if err != nil {
+		log.WithError(err).Warn("failed to remove replication settings from promoted target")
+	}
+
+	return result, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/LINBIT/golinstor/client"
 
+	"github.com/LINBIT/linstor-gateway/ocf"
 	"github.com/LINBIT/linstor-gateway/pkg/common"
 	"github.com/LINBIT/linstor-gateway/pkg/reactor"
 )
@@ -26,6 +28,13 @@ const (
 	DefaultResourceTimeout  = 30 * time.Second
 )
 
+// CHAPCredentials is a username and password pair used for CHAP
+// authentication.
+type CHAPCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
 type ResourceConfig struct {
 	IQN               Iqn                   `json:"iqn"`
 	AllowedInitiators []Iqn                 `json:"allowed_initiators,omitempty"`
@@ -38,18 +47,89 @@ type ResourceConfig struct {
 	GrossSize         bool                  `json:"gross_size"`
 	Implementation    string                `json:"implementation"`
 	ResourceTimeout   time.Duration         `json:"resource_timeout,omitempty"`
+
+	// MutualUsername and MutualPassword are the credentials the target
+	// uses to authenticate itself to initiators (mutual CHAP).
+	MutualUsername string `json:"mutual_username,omitempty"`
+	MutualPassword string `json:"mutual_password,omitempty"`
+	// InitiatorCredentials overrides Username and Password for individual
+	// allowed initiators.
+	InitiatorCredentials map[Iqn]CHAPCredentials `json:"initiator_credentials,omitempty"`
+	// InlineSecrets stores the CHAP passwords in the promoter
+	// configuration, which is readable by every LINSTOR user. By default,
+	// the passwords are kept out of LINSTOR: the target reads them from
+	// SecretsPath and AuthSecretsPath on each node, which have to be
+	// provisioned by the administrator, and only the usernames are managed
+	// by LINSTOR Gateway.
+	InlineSecrets bool `json:"inline_secrets,omitempty"`
+	// PreferredNodes are tried first, in order, when drbd-reactor picks a
+	// node to start the target on. The target is never migrated or evacuated to
+	// ForbiddenNodes, but drbd-reactor cannot exclude nodes entirely: on a
+	// failover, they are used if no other node can run the target.
+	// Use "linstor-gateway node evacuate" to keep a node free completely.
+	PreferredNodes []string `json:"preferred_nodes,omitempty"`
+	ForbiddenNodes []string `json:"forbidden_nodes,omitempty"`
+}
+
+// ResourcePatch describes a change to an existing target. Fields that are
+// nil are left unchanged.
+type ResourcePatch struct {
+	AllowedInitiators *[]Iqn `json:"allowed_initiators,omitempty"`
+}
+
+// SecretsPath returns the path of the file holding the CHAP password of the
+// target if InlineSecrets is not set. The file is loaded as systemd
+// EnvironmentFile by common.SecretsDropIn, so the password is given as
+// OCF_RESKEY_incoming_password.
+func (r *ResourceConfig) SecretsPath() string {
+	return filepath.Join(common.SecretsDir, "target_"+r.IQN.WWN())
+}
+
+// AuthSecretsPath is like SecretsPath, but for the passwords of mutual and
+// per-initiator CHAP, which are applied by a separate agent. They are given
+// as OCF_RESKEY_outgoing_password and OCF_RESKEY_initiator_passwords
+// (';'-separated, in the order of the allowed initiators).
+func (r *ResourceConfig) AuthSecretsPath() string {
+	return filepath.Join(common.SecretsDir, "auth_"+r.IQN.WWN())
+}
+
+// Redact removes all secrets from the configuration, so that it can be
+// shown to users. The usernames are kept.
+func (r *ResourceConfig) Redact() {
+	r.Password = ""
+	r.MutualPassword = ""
+	if r.InitiatorCredentials != nil {
+		redacted := make(map[Iqn]CHAPCredentials, len(r.InitiatorCredentials))
+		for initiator, creds := range r.InitiatorCredentials {
+			redacted[initiator] = CHAPCredentials{Username: creds.Username}
+		}
+		r.InitiatorCredentials = redacted
+	}
+}
+
+// RedactPromoter removes the CHAP passwords from the promoter config of an
+// iSCSI target. It reports whether anything was removed.
+func RedactPromoter(cfg *reactor.PromoterConfig) bool {
+	return common.RedactSecrets(cfg)
 }
 
 const (
 	agentTypePortblock   = "ocf:heartbeat:portblock"
 	agentTypeIPaddr2     = "ocf:heartbeat:IPaddr2"
 	agentTypeISCSITarget = "ocf:heartbeat:iSCSITarget"
+	// the iSCSITarget agent only supports a single set of incoming
+	// credentials, mutual and per-initiator CHAP are configured by our own
+	// agent after it
+	agentTypeISCSIAuth = "ocf:" + ocf.Vendor + ":iscsi-auth"
 )
 
 const minAgentEntries = 4 // portblock, service_ip, target, portunblock
 
 func parsePromoterConfig(cfg *reactor.PromoterConfig) (*ResourceConfig, error) {
-	r := &ResourceConfig{}
+	r := &ResourceConfig{
+		PreferredNodes: cfg.Metadata.PreferredNodes,
+		ForbiddenNodes: cfg.Metadata.ForbiddenNodes,
+	}
 
 	_, rscCfg := cfg.FirstResource()
 	if rscCfg == nil {
@@ -105,12 +185,30 @@ func parsePromoterConfig(cfg *reactor.PromoterConfig) (*ResourceConfig, error) {
 					}
 				}
 				r.Implementation = agent.Attributes["implementation"]
+			case agentTypeISCSIAuth:
+				r.MutualUsername = agent.Attributes["outgoing_username"]
+				r.MutualPassword = agent.Attributes["outgoing_password"]
+
+				r.InitiatorCredentials, err = parseInitiatorCredentials(r.AllowedInitiators,
+					agent.Attributes["initiator_usernames"], agent.Attributes["initiator_passwords"])
+				if err != nil {
+					return nil, err
+				}
 			}
 		case *reactor.SystemdService:
 			// ignore systemd services for now
 		}
 	}
 
+	// targets created before external secrets were the default have
+	// their passwords in the config, but no marker
+	if !cfg.Metadata.ExternalSecrets {
+		r.InlineSecrets = r.Password != "" || r.MutualPassword != ""
+		for _, creds := range r.InitiatorCredentials {
+			r.InlineSecrets = r.InlineSecrets || creds.Password != ""
+		}
+	}
+
 	if numPortblocks != numPortunblocks {
 		return nil, fmt.Errorf("malformed configuration: got a different number of portblock and portunblock agents")
 	}
@@ -122,6 +220,37 @@ func parsePromoterConfig(cfg *reactor.PromoterConfig) (*ResourceConfig, error) {
 	return r, nil
 }
 
+// parseInitiatorCredentials parses the ';'-separated credential lists of the
+// iscsi-auth agent. Both lists have one (possibly empty) entry per allowed
+// initiator.
+func parseInitiatorCredentials(initiators []Iqn, rawUsernames, rawPasswords string) (map[Iqn]CHAPCredentials, error) {
+	if rawUsernames == "" {
+		return nil, nil
+	}
+
+	usernames := strings.Split(rawUsernames, ";")
+	// the passwords are missing if the target uses external secrets
+	passwords := make([]string, len(usernames))
+	if rawPasswords != "" {
+		passwords = strings.Split(rawPasswords, ";")
+	}
+	if len(usernames) != len(initiators) || len(passwords) != len(initiators) {
+		return nil, fmt.Errorf("malformed configuration: got %d initiator usernames and %d passwords for %d allowed initiators",
+			len(usernames), len(passwords), len(initiators))
+	}
+
+	result := make(map[Iqn]CHAPCredentials)
+	for i, initiator := range initiators {
+		if usernames[i] == "" {
+			continue
+		}
+
+		result[initiator] = CHAPCredentials{Username: usernames[i], Password: passwords[i]}
+	}
+
+	return result, nil
+}
+
 func FromPromoter(cfg *reactor.PromoterConfig, definition *client.ResourceDefinition, volumeDefinitions []client.VolumeDefinition) (*ResourceConfig, error) {
 	r, err := parsePromoterConfig(cfg)
 	if err != nil {
@@ -198,6 +327,11 @@ func (r *ResourceConfig) Valid() error {
 		return common.ValidationError("missing service ips")
 	}
 
+	err := r.validCredentials()
+	if err != nil {
+		return err
+	}
+
 	sort.Slice(r.Volumes, func(i, j int) bool {
 		return r.Volumes[i].Number < r.Volumes[j].Number
 	})
@@ -218,6 +352,105 @@ func (r *ResourceConfig) Valid() error {
 		}
 	}
 
+	err = common.ValidNodePreferences(r.PreferredNodes, r.ForbiddenNodes)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validCredentials checks that the CHAP settings are complete and can be
+// rendered into the agent configuration.
+func (r *ResourceConfig) validCredentials() error {
+	if !r.InlineSecrets {
+		return r.validExternalCredentials()
+	}
+
+	if (r.MutualUsername == "") != (r.MutualPassword == "") {
+		return common.ValidationError("mutual CHAP username and password must be set together")
+	}
+
+	err := r.validImplementation()
+	if err != nil {
+		return err
+	}
+
+	if r.MutualUsername != "" && r.Username == "" && len(r.InitiatorCredentials) == 0 {
+		return common.ValidationError("mutual CHAP requires initiator CHAP credentials")
+	}
+
+	if r.MutualPassword != "" && r.MutualPassword == r.Password {
+		return common.ValidationError("mutual CHAP password must differ from the initiator CHAP password")
+	}
+
+	for initiator, creds := range r.InitiatorCredentials {
+		if !slices.Contains(r.AllowedInitiators, initiator) {
+			return common.ValidationError(fmt.Sprintf("initiator %s has credentials, but is not an allowed initiator", initiator))
+		}
+
+		if creds.Username == "" || creds.Password == "" {
+			return common.ValidationError(fmt.Sprintf("missing CHAP username or password for initiator %s", initiator))
+		}
+
+		// the credentials of all initiators are stored as ';'-separated lists
+		if strings.ContainsAny(creds.Username+creds.Password, "; \t\n") {
+			return common.ValidationError(fmt.Sprintf("CHAP credentials for initiator %s must not contain ';' or whitespace", initiator))
+		}
+
+		if r.MutualPassword != "" && r.MutualPassword == creds.Password {
+			return common.ValidationError(fmt.Sprintf("mutual CHAP password must differ from the CHAP password of initiator %s", initiator))
+		}
+	}
+
+	return nil
+}
+
+// validExternalCredentials checks the CHAP settings of a target that reads its
+// passwords from SecretsPath.
+func (r *ResourceConfig) validExternalCredentials() error {
+	if r.Password != "" {
+		return common.ValidationError(fmt.Sprintf("CHAP password is read from %s on each node and must not be given, unless inline secrets are enabled to store it in LINSTOR", r.SecretsPath()))
+	}
+
+	if r.MutualPassword != "" {
+		return common.ValidationError(fmt.Sprintf("mutual CHAP password is read from %s on each node and must not be given, unless inline secrets are enabled to store it in LINSTOR", r.AuthSecretsPath()))
+	}
+
+	err := r.validImplementation()
+	if err != nil {
+		return err
+	}
+
+	for initiator, creds := range r.InitiatorCredentials {
+		if !slices.Contains(r.AllowedInitiators, initiator) {
+			return common.ValidationError(fmt.Sprintf("initiator %s has credentials, but is not an allowed initiator", initiator))
+		}
+
+		if creds.Password != "" {
+			return common.ValidationError(fmt.Sprintf("CHAP password for initiator %s is read from %s on each node and must not be given, unless inline secrets are enabled to store it in LINSTOR", initiator, r.AuthSecretsPath()))
+		}
+
+		if creds.Username == "" || strings.ContainsAny(creds.Username, "; \t\n") {
+			return common.ValidationError(fmt.Sprintf("CHAP username for initiator %s must be set and must not contain ';' or whitespace", initiator))
+		}
+	}
+
+	return nil
+}
+
+// validImplementation checks that the iscsi-auth agent can apply the mutual
+// and per-initiator CHAP settings with the configured implementation.
+func (r *ResourceConfig) validImplementation() error {
+	if r.MutualUsername != "" && r.Implementation != "lio-t" && r.Implementation != "scst" {
+		return common.ValidationError(`mutual CHAP requires the "lio-t" or "scst" implementation`)
+	}
+
+	// SCST only knows target-wide credentials
+	if len(r.InitiatorCredentials) > 0 && r.Implementation != "lio-t" {
+		return common.ValidationError(`per-initiator CHAP requires the "lio-t" implementation`)
+	}
+
 	return nil
 }
 
@@ -236,6 +469,10 @@ func (r *ResourceConfig) Matches(o *ResourceConfig) bool {
 		return false
 	}
 
+	if !slices.Equal(r.PreferredNodes, o.PreferredNodes) || !slices.Equal(r.ForbiddenNodes, o.ForbiddenNodes) {
+		return false
+	}
+
 	if len(r.Volumes) != len(o.Volumes) {
 		return false
 	}
@@ -258,6 +495,18 @@ func (r *ResourceConfig) Matches(o *ResourceConfig) bool {
 		return false
 	}
 
+	if r.MutualUsername != o.MutualUsername {
+		return false
+	}
+
+	if r.MutualPassword != o.MutualPassword {
+		return false
+	}
+
+	if r.InlineSecrets != o.InlineSecrets {
+		return false
+	}
+
 	return true
 }
 
@@ -273,6 +522,44 @@ func (r *ResourceConfig) ID() string {
 	return fmt.Sprintf(IDFormat, r.IQN.WWN())
 }
 
+// authAgent returns the iscsi-auth agent that applies the mutual and
+// per-initiator CHAP credentials to the target.
+func (r *ResourceConfig) authAgent(allowedInitiators []string) *reactor.ResourceAgent {
+	authAttrs := map[string]string{
+		"iqn":                r.IQN.String(),
+		"allowed_initiators": strings.Join(allowedInitiators, " "),
+	}
+	if r.Implementation != "" {
+		authAttrs["implementation"] = r.Implementation
+	}
+	if r.MutualUsername != "" {
+		authAttrs["outgoing_username"] = r.MutualUsername
+		if r.InlineSecrets {
+			authAttrs["outgoing_password"] = r.MutualPassword
+		}
+	}
+	if len(r.InitiatorCredentials) > 0 {
+		// one entry per allowed initiator, in the same order as "allowed_initiators"
+		usernames := make([]string, len(r.AllowedInitiators))
+		passwords := make([]string, len(r.AllowedInitiators))
+		for i, initiator := range r.AllowedInitiators {
+			usernames[i] = r.InitiatorCredentials[initiator].Username
+			passwords[i] = r.InitiatorCredentials[initiator].Password
+		}
+
+		authAttrs["initiator_usernames"] = strings.Join(usernames, ";")
+		if r.InlineSecrets {
+			authAttrs["initiator_passwords"] = strings.Join(passwords, ";")
+		}
+	}
+
+	return &reactor.ResourceAgent{
+		Type:       agentTypeISCSIAuth,
+		Name:       "auth",
+		Attributes: authAttrs,
+	}
+}
+
 func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*reactor.PromoterConfig, error) {
 	if len(deployment) == 0 {
 		return nil, errors.New("resource config is missing deployment information")
@@ -318,9 +605,11 @@ func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*r
 		"iqn":                r.IQN.String(),
 		"portals":            r.portals(),
 		"incoming_username":  r.Username,
-		"incoming_password":  r.Password,
 		"allowed_initiators": strings.Join(allowedInitiatorStrings, " "),
 	}
+	if r.InlineSecrets {
+		targetAttrs["incoming_password"] = r.Password
+	}
 	if r.Implementation != "" {
 		targetAttrs["implementation"] = r.Implementation
 	}
@@ -331,6 +620,10 @@ func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*r
 		Attributes: targetAttrs,
 	})
 
+	if r.MutualUsername != "" || len(r.InitiatorCredentials) > 0 {
+		agents = append(agents, r.authAgent(allowedInitiatorStrings))
+	}
+
 	for i := 1; i < len(deployedRes.Volumes); i++ {
 		vol := deployedRes.Volumes[i]
 		if int(vol.VolumeNumber) != r.Volumes[i].Number {
@@ -405,10 +698,14 @@ func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*r
 				StopServicesOnExit:  true,
 				OnDrbdDemoteFailure: "reboot-immediate",
 				TargetAs:            "Requires",
+				PreferredNodes:      common.PromoterNodeOrder(r.PreferredNodes, r.ForbiddenNodes, deployment),
 			},
 		},
 		Metadata: reactor.PromoterMetadata{
 			LinstorGatewaySchemaVersion: CurrentVersion,
+			ExternalSecrets:             !r.InlineSecrets,
+			PreferredNodes:              r.PreferredNodes,
+			ForbiddenNodes:              r.ForbiddenNodes,
 		},
 	}, nil
 }
@@ -1,10 +1,16 @@
 package iscsi
 
 import (
+	"strings"
+	"testing"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/ocf"
 	"github.com/LINBIT/linstor-gateway/pkg/common"
 	"github.com/LINBIT/linstor-gateway/pkg/reactor"
-	"github.com/stretchr/testify/assert"
-	"testing"
 )
 
 func ipnet(str string) common.IpCidr {
@@ -40,7 +46,7 @@ func TestParsePromoterConfig(t *testing.T) {
 				},
 			},
 			want: &ResourceConfig{
-				IQN: Iqn{"iqn.2021-08.com.linbit", "target1"}, AllowedInitiators: nil, Username: "user", Password: "password",
+				IQN: Iqn{"iqn.2021-08.com.linbit", "target1"}, AllowedInitiators: nil, Username: "user", Password: "password", InlineSecrets: true,
 				ServiceIPs: []common.IpCidr{ipnet("1.1.1.1/16")},
 			},
 		},
@@ -115,7 +121,7 @@ func TestParsePromoterConfig(t *testing.T) {
 				},
 			},
 			want: &ResourceConfig{
-				IQN: Iqn{"iqn.2021-08.com.linbit", "target1"}, AllowedInitiators: nil, Username: "user", Password: "password",
+				IQN: Iqn{"iqn.2021-08.com.linbit", "target1"}, AllowedInitiators: nil, Username: "user", Password: "password", InlineSecrets: true,
 				ServiceIPs: []common.IpCidr{ipnet("1.1.1.1/16"), ipnet("2.2.2.2/16"), ipnet("3.3.3.3/16")},
 			},
 		},
@@ -135,10 +141,65 @@ func TestParsePromoterConfig(t *testing.T) {
 				},
 			},
 			want: &ResourceConfig{
-				IQN: Iqn{"iqn.2021-08.com.linbit", "target1"}, AllowedInitiators: nil, Username: "user", Password: "password",
+				IQN: Iqn{"iqn.2021-08.com.linbit", "target1"}, AllowedInitiators: nil, Username: "user", Password: "password", InlineSecrets: true,
 				ServiceIPs: []common.IpCidr{ipnet("1.1.1.1/16")}, Implementation: "scst",
 			},
 		},
+		{
+			name: "with mutual and per-initiator chap",
+			cfg: &reactor.PromoterConfig{
+				Resources: map[string]reactor.PromoterResourceConfig{
+					"target1": {
+						Start: []reactor.StartEntry{
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:portblock", Name: "pblock0", Attributes: map[string]string{"action": "block", "ip": "1.1.1.1", "portno": "3260", "protocol": "tcp"}},
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:IPaddr2", Name: "service_ip0", Attributes: map[string]string{"cidr_netmask": "16", "ip": "1.1.1.1"}},
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:iSCSITarget", Name: "target", Attributes: map[string]string{"implementation": "lio-t", "allowed_initiators": "iqn.2021-08.com.linbit:init1 iqn.2021-08.com.linbit:init2", "incoming_username": "user", "incoming_password": "password", "iqn": "iqn.2021-08.com.linbit:target1", "portals": "1.1.1.1:3260"}},
+							&reactor.ResourceAgent{Type: "ocf:linstor-gateway:iscsi-auth", Name: "auth", Attributes: map[string]string{"implementation": "lio-t", "allowed_initiators": "iqn.2021-08.com.linbit:init1 iqn.2021-08.com.linbit:init2", "outgoing_username": "target", "outgoing_password": "targetpassword", "initiator_usernames": ";user2", "initiator_passwords": ";password2", "iqn": "iqn.2021-08.com.linbit:target1"}},
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:iSCSILogicalUnit", Name: "lu1", Attributes: map[string]string{"implementation": "lio-t", "lun": "1", "path": "/dev/drbd/by-res/target1/1", "product_id": "LINSTOR iSCSI", "target_iqn": "iqn.2021-08.com.linbit:target1"}},
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:portblock", Name: "punblock0", Attributes: map[string]string{"action": "unblock", "ip": "1.1.1.1", "portno": "3260", "protocol": "tcp"}},
+						},
+					},
+				},
+			},
+			want: &ResourceConfig{
+				IQN:               Iqn{"iqn.2021-08.com.linbit", "target1"},
+				AllowedInitiators: []Iqn{{"iqn.2021-08.com.linbit", "init1"}, {"iqn.2021-08.com.linbit", "init2"}},
+				Username:          "user", Password: "password", InlineSecrets: true,
+				MutualUsername: "target", MutualPassword: "targetpassword",
+				InitiatorCredentials: map[Iqn]CHAPCredentials{
+					{"iqn.2021-08.com.linbit", "init2"}: {Username: "user2", Password: "password2"},
+				},
+				ServiceIPs: []common.IpCidr{ipnet("1.1.1.1/16")}, Implementation: "lio-t",
+			},
+		},
+		{
+			name: "with external secrets",
+			cfg: &reactor.PromoterConfig{
+				Resources: map[string]reactor.PromoterResourceConfig{
+					"target1": {
+						Start: []reactor.StartEntry{
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:portblock", Name: "pblock0", Attributes: map[string]string{"action": "block", "ip": "1.1.1.1", "portno": "3260", "protocol": "tcp"}},
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:IPaddr2", Name: "service_ip0", Attributes: map[string]string{"cidr_netmask": "16", "ip": "1.1.1.1"}},
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:iSCSITarget", Name: "target", Attributes: map[string]string{"implementation": "lio-t", "allowed_initiators": "iqn.2021-08.com.linbit:init1 iqn.2021-08.com.linbit:init2", "incoming_username": "user", "iqn": "iqn.2021-08.com.linbit:target1", "portals": "1.1.1.1:3260"}},
+							&reactor.ResourceAgent{Type: "ocf:linstor-gateway:iscsi-auth", Name: "auth", Attributes: map[string]string{"implementation": "lio-t", "allowed_initiators": "iqn.2021-08.com.linbit:init1 iqn.2021-08.com.linbit:init2", "outgoing_username": "target", "initiator_usernames": ";user2", "iqn": "iqn.2021-08.com.linbit:target1"}},
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:iSCSILogicalUnit", Name: "lu1", Attributes: map[string]string{"implementation": "lio-t", "lun": "1", "path": "/dev/drbd/by-res/target1/1", "product_id": "LINSTOR iSCSI", "target_iqn": "iqn.2021-08.com.linbit:target1"}},
+							&reactor.ResourceAgent{Type: "ocf:heartbeat:portblock", Name: "punblock0", Attributes: map[string]string{"action": "unblock", "ip": "1.1.1.1", "portno": "3260", "protocol": "tcp"}},
+						},
+					},
+				},
+				Metadata: reactor.PromoterMetadata{LinstorGatewaySchemaVersion: 1, ExternalSecrets: true},
+			},
+			want: &ResourceConfig{
+				IQN:               Iqn{"iqn.2021-08.com.linbit", "target1"},
+				AllowedInitiators: []Iqn{{"iqn.2021-08.com.linbit", "init1"}, {"iqn.2021-08.com.linbit", "init2"}},
+				Username:          "user",
+				MutualUsername:    "target",
+				InitiatorCredentials: map[Iqn]CHAPCredentials{
+					{"iqn.2021-08.com.linbit", "init2"}: {Username: "user2"},
+				},
+				ServiceIPs: []common.IpCidr{ipnet("1.1.1.1/16")}, Implementation: "lio-t",
+			},
+		},
 	}
 	for i := range tests {
 		tcase := &tests[i]
@@ -154,3 +215,196 @@ func TestParsePromoterConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestValidCredentials(t *testing.T) {
+	t.Parallel()
+
+	init1 := Iqn{"iqn.2021-08.com.linbit", "init1"}
+
+	tests := []struct {
+		name    string
+		rsc     ResourceConfig
+		wantErr bool
+	}{
+		{
+			name: "plain chap",
+			rsc:  ResourceConfig{Username: "user", Password: "password", InlineSecrets: true},
+		},
+		{
+			name: "mutual chap",
+			rsc:  ResourceConfig{Username: "user", Password: "password", MutualUsername: "target", MutualPassword: "targetpassword", InlineSecrets: true, Implementation: "lio-t"},
+		},
+		{
+			name:    "mutual chap with unsupported implementation",
+			rsc:     ResourceConfig{Username: "user", Password: "password", MutualUsername: "target", MutualPassword: "targetpassword", InlineSecrets: true, Implementation: "tgt"},
+			wantErr: true,
+		},
+		{
+			name:    "mutual chap without initiator chap",
+			rsc:     ResourceConfig{MutualUsername: "target", MutualPassword: "targetpassword", InlineSecrets: true, Implementation: "scst"},
+			wantErr: true,
+		},
+		{
+			name:    "mutual chap with same password",
+			rsc:     ResourceConfig{Username: "user", Password: "password", MutualUsername: "target", MutualPassword: "password", InlineSecrets: true, Implementation: "scst"},
+			wantErr: true,
+		},
+		{
+			name: "per-initiator chap",
+			rsc: ResourceConfig{
+				AllowedInitiators:    []Iqn{init1},
+				InitiatorCredentials: map[Iqn]CHAPCredentials{init1: {Username: "user1", Password: "password1"}},
+				InlineSecrets:        true,
+				Implementation:       "lio-t",
+			},
+		},
+		{
+			name: "per-initiator chap with scst",
+			rsc: ResourceConfig{
+				AllowedInitiators:    []Iqn{init1},
+				InitiatorCredentials: map[Iqn]CHAPCredentials{init1: {Username: "user1", Password: "password1"}},
+				InlineSecrets:        true,
+				Implementation:       "scst",
+			},
+			wantErr: true,
+		},
+		{
+			name: "per-initiator chap for unknown initiator",
+			rsc: ResourceConfig{
+				InitiatorCredentials: map[Iqn]CHAPCredentials{init1: {Username: "user1", Password: "password1"}},
+				InlineSecrets:        true,
+				Implementation:       "lio-t",
+			},
+			wantErr: true,
+		},
+		{
+			name: "per-initiator chap with separator",
+			rsc: ResourceConfig{
+				AllowedInitiators:    []Iqn{init1},
+				InitiatorCredentials: map[Iqn]CHAPCredentials{init1: {Username: "user1", Password: "pass;word1"}},
+				InlineSecrets:        true,
+				Implementation:       "lio-t",
+			},
+			wantErr: true,
+		},
+		{
+			name: "external secrets",
+			rsc: ResourceConfig{
+				Username:             "user",
+				MutualUsername:       "target",
+				AllowedInitiators:    []Iqn{init1},
+				InitiatorCredentials: map[Iqn]CHAPCredentials{init1: {Username: "user1"}},
+				Implementation:       "lio-t",
+			},
+		},
+		{
+			name:    "password without inline secrets",
+			rsc:     ResourceConfig{Username: "user", Password: "password"},
+			wantErr: true,
+		},
+	}
+	for i := range tests {
+		tcase := &tests[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+			err := tcase.rsc.validCredentials()
+			if tcase.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRedactPromoter(t *testing.T) {
+	t.Parallel()
+
+	target := &reactor.ResourceAgent{
+		Type: agentTypeISCSITarget,
+		Name: "target",
+		Attributes: map[string]string{
+			"iqn":                 "iqn.2021-08.com.linbit:target",
+			"incoming_username":   "user",
+			"incoming_password":   "password",
+			"outgoing_password":   "targetpassword",
+			"initiator_passwords": "a;b",
+		},
+	}
+	ip := &reactor.ResourceAgent{Type: agentTypeIPaddr2, Name: "service_ip0", Attributes: map[string]string{"ip": "192.168.127.1"}}
+	cfg := &reactor.PromoterConfig{
+		Resources: map[string]reactor.PromoterResourceConfig{
+			"target": {Start: []reactor.StartEntry{ip, target}},
+		},
+	}
+
+	assert.True(t, RedactPromoter(cfg))
+	assert.Equal(t, map[string]string{"iqn": "iqn.2021-08.com.linbit:target", "incoming_username": "user"}, target.Attributes)
+	assert.Equal(t, map[string]string{"ip": "192.168.127.1"}, ip.Attributes)
+
+	assert.False(t, RedactPromoter(cfg))
+}
+
+// heartbeatParameters are the parameters of the agents from the
+// resource-agents package that are used for iSCSI targets.
+var heartbeatParameters = map[string][]string{
+	"ocf:heartbeat:Filesystem":       {"device", "directory", "fstype", "options", "statusfile_prefix", "run_fsck", "fast_stop", "force_clones", "force_unmount", "term_signals"},
+	"ocf:heartbeat:portblock":        {"protocol", "portno", "action", "ip", "reset_local_on_unblock_stop", "tickle_dir", "sync_script", "direction"},
+	"ocf:heartbeat:IPaddr2":          {"ip", "nic", "cidr_netmask", "broadcast", "iflabel"},
+	"ocf:heartbeat:iSCSITarget":      {"implementation", "iqn", "tid", "portals", "allowed_initiators", "incoming_username", "incoming_password", "additional_parameters"},
+	"ocf:heartbeat:iSCSILogicalUnit": {"implementation", "target_iqn", "lun", "path", "scsi_id", "scsi_sn", "vendor_id", "product_id", "additional_parameters", "allowed_initiators"},
+}
+
+func TestToPromoterAgentParameters(t *testing.T) {
+	t.Parallel()
+
+	init1 := Iqn{"iqn.2021-08.com.linbit", "init1"}
+	init2 := Iqn{"iqn.2021-08.com.linbit", "init2"}
+	rsc := &ResourceConfig{
+		IQN:               Iqn{"iqn.2021-08.com.linbit", "target1"},
+		AllowedInitiators: []Iqn{init1, init2},
+		Volumes:           []common.VolumeConfig{{Number: 0, SizeKiB: 64 * 1024}, {Number: 1, SizeKiB: 1024}},
+		Username:          "user", Password: "password",
+		MutualUsername: "target", MutualPassword: "targetpassword",
+		InitiatorCredentials: map[Iqn]CHAPCredentials{init2: {Username: "user2", Password: "password2"}},
+		InlineSecrets:        true,
+		ServiceIPs:           []common.IpCidr{ipnet("1.1.1.1/16")},
+		Implementation:       "lio-t",
+	}
+
+	cfg, err := rsc.ToPromoter([]client.ResourceWithVolumes{{
+		Resource: client.Resource{Name: "target1"},
+		Volumes:  []client.Volume{{VolumeNumber: 0, DevicePath: "/dev/drbd1000"}, {VolumeNumber: 1, DevicePath: "/dev/drbd1001"}},
+	}})
+	require.NoError(t, err)
+
+	_, rscCfg := cfg.FirstResource()
+	var types []string
+	for _, entry := range rscCfg.Start {
+		agent, ok := entry.(*reactor.ResourceAgent)
+		if !ok {
+			continue
+		}
+		types = append(types, agent.Type)
+
+		supported, ok := heartbeatParameters[agent.Type]
+		if vendorAgent, found := strings.CutPrefix(agent.Type, "ocf:"+ocf.Vendor+":"); found {
+			supported, err = ocf.Parameters(vendorAgent)
+			require.NoError(t, err)
+			ok = true
+		}
+		require.True(t, ok, "unknown agent %s", agent.Type)
+
+		for key := range agent.Attributes {
+			assert.Contains(t, supported, key, "agent %s does not support parameter %s", agent.Type, key)
+		}
+	}
+
+	assert.Contains(t, types, agentTypeISCSIAuth)
+
+	parsed, err := parsePromoterConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, rsc.MutualUsername, parsed.MutualUsername)
+	assert.Equal(t, rsc.MutualPassword, parsed.MutualPassword)
+	assert.Equal(t, rsc.InitiatorCredentials, parsed.InitiatorCredentials)
+}
@@ -0,0 +1,220 @@
+package iscsi
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/linstorcontrol"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+// UpdateResult is the outcome of an Update.
+type UpdateResult struct {
+	// Config is the configuration of the target after the update.
+	Config *ResourceConfig `json:"config"`
+	// Diff shows the changes to the drbd-reactor configuration. It is
+	// empty if nothing changes.
+	Diff string `json:"diff"`
+	// Restart is set if the target has to be stopped and started again to
+	// apply the change.
+	Restart bool `json:"restart"`
+	// ResourceGroup is the LINSTOR resource group the target is moved to. It
+	// is empty if the resource group does not change.
+	ResourceGroup string `json:"resource_group,omitempty"`
+}
+
+// Update changes an existing target to match desired, without deleting and
+// recreating it. The target is identified by the IQN of desired.
+//
+// Changes to the service IPs or the implementation require the target to be
+// stopped and started again, which is done automatically if it is running.
+// Everything else is only written to the promoter config; drbd-reactor then
+// restarts the services of the target on the node it is active on.
+// A new resource group is set on the LINSTOR resource definition; the
+// resources that are already placed are not moved.
+// If dryRun is set, the changes are only computed, but not applied.
+// If the target does not exist, nil is returned.
+func (i *ISCSI) Update(ctx context.Context, desired *ResourceConfig, dryRun bool) (*UpdateResult, error) {
+	cfg, path, err := reactor.FindConfig(ctx, i.cli.Client, desired.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, resourceGroup, volumeDefinitions, resources, err := cfg.DeployedResources(ctx, i.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	current, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+	current.Status = linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+
+	next, err := mergeUpdate(current, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	err = next.Valid()
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	err = i.checkNewIPs(ctx, current, next)
+	if err != nil {
+		return nil, err
+	}
+
+	newCfg, err := next.ToPromoter(resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert resource to promoter configuration: %w", err)
+	}
+
+	diff, err := reactor.Diff(cfg, newCfg, common.SecretAttributes...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UpdateResult{
+		Config:  next,
+		Diff:    diff,
+		Restart: diff != "" && current.Status.Service == common.ServiceStateStarted && needsRestart(current, next),
+	}
+
+	if next.ResourceGroup != current.ResourceGroup {
+		result.ResourceGroup = next.ResourceGroup
+	}
+
+	if dryRun || (diff == "" && result.ResourceGroup == "") {
+		return result, nil
+	}
+
+	if result.ResourceGroup != "" {
+		err = i.cli.SetResourceGroup(ctx, next.IQN.WWN(), next.ResourceGroup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Restart {
+		_, err = i.Stop(ctx, next.IQN, next.ResourceTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stop target: %w", err)
+		}
+	}
+
+	if diff != "" {
+		err = reactor.EnsureConfig(ctx, i.cli.Client, newCfg, next.ID())
+		if err != nil {
+			return nil, fmt.Errorf("failed to update config: %w", err)
+		}
+	}
+
+	if result.Restart {
+		_, err = i.Start(ctx, next.IQN, next.ResourceTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start target: %w", err)
+		}
+	}
+
+	result.Config, err = i.Get(ctx, next.IQN)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// mergeUpdate returns the configuration that results from applying desired to
+// current. Secrets that are left empty in desired are kept, so that a
+// redacted configuration can be passed back as is.
+func mergeUpdate(current, desired *ResourceConfig) (*ResourceConfig, error) {
+	next := *desired
+	next.Status = common.ResourceStatus{}
+
+	if next.ResourceGroup == "" {
+		next.ResourceGroup = current.ResourceGroup
+	}
+
+	if len(next.Volumes) != 0 && !common.SameUserVolumes(next.Volumes, current.Volumes) {
+		return nil, common.ValidationError("logical units cannot be changed by an update, add or resize them instead")
+	}
+	next.Volumes = current.Volumes
+	next.GrossSize = current.GrossSize
+
+	if next.Password == "" && next.Username == current.Username {
+		next.Password = current.Password
+	}
+	if next.MutualPassword == "" && next.MutualUsername == current.MutualUsername {
+		next.MutualPassword = current.MutualPassword
+	}
+	if next.InitiatorCredentials != nil {
+		credentials := make(map[Iqn]CHAPCredentials, len(next.InitiatorCredentials))
+		for initiator, creds := range next.InitiatorCredentials {
+			old, ok := current.InitiatorCredentials[initiator]
+			if creds.Password == "" && ok && creds.Username == old.Username {
+				creds.Password = old.Password
+			}
+			credentials[initiator] = creds
+		}
+		next.InitiatorCredentials = credentials
+	}
+
+	next.FillDefaults()
+
+	return &next, nil
+}
+
+// needsRestart reports whether changing a running target from current to
+// next requires it to be stopped and started again.
+func needsRestart(current, next *ResourceConfig) bool {
+	if current.Implementation != next.Implementation {
+		return true
+	}
+
+	if len(current.ServiceIPs) != len(next.ServiceIPs) {
+		return true
+	}
+
+	for i := range current.ServiceIPs {
+		if current.ServiceIPs[i].String() != next.ServiceIPs[i].String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkNewIPs checks that the service IPs added to the target are not in use
+// by any other resource.
+func (i *ISCSI) checkNewIPs(ctx context.Context, current, next *ResourceConfig) error {
+	configs, _, err := reactor.ListConfigs(ctx, i.cli.Client)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve existing configs: %w", err)
+	}
+
+	for _, ip := range next.ServiceIPs {
+		if slices.ContainsFunc(current.ServiceIPs, func(c common.IpCidr) bool { return c.IP().Equal(ip.IP()) }) {
+			continue
+		}
+
+		for _, c := range configs {
+			if name, _ := c.FirstResource(); name == next.IQN.WWN() {
+				continue
+			}
+
+			if err := common.CheckIPCollision(c, ip.IP()); err != nil {
+				return fmt.Errorf("invalid configuration: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package iscsi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func TestMergeUpdate(t *testing.T) {
+	t.Parallel()
+
+	init1 := Iqn{"iqn.2021-08.com.linbit", "init1"}
+	current := &ResourceConfig{
+		IQN:                  Iqn{"iqn.2021-08.com.linbit", "target1"},
+		ResourceGroup:        "rg1",
+		Volumes:              []common.VolumeConfig{common.ClusterPrivateVolume(), {Number: 1, SizeKiB: 1024}},
+		Username:             "user",
+		Password:             "password",
+		AllowedInitiators:    []Iqn{init1},
+		InitiatorCredentials: map[Iqn]CHAPCredentials{init1: {Username: "user1", Password: "password1"}},
+		ServiceIPs:           []common.IpCidr{ipnet("1.1.1.1/24")},
+	}
+
+	t.Run("keeps redacted secrets", func(t *testing.T) {
+		t.Parallel()
+
+		desired := *current
+		desired.Redact()
+		desired.Volumes = nil
+
+		next, err := mergeUpdate(current, &desired)
+		assert.NoError(t, err)
+		assert.Equal(t, "password", next.Password)
+		assert.Equal(t, "password1", next.InitiatorCredentials[init1].Password)
+		assert.Equal(t, current.Volumes, next.Volumes)
+		assert.False(t, needsRestart(current, next))
+	})
+
+	t.Run("drops secrets of changed usernames", func(t *testing.T) {
+		t.Parallel()
+
+		desired := *current
+		desired.Username = "other"
+		desired.Password = ""
+
+		next, err := mergeUpdate(current, &desired)
+		assert.NoError(t, err)
+		assert.Empty(t, next.Password)
+	})
+
+	t.Run("service ip change needs restart", func(t *testing.T) {
+		t.Parallel()
+
+		desired := *current
+		desired.ServiceIPs = []common.IpCidr{ipnet("1.1.1.2/24")}
+
+		next, err := mergeUpdate(current, &desired)
+		assert.NoError(t, err)
+		assert.True(t, needsRestart(current, next))
+	})
+
+	t.Run("rejects volume changes", func(t *testing.T) {
+		t.Parallel()
+
+		desired := *current
+		desired.Volumes = []common.VolumeConfig{{Number: 1, SizeKiB: 2048}}
+
+		_, err := mergeUpdate(current, &desired)
+		assert.Error(t, err)
+	})
+
+	t.Run("resource group change", func(t *testing.T) {
+		t.Parallel()
+
+		desired := *current
+		desired.ResourceGroup = "rg2"
+
+		next, err := mergeUpdate(current, &desired)
+		assert.NoError(t, err)
+		assert.Equal(t, "rg2", next.ResourceGroup)
+		assert.False(t, needsRestart(current, next))
+	})
+
+	t.Run("keeps resource group", func(t *testing.T) {
+		t.Parallel()
+
+		desired := *current
+		desired.ResourceGroup = ""
+
+		next, err := mergeUpdate(current, &desired)
+		assert.NoError(t, err)
+		assert.Equal(t, "rg1", next.ResourceGroup)
+	})
+}
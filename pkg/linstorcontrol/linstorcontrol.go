@@ -4,8 +4,13 @@ package linstorcontrol
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"slices"
 	"sort"
 
 	"github.com/icza/gog"
@@ -15,12 +20,16 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/metrics"
 	"github.com/LINBIT/linstor-gateway/pkg/version"
 )
 
 // Linstor is a struct containing the configuration that is needed to create or delete a LINSTOR resource.
 type Linstor struct {
 	*client.Client
+	// http is the HTTP client golinstor uses, for the few requests it has no
+	// call for.
+	http *http.Client
 }
 
 type Resource struct {
@@ -29,6 +38,15 @@ type Resource struct {
 	ResourceGroup string                `json:"resource_group_name,omitempty"`
 	FileSystem    string                `json:"file_system,omitempty"`
 	GrossSize     bool                  `json:"gross_size"`
+	// FromSnapshot, if set, restores the volumes of the new resource from a
+	// snapshot instead of creating empty ones.
+	FromSnapshot *SnapshotSource `json:"from_snapshot,omitempty"`
+}
+
+// SnapshotSource identifies a snapshot of an existing resource.
+type SnapshotSource struct {
+	Resource string `json:"resource_name"`
+	Snapshot string `json:"snapshot_name"`
 }
 
 // CreateResult is a struct than is used as the result of a successful create action.
@@ -127,16 +145,68 @@ func StatusFromResources(serviceCfgPath string, definition *client.ResourceDefin
 }
 
 func Default(controllers []string) (*Linstor, error) {
-	cli, err := client.NewClient(
+	options := []client.Option{
 		client.Log(log.StandardLogger()),
 		client.Controllers(controllers),
 		client.UserAgent(version.UserAgent()),
-	)
+	}
+
+	// golinstor sets up TLS from its environment variables only if no HTTP
+	// client is passed in, so the client is built the same way here, with a
+	// transport that also counts failed requests in the metrics.
+	httpClient, err := httpClientFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Transport = metrics.Transport(httpClient.Transport)
+	options = append(options, client.HTTPClient(httpClient))
+
+	cli, err := client.NewClient(options...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Linstor{Client: cli}, nil
+	return &Linstor{Client: cli, http: httpClient}, nil
+}
+
+// httpClientFromEnv returns the HTTP client for the LINSTOR controller, with
+// TLS set up from golinstor's environment variables in the same way golinstor
+// does it. golinstor only does that itself if no HTTP client is passed in, but
+// the client is also needed for the few requests golinstor has no call for.
+func httpClientFromEnv() (*http.Client, error) {
+	certPEM, cert := os.LookupEnv(client.UserCertEnv)
+	keyPEM, key := os.LookupEnv(client.UserKeyEnv)
+	caPEM, ca := os.LookupEnv(client.RootCAEnv)
+
+	if key != cert {
+		return nil, fmt.Errorf("'%s', '%s': specify both or none", client.UserKeyEnv, client.UserCertEnv)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !cert && !ca {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if ca {
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, fmt.Errorf("failed to get a valid certificate from '%s'", client.RootCAEnv)
+		}
+		tlsConfig.RootCAs = caPool
+	}
+
+	if cert {
+		keyPair, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keys: %w", err)
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, keyPair)
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
 }
 
 // DefaultResourceProps returns the default LINSTOR properties for a new resource
@@ -149,7 +219,8 @@ func DefaultResourceProps() map[string]string {
 	}
 }
 
-// EnsureResource creates or updates the given resource.
+// EnsureResource creates or updates the given resource. Resources placed on
+// evacuated nodes are deactivated there.
 // It returns three values:
 //   - The newly created resource definition
 //   - A slice of all resources that have been spawned from this resource
@@ -212,37 +283,46 @@ func (l *Linstor) EnsureResource(ctx context.Context, res Resource, mayExist boo
 		}()
 	}
 
-	for _, vol := range res.Volumes {
-		logger.WithField("volNr", vol.Number).Trace("ensure volume definition exists")
+	if res.FromSnapshot != nil {
+		logger.WithField("snapshot", res.FromSnapshot.Snapshot).Trace("restore resource from snapshot")
 
-		volProps := map[string]string{}
-		if vol.FileSystem != "" {
-			volProps[apiconsts.NamespcFilesystem+"/"+apiconsts.KeyFsType] = vol.FileSystem
-			volProps[apiconsts.NamespcFilesystem+"/"+apiconsts.KeyFsUser] = vol.FileSystemRootOwner.User
-			volProps[apiconsts.NamespcFilesystem+"/"+apiconsts.KeyFsGroup] = vol.FileSystemRootOwner.Group
-		}
-		var volFlags []string
-		if res.GrossSize {
-			volFlags = append(volFlags, "GROSS_SIZE")
+		err = l.restoreSnapshot(ctx, *res.FromSnapshot, res.Name)
+		if err != nil {
+			return nil, nil, nil, err
 		}
-		err := l.ResourceDefinitions.CreateVolumeDefinition(ctx, res.Name, client.VolumeDefinitionCreate{
-			VolumeDefinition: client.VolumeDefinition{
-				VolumeNumber: gog.Ptr(int32(vol.Number)),
-				SizeKib:      vol.SizeKiB,
-				Props:        volProps,
-				Flags:        volFlags,
-			},
-		})
-		if err != nil && !isErrAlreadyExists(err) {
-			return nil, nil, nil, fmt.Errorf("failed to ensure volume definition: %w", err)
+	} else {
+		for _, vol := range res.Volumes {
+			logger.WithField("volNr", vol.Number).Trace("ensure volume definition exists")
+
+			volProps := map[string]string{}
+			if vol.FileSystem != "" {
+				volProps[apiconsts.NamespcFilesystem+"/"+apiconsts.KeyFsType] = vol.FileSystem
+				volProps[apiconsts.NamespcFilesystem+"/"+apiconsts.KeyFsUser] = vol.FileSystemRootOwner.User
+				volProps[apiconsts.NamespcFilesystem+"/"+apiconsts.KeyFsGroup] = vol.FileSystemRootOwner.Group
+			}
+			var volFlags []string
+			if res.GrossSize {
+				volFlags = append(volFlags, "GROSS_SIZE")
+			}
+			err := l.ResourceDefinitions.CreateVolumeDefinition(ctx, res.Name, client.VolumeDefinitionCreate{
+				VolumeDefinition: client.VolumeDefinition{
+					VolumeNumber: gog.Ptr(int32(vol.Number)),
+					SizeKib:      vol.SizeKiB,
+					Props:        volProps,
+					Flags:        volFlags,
+				},
+			})
+			if err != nil && !isErrAlreadyExists(err) {
+				return nil, nil, nil, fmt.Errorf("failed to ensure volume definition: %w", err)
+			}
 		}
-	}
 
-	logger.Trace("ensure resource is placed")
+		logger.Trace("ensure resource is placed")
 
-	err = l.Resources.Autoplace(ctx, res.Name, client.AutoPlaceRequest{})
-	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to autoplace resources: %w", err)
+		err = l.Resources.Autoplace(ctx, res.Name, client.AutoPlaceRequest{})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to autoplace resources: %w", err)
+		}
 	}
 
 	// XXX: remove this when LINSTOR supports this (see comment above).
@@ -275,6 +355,20 @@ func (l *Linstor) EnsureResource(ctx context.Context, res Resource, mayExist boo
 		return nil, nil, nil, errors.New(fmt.Sprintf("failed to fetch resource '%s'", res.Name))
 	}
 
+	logger.Trace("ensure resource is inactive on evacuated nodes")
+
+	deactivated, err := l.deactivateOnEvacuatedNodes(ctx, view)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if deactivated {
+		view, err = l.Resources.GetResourceView(ctx, &client.ListOpts{Resource: []string{res.Name}})
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to fetch resource view: %w", err)
+		}
+	}
+
 	for _, existingVol := range view[0].Volumes {
 		logger.WithField("volNr", existingVol.VolumeNumber).Trace("ensure existing volume is defined")
 
@@ -297,6 +391,34 @@ func (l *Linstor) EnsureResource(ctx context.Context, res Resource, mayExist boo
 	return &rdef, &rgroup, view, nil
 }
 
+// deactivateOnEvacuatedNodes deactivates the resources placed on nodes marked
+// as evacuated, just like "linstor-gateway node evacuate" does for existing
+// resources, so that drbd-reactor cannot promote them there. It reports
+// whether any resource was deactivated.
+func (l *Linstor) deactivateOnEvacuatedNodes(ctx context.Context, resources []client.ResourceWithVolumes) (bool, error) {
+	evacuated, err := common.EvacuatedNodes(ctx, l.Client)
+	if err != nil {
+		return false, err
+	}
+
+	deactivated := false
+	for _, resource := range resources {
+		if !slices.Contains(evacuated, resource.NodeName) || slices.Contains(resource.Flags, apiconsts.FlagRscInactive) {
+			continue
+		}
+
+		log.WithFields(log.Fields{"resource": resource.Name, "node": resource.NodeName}).Info("deactivating resource on evacuated node")
+
+		err := l.Resources.Deactivate(ctx, resource.Name, resource.NodeName)
+		if err != nil {
+			return false, fmt.Errorf("failed to deactivate resource on evacuated node %s: %w", resource.NodeName, err)
+		}
+		deactivated = true
+	}
+
+	return deactivated, nil
+}
+
 func isErrAlreadyExists(err error) bool {
 	if err == nil {
 		return false
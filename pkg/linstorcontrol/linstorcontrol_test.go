@@ -1,6 +1,9 @@
 package linstorcontrol
 
 import (
+	"net/http"
+	"os"
+
 	"github.com/LINBIT/golinstor/client"
 	"github.com/LINBIT/linstor-gateway/pkg/common"
 	"github.com/stretchr/testify/assert"
@@ -199,3 +202,27 @@ func TestStatusFromResources(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPClientFromEnv(t *testing.T) {
+	httpClient, err := httpClientFromEnv()
+	assert.NoError(t, err)
+	tlsConfig := httpClient.Transport.(*http.Transport).TLSClientConfig
+	if tlsConfig != nil {
+		assert.Nil(t, tlsConfig.RootCAs)
+		assert.Empty(t, tlsConfig.Certificates)
+	}
+
+	t.Setenv(client.UserCertEnv, "cert")
+	_, err = httpClientFromEnv()
+	assert.Error(t, err, "certificate without key")
+
+	t.Setenv(client.UserKeyEnv, "key")
+	_, err = httpClientFromEnv()
+	assert.Error(t, err, "invalid key pair")
+
+	os.Unsetenv(client.UserCertEnv)
+	os.Unsetenv(client.UserKeyEnv)
+	t.Setenv(client.RootCAEnv, "not a certificate")
+	_, err = httpClientFromEnv()
+	assert.Error(t, err, "invalid CA")
+}
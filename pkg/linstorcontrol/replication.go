@@ -0,0 +1,280 @@
+package linstorcontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	apiconsts "github.com/LINBIT/golinstor"
+	"github.com/LINBIT/golinstor/client"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+// DRResourcePrefix is prepended to the name of a resource to get the name of
+// its replica on the remote cluster. The replica only holds the shipped
+// snapshots; the resource itself is recreated from them on promotion.
+const DRResourcePrefix = "dr-"
+
+const (
+	replicationPropPrefix = apiconsts.NamespcAuxiliary + "/linstor-gateway/replication/"
+	replicationPropRemote = replicationPropPrefix + "remote"
+	replicationPropCron   = replicationPropPrefix + "cron"
+	// promoterConfigProp holds a copy of the drbd-reactor configuration of a
+	// resource. It is updated before every shipment, so that the replica
+	// carries everything needed to recreate the resource, except for the
+	// secrets.
+	promoterConfigProp = apiconsts.NamespcAuxiliary + "/linstor-gateway/promoter-config"
+)
+
+// DRResourceName returns the name of the replica of the given resource on the
+// remote cluster.
+func DRResourceName(resource string) string {
+	return DRResourcePrefix + resource
+}
+
+// DRReplica is a resource that was replicated from another cluster.
+type DRReplica struct {
+	// Definition is the resource definition holding the shipped snapshots.
+	Definition client.ResourceDefinition
+	// VolumeDefinitions are the volume definitions of the replica.
+	VolumeDefinitions []client.VolumeDefinition
+	// Config is the drbd-reactor configuration of the original resource.
+	Config *reactor.PromoterConfig
+	// Snapshot is the shipped snapshot to recreate the resource from.
+	Snapshot common.Snapshot
+}
+
+func replicationFromProps(props map[string]string) *common.Replication {
+	remote, ok := props[replicationPropRemote]
+	if !ok {
+		return nil
+	}
+
+	return &common.Replication{
+		Remote: remote,
+		Cron:   props[replicationPropCron],
+	}
+}
+
+// Replication returns the replication settings of the given resource, or nil
+// if it is not replicated.
+func (l *Linstor) Replication(ctx context.Context, resource string) (*common.Replication, error) {
+	rd, err := l.ResourceDefinitions.Get(ctx, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch resource definition: %w", err)
+	}
+
+	return replicationFromProps(rd.Props), nil
+}
+
+// Replications returns the replication settings of all replicated resources,
+// indexed by resource name.
+func (l *Linstor) Replications(ctx context.Context) (map[string]common.Replication, error) {
+	rds, err := l.ResourceDefinitions.GetAll(ctx, client.RDGetAllRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource definitions: %w", err)
+	}
+
+	result := make(map[string]common.Replication)
+	for _, rd := range rds {
+		if replication := replicationFromProps(rd.Props); replication != nil {
+			result[rd.Name] = *replication
+		}
+	}
+
+	return result, nil
+}
+
+// SetReplication sets or replaces the replication settings of the given
+// resource. The remote has to be a LINSTOR remote known to this cluster.
+func (l *Linstor) SetReplication(ctx context.Context, resource string, replication common.Replication) error {
+	_, err := parseCron(replication.Cron)
+	if err != nil {
+		return common.ValidationError(err.Error())
+	}
+
+	remotes, err := l.Remote.GetAllLinstor(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list remotes: %w", err)
+	}
+
+	found := false
+	for _, remote := range remotes {
+		if remote.RemoteName == replication.Remote {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return common.ValidationError(fmt.Sprintf("no LINSTOR remote named %q", replication.Remote))
+	}
+
+	err = l.ResourceDefinitions.Modify(ctx, resource, client.GenericPropsModify{
+		OverrideProps: map[string]string{
+			replicationPropRemote: replication.Remote,
+			replicationPropCron:   replication.Cron,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set replication: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteReplication stops replicating the given resource. Snapshots that
+// were already shipped are kept on the remote cluster.
+func (l *Linstor) DeleteReplication(ctx context.Context, resource string) error {
+	err := l.ResourceDefinitions.Modify(ctx, resource, client.GenericPropsModify{
+		DeleteNamespaces: []string{strings.TrimSuffix(replicationPropPrefix, "/")},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete replication: %w", err)
+	}
+
+	return nil
+}
+
+// ShipSnapshot takes a snapshot of the given resource and ships it to its
+// replica on the remote cluster. The current drbd-reactor configuration of
+// the resource, without its secrets, is shipped along with it. If a shipment of the resource is
+// already in progress, nothing is done.
+// It returns the name of the shipped snapshot.
+func (l *Linstor) ShipSnapshot(ctx context.Context, resource, remote string) (string, error) {
+	configs, _, err := reactor.ListConfigs(ctx, l.Client)
+	if err != nil {
+		return "", err
+	}
+
+	var cfg *reactor.PromoterConfig
+	for i := range configs {
+		if _, ok := configs[i].Resources[resource]; ok {
+			cfg = &configs[i]
+			break
+		}
+	}
+	if cfg == nil {
+		return "", fmt.Errorf("no promoter config found for resource %s", resource)
+	}
+
+	// the properties end up on the remote cluster, where every LINSTOR user
+	// can read them. On promotion, the secrets are read from the secrets
+	// files of the remote nodes, unless they are given again.
+	if common.RedactSecrets(cfg) {
+		cfg.Metadata.ExternalSecrets = true
+	}
+
+	content, err := reactor.EncodeConfig(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	err = l.ResourceDefinitions.Modify(ctx, resource, client.GenericPropsModify{
+		OverrideProps: map[string]string{promoterConfigProp: string(content)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store promoter config: %w", err)
+	}
+
+	snapshot, err := l.Backup.Ship(ctx, remote, client.BackupShipRequest{
+		SrcRscName: resource,
+		DstRscName: DRResourceName(resource),
+	})
+	if isErrAlreadyExists(err) {
+		log.WithField("resource", resource).Debug("shipment already in progress")
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to ship snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+func (l *Linstor) runReplications(ctx context.Context, now time.Time) {
+	replications, err := l.Replications(ctx)
+	if err != nil {
+		log.WithError(err).Warn("failed to fetch replication settings")
+		return
+	}
+
+	for resource, replication := range replications {
+		logger := log.WithField("resource", resource).WithField("remote", replication.Remote)
+
+		cron, err := parseCron(replication.Cron)
+		if err != nil {
+			logger.WithError(err).Warn("ignoring invalid replication schedule")
+			continue
+		}
+
+		if !cron.matches(now) {
+			continue
+		}
+
+		logger.Debug("shipping snapshot")
+
+		_, err = l.ShipSnapshot(ctx, resource, replication.Remote)
+		if err != nil {
+			logger.WithError(err).Warn("failed to ship snapshot")
+		}
+	}
+}
+
+// FindDRReplica looks up the replica of the given resource that was shipped to
+// this cluster. If snapshot is empty, the latest shipped snapshot is used.
+// It returns nil if there is no replica of the resource.
+func (l *Linstor) FindDRReplica(ctx context.Context, resource, snapshot string) (*DRReplica, error) {
+	name := DRResourceName(resource)
+
+	rd, err := l.ResourceDefinitions.Get(ctx, name)
+	if errors.Is(err, client.NotFoundError) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch resource definition: %w", err)
+	}
+
+	content, ok := rd.Props[promoterConfigProp]
+	if !ok {
+		return nil, fmt.Errorf("replica %s does not contain a promoter config", name)
+	}
+
+	cfg, err := reactor.DecodeConfig([]byte(content))
+	if err != nil {
+		return nil, err
+	}
+
+	vds, err := l.ResourceDefinitions.GetVolumeDefinitions(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch volume definitions: %w", err)
+	}
+
+	var snap *common.Snapshot
+	if snapshot != "" {
+		snap, err = l.Snapshot(ctx, name, snapshot)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		snaps, err := l.Snapshots(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(snaps) == 0 {
+			return nil, fmt.Errorf("no snapshots were shipped to replica %s", name)
+		}
+		snap = &snaps[len(snaps)-1]
+	}
+
+	return &DRReplica{
+		Definition:        rd,
+		VolumeDefinitions: vds,
+		Config:            cfg,
+		Snapshot:          *snap,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package linstorcontrol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/LINBIT/golinstor/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// SetResourceGroup moves the resource definition to another resource group,
+// which is created if it does not exist yet. The resources that are already
+// placed stay where they are, but take on the properties of the new group.
+func (l *Linstor) SetResourceGroup(ctx context.Context, resource, group string) error {
+	err := l.ResourceGroups.Create(ctx, client.ResourceGroup{Name: group})
+	if err != nil && !isErrAlreadyExists(err) {
+		return fmt.Errorf("failed to create resource group: %w", err)
+	}
+
+	// golinstor only sends the properties when modifying a resource
+	// definition, so this request is made directly.
+	err = l.put(ctx, "/v1/resource-definitions/"+url.PathEscape(resource), client.ResourceDefinitionModify{
+		ResourceGroup: group,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change resource group of '%s' to '%s': %w", resource, group, err)
+	}
+
+	log.WithFields(log.Fields{"resource": resource, "resourceGroup": group}).Debug("Changed resource group")
+	return nil
+}
+
+// put sends body to the current LINSTOR controller, with the HTTP client
+// golinstor uses and authenticated the same way golinstor authenticates its
+// requests.
+func (l *Linstor) put(ctx context.Context, path string, body any) error {
+	rel, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, l.BaseURL().ResolveReference(rel).String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if username := os.Getenv(client.UsernameEnv); username != "" {
+		req.SetBasicAuth(username, os.Getenv(client.PasswordEnv))
+	}
+	if tokenFile, ok := os.LookupEnv(client.BearerTokenFileEnv); ok {
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read token from file: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	}
+
+	resp, err := l.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return client.NotFoundError
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		var rets client.ApiCallError
+		if err := json.NewDecoder(resp.Body).Decode(&rets); err != nil {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return rets
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package linstorcontrol
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetResourceGroup(t *testing.T) {
+	var modify client.ResourceDefinitionModify
+	var groups []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/resource-groups":
+			var rg client.ResourceGroup
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&rg))
+			groups = append(groups, rg.Name)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("[]"))
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/resource-definitions/example":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&modify))
+			_, _ = w.Write([]byte("[]"))
+		case r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	// the requests golinstor has no call for use the same TLS setup
+	t.Setenv(client.RootCAEnv, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})))
+	httpClient, err := httpClientFromEnv()
+	require.NoError(t, err)
+
+	base, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	cli, err := client.NewClient(client.BaseURL(base), client.HTTPClient(httpClient))
+	require.NoError(t, err)
+	l := &Linstor{Client: cli, http: httpClient}
+
+	err = l.SetResourceGroup(context.Background(), "example", "newgroup")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"newgroup"}, groups)
+	assert.Equal(t, "newgroup", modify.ResourceGroup)
+
+	err = l.SetResourceGroup(context.Background(), "missing", "newgroup")
+	assert.ErrorIs(t, err, client.NotFoundError)
+}
@@ -0,0 +1,423 @@
+package linstorcontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apiconsts "github.com/LINBIT/golinstor"
+	"github.com/LINBIT/golinstor/client"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+// ScheduledSnapshotNameFormat is used to name snapshots taken by the snapshot
+// scheduler. The name is derived from the scheduled time (in UTC), so that
+// schedulers running on multiple nodes agree on it.
+const ScheduledSnapshotNameFormat = "auto-20060102-1504"
+
+// Snapshot schedules are stored as properties on the resource definition, so
+// that they are available on every node.
+const (
+	schedulePropPrefix     = apiconsts.NamespcAuxiliary + "/linstor-gateway/snapshot-schedule/"
+	schedulePropCron       = schedulePropPrefix + "cron"
+	schedulePropKeepHourly = schedulePropPrefix + "keep-hourly"
+	schedulePropKeepDaily  = schedulePropPrefix + "keep-daily"
+	schedulePropKeepWeekly = schedulePropPrefix + "keep-weekly"
+)
+
+var cronShortcuts = map[string]string{
+	"@hourly": "0 * * * *",
+	"@daily":  "0 0 * * *",
+	"@weekly": "0 0 * * 0",
+}
+
+// cronField describes the range of valid values of a cron field.
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// cronSchedule is a parsed cron expression. Each field is a bit set of the
+// values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set if the respective field is "*". If both day
+	// fields are restricted, a time matches if either of them matches.
+	domAny, dowAny bool
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	if shortcut, ok := cronShortcuts[strings.TrimSpace(expr)]; ok {
+		expr = shortcut
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("expected %d fields in cron expression %q, got %d", len(cronFields), expr, len(fields))
+	}
+
+	var bits [len(cronFields)]uint64
+	for i, field := range fields {
+		var err error
+		bits[i], err = parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// both 0 and 7 mean sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges ("1-5") and
+// steps ("*/15", "0-30/10") into a bit set.
+func parseCronField(field string, r cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		values, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepStr, r.name)
+			}
+		}
+
+		lo, hi := r.min, r.max
+		if values != "*" {
+			loStr, hiStr, isRange := strings.Cut(values, "-")
+
+			var err error
+			lo, err = strconv.Atoi(loStr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s field", loStr, r.name)
+			}
+
+			switch {
+			case isRange:
+				hi, err = strconv.Atoi(hiStr)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s field", hiStr, r.name)
+				}
+			case !hasStep:
+				hi = lo
+			}
+
+			if lo < r.min || hi > r.max || lo > hi {
+				return 0, fmt.Errorf("%s field %q out of range %d-%d", r.name, values, r.min, r.max)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+// matches checks if the schedule fires at the minute of t.
+func (c *cronSchedule) matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := c.dom&(1<<t.Day()) != 0
+	dowMatch := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// ValidSchedule checks that the cron expression of a snapshot schedule can
+// be parsed and that it retains at least one snapshot.
+func ValidSchedule(schedule common.SnapshotSchedule) error {
+	_, err := parseCron(schedule.Cron)
+	if err != nil {
+		return common.ValidationError(err.Error())
+	}
+
+	if schedule.KeepHourly < 0 || schedule.KeepDaily < 0 || schedule.KeepWeekly < 0 {
+		return common.ValidationError("number of snapshots to keep must not be negative")
+	}
+
+	if schedule.KeepHourly+schedule.KeepDaily+schedule.KeepWeekly == 0 {
+		return common.ValidationError("schedule must keep at least one snapshot")
+	}
+
+	return nil
+}
+
+// snapshotsToPrune returns the names of the scheduled snapshots which are not
+// retained by the schedule. For each of the hourly, daily and weekly
+// policies, the latest snapshot of each of the last N periods is kept.
+// Snapshots that were not taken by the scheduler are never pruned.
+func snapshotsToPrune(snaps []common.Snapshot, schedule common.SnapshotSchedule) []string {
+	type scheduled struct {
+		name string
+		at   time.Time
+	}
+
+	var candidates []scheduled
+	for _, snap := range snaps {
+		at, err := time.Parse(ScheduledSnapshotNameFormat, snap.Name)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, scheduled{name: snap.Name, at: at.Local()})
+	}
+
+	// newest first, so that the latest snapshot of each period is kept
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].at.After(candidates[j].at)
+	})
+
+	policies := []struct {
+		keep   int
+		period func(t time.Time) string
+	}{
+		{schedule.KeepHourly, func(t time.Time) string { return t.Format("2006-01-02T15") }},
+		{schedule.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{schedule.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+	}
+
+	keep := make(map[string]bool)
+	for _, policy := range policies {
+		periods := make(map[string]bool)
+		for _, c := range candidates {
+			if len(periods) >= policy.keep {
+				break
+			}
+
+			period := policy.period(c.at)
+			if !periods[period] {
+				periods[period] = true
+				keep[c.name] = true
+			}
+		}
+	}
+
+	var prune []string
+	for _, c := range candidates {
+		if !keep[c.name] {
+			prune = append(prune, c.name)
+		}
+	}
+
+	return prune
+}
+
+// scheduleFromProps reads a snapshot schedule from resource definition
+// properties. It returns nil if no schedule is set.
+func scheduleFromProps(props map[string]string) (*common.SnapshotSchedule, error) {
+	cron, ok := props[schedulePropCron]
+	if !ok {
+		return nil, nil
+	}
+
+	schedule := &common.SnapshotSchedule{Cron: cron}
+	for key, value := range map[string]*int{
+		schedulePropKeepHourly: &schedule.KeepHourly,
+		schedulePropKeepDaily:  &schedule.KeepDaily,
+		schedulePropKeepWeekly: &schedule.KeepWeekly,
+	} {
+		raw, ok := props[key]
+		if !ok {
+			continue
+		}
+
+		var err error
+		*value, err = strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for property %s: %w", key, err)
+		}
+	}
+
+	return schedule, nil
+}
+
+// SnapshotSchedule returns the snapshot schedule of the given resource, or nil
+// if none is set.
+func (l *Linstor) SnapshotSchedule(ctx context.Context, resource string) (*common.SnapshotSchedule, error) {
+	rd, err := l.ResourceDefinitions.Get(ctx, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch resource definition: %w", err)
+	}
+
+	return scheduleFromProps(rd.Props)
+}
+
+// SnapshotSchedules returns the snapshot schedules of all resources that have
+// one, indexed by resource name.
+func (l *Linstor) SnapshotSchedules(ctx context.Context) (map[string]common.SnapshotSchedule, error) {
+	rds, err := l.ResourceDefinitions.GetAll(ctx, client.RDGetAllRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource definitions: %w", err)
+	}
+
+	result := make(map[string]common.SnapshotSchedule)
+	for _, rd := range rds {
+		schedule, err := scheduleFromProps(rd.Props)
+		if err != nil {
+			log.WithError(err).WithField("resource", rd.Name).Warn("ignoring invalid snapshot schedule")
+			continue
+		}
+
+		if schedule != nil {
+			result[rd.Name] = *schedule
+		}
+	}
+
+	return result, nil
+}
+
+// SetSnapshotSchedule sets or replaces the snapshot schedule of the given
+// resource.
+func (l *Linstor) SetSnapshotSchedule(ctx context.Context, resource string, schedule common.SnapshotSchedule) error {
+	err := ValidSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	err = l.ResourceDefinitions.Modify(ctx, resource, client.GenericPropsModify{
+		OverrideProps: map[string]string{
+			schedulePropCron:       schedule.Cron,
+			schedulePropKeepHourly: strconv.Itoa(schedule.KeepHourly),
+			schedulePropKeepDaily:  strconv.Itoa(schedule.KeepDaily),
+			schedulePropKeepWeekly: strconv.Itoa(schedule.KeepWeekly),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set snapshot schedule: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSnapshotSchedule removes the snapshot schedule of the given resource.
+// Snapshots that were already taken are kept.
+func (l *Linstor) DeleteSnapshotSchedule(ctx context.Context, resource string) error {
+	err := l.ResourceDefinitions.Modify(ctx, resource, client.GenericPropsModify{
+		DeleteNamespaces: []string{strings.TrimSuffix(schedulePropPrefix, "/")},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot schedule: %w", err)
+	}
+
+	return nil
+}
+
+// RunScheduler takes and prunes scheduled snapshots, and ships snapshots of
+// replicated resources, until ctx is cancelled. At the start of every minute,
+// the snapshot schedules and replication settings of all resources are
+// checked.
+// As snapshot names are derived from the scheduled time, schedulers running
+// on multiple nodes at once do not take duplicate snapshots. Likewise, a
+// resource is only shipped once at a time.
+func (l *Linstor) RunScheduler(ctx context.Context) {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		l.runSnapshotSchedules(ctx, next)
+		l.runReplications(ctx, next)
+	}
+}
+
+func (l *Linstor) runSnapshotSchedules(ctx context.Context, now time.Time) {
+	schedules, err := l.SnapshotSchedules(ctx)
+	if err != nil {
+		log.WithError(err).Warn("failed to fetch snapshot schedules")
+		return
+	}
+
+	for resource, schedule := range schedules {
+		logger := log.WithField("resource", resource)
+
+		cron, err := parseCron(schedule.Cron)
+		if err != nil {
+			logger.WithError(err).Warn("ignoring invalid snapshot schedule")
+			continue
+		}
+
+		if !cron.matches(now) {
+			continue
+		}
+
+		err = l.takeScheduledSnapshot(ctx, resource, schedule, now)
+		if err != nil {
+			logger.WithError(err).Warn("failed to take scheduled snapshot")
+		}
+	}
+}
+
+// takeScheduledSnapshot takes the snapshot scheduled at the given time, and
+// deletes all scheduled snapshots that are no longer retained.
+func (l *Linstor) takeScheduledSnapshot(ctx context.Context, resource string, schedule common.SnapshotSchedule, now time.Time) error {
+	name := now.UTC().Format(ScheduledSnapshotNameFormat)
+
+	log.WithField("resource", resource).WithField("snapshot", name).Debug("taking scheduled snapshot")
+
+	err := l.Resources.CreateSnapshot(ctx, client.Snapshot{
+		Name:         name,
+		ResourceName: resource,
+	})
+	if err != nil && !isErrAlreadyExists(err) {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	snaps, err := l.Snapshots(ctx, resource)
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range snapshotsToPrune(snaps, schedule) {
+		log.WithField("resource", resource).WithField("snapshot", snap).Debug("pruning scheduled snapshot")
+
+		err := l.Resources.DeleteSnapshot(ctx, resource, snap)
+		if err != nil && !errors.Is(err, client.NotFoundError) {
+			return fmt.Errorf("failed to prune snapshot %s: %w", snap, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package linstorcontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func TestCronMatches(t *testing.T) {
+	t.Parallel()
+
+	// 2024-03-04 is a monday
+	monday := time.Date(2024, 3, 4, 10, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		expr    string
+		t       time.Time
+		matches bool
+	}{
+		{"* * * * *", monday, true},
+		{"30 10 * * *", monday, true},
+		{"0 10 * * *", monday, false},
+		{"*/15 * * * *", monday, true},
+		{"*/20 * * * *", monday, false},
+		{"0-30/10 * * * *", monday, true},
+		{"30 9-17 * * 1-5", monday, true},
+		{"30 9-17 * * 0,6", monday, false},
+		{"30 10 * * 7", monday.AddDate(0, 0, 6), true},
+		{"30 10 1 * 1", monday, true},
+		{"30 10 1 * 2", monday, false},
+		{"30 10 4 3 *", monday, true},
+		{"@hourly", monday.Truncate(time.Hour), true},
+		{"@hourly", monday, false},
+		{"@daily", time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), true},
+		{"@weekly", time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tcase := range cases {
+		cron, err := parseCron(tcase.expr)
+		if assert.NoError(t, err, tcase.expr) {
+			assert.Equal(t, tcase.matches, cron.matches(tcase.t), "%s at %s", tcase.expr, tcase.t)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@yearly",
+	} {
+		_, err := parseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSnapshotsToPrune(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+	name := func(t time.Time) string {
+		return t.UTC().Format(ScheduledSnapshotNameFormat)
+	}
+
+	// one snapshot every 30 minutes for 10 days, plus a manual snapshot
+	var snaps []common.Snapshot
+	for at := start; at.Before(start.AddDate(0, 0, 10)); at = at.Add(30 * time.Minute) {
+		snaps = append(snaps, common.Snapshot{Name: name(at)})
+	}
+	snaps = append(snaps, common.Snapshot{Name: "before-upgrade"})
+	latest := start.AddDate(0, 0, 10).Add(-30 * time.Minute)
+
+	prune := snapshotsToPrune(snaps, common.SnapshotSchedule{KeepHourly: 3, KeepDaily: 2})
+
+	var kept []string
+	pruned := make(map[string]bool)
+	for _, p := range prune {
+		pruned[p] = true
+	}
+	for _, snap := range snaps {
+		if !pruned[snap.Name] {
+			kept = append(kept, snap.Name)
+		}
+	}
+
+	// the latest snapshot of the last 3 hours; the latest of the last 2 days
+	// coincides with the latest hourly one.
+	assert.ElementsMatch(t, []string{
+		name(latest.Add(-2 * time.Hour)),
+		name(latest.Add(-1 * time.Hour)),
+		name(latest),
+		name(start.AddDate(0, 0, 9).Add(-30 * time.Minute)),
+		"before-upgrade",
+	}, kept)
+}
+
+func TestValidSchedule(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidSchedule(common.SnapshotSchedule{Cron: "@hourly", KeepHourly: 24}))
+	assert.Error(t, ValidSchedule(common.SnapshotSchedule{Cron: "@hourly"}))
+	assert.Error(t, ValidSchedule(common.SnapshotSchedule{Cron: "@hourly", KeepDaily: -1, KeepWeekly: 2}))
+	assert.Error(t, ValidSchedule(common.SnapshotSchedule{Cron: "every hour", KeepDaily: 1}))
+}
+
+func TestScheduleFromProps(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := scheduleFromProps(map[string]string{"DrbdOptions/Resource/quorum": "majority"})
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+
+	schedule, err = scheduleFromProps(map[string]string{
+		schedulePropCron:       "0 */4 * * *",
+		schedulePropKeepHourly: "6",
+		schedulePropKeepWeekly: "4",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &common.SnapshotSchedule{Cron: "0 */4 * * *", KeepHourly: 6, KeepWeekly: 4}, schedule)
+
+	_, err = scheduleFromProps(map[string]string{
+		schedulePropCron:      "@daily",
+		schedulePropKeepDaily: "seven",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package linstorcontrol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/LINBIT/golinstor/client"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+// SnapshotNameFormat is used to generate a snapshot name if none was given.
+const SnapshotNameFormat = "snap-20060102-150405"
+
+// snapshotFromLinstor converts a LINSTOR snapshot to a common.Snapshot.
+// The creation time is the time the snapshot was first taken on any node.
+func snapshotFromLinstor(snap client.Snapshot) common.Snapshot {
+	result := common.Snapshot{
+		Name:  snap.Name,
+		Nodes: snap.Nodes,
+	}
+
+	for _, node := range snap.Snapshots {
+		if node.CreateTimestamp == nil {
+			continue
+		}
+		if result.CreatedAt.IsZero() || node.CreateTimestamp.Before(result.CreatedAt) {
+			result.CreatedAt = node.CreateTimestamp.Time
+		}
+	}
+
+	for _, vol := range snap.VolumeDefinitions {
+		result.Volumes = append(result.Volumes, common.VolumeConfig{
+			Number:  int(vol.VolumeNumber),
+			SizeKiB: vol.SizeKib,
+		})
+	}
+
+	sort.Slice(result.Volumes, func(i, j int) bool {
+		return result.Volumes[i].Number < result.Volumes[j].Number
+	})
+
+	return result
+}
+
+// CreateSnapshot takes a snapshot of all volumes of the given resource. If
+// name is empty, a name is generated from the current time.
+func (l *Linstor) CreateSnapshot(ctx context.Context, resource, name string) (*common.Snapshot, error) {
+	if name == "" {
+		name = time.Now().UTC().Format(SnapshotNameFormat)
+	}
+
+	err := l.Resources.CreateSnapshot(ctx, client.Snapshot{
+		Name:         name,
+		ResourceName: resource,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	snap, err := l.Resources.GetSnapshot(ctx, resource, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch created snapshot: %w", err)
+	}
+
+	result := snapshotFromLinstor(snap)
+	return &result, nil
+}
+
+// Snapshots lists all snapshots of the given resource, oldest first.
+func (l *Linstor) Snapshots(ctx context.Context, resource string) ([]common.Snapshot, error) {
+	snaps, err := l.Resources.GetSnapshots(ctx, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	result := make([]common.Snapshot, 0, len(snaps))
+	for _, snap := range snaps {
+		result = append(result, snapshotFromLinstor(snap))
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// DeleteSnapshot deletes the named snapshot of the given resource on all nodes.
+func (l *Linstor) DeleteSnapshot(ctx context.Context, resource, name string) error {
+	err := l.Resources.DeleteSnapshot(ctx, resource, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// RollbackSnapshot resets all volumes of the given resource to the state of
+// the named snapshot. The resource must not be in use on any node.
+func (l *Linstor) RollbackSnapshot(ctx context.Context, resource, name string) error {
+	err := l.Resources.RollbackSnapshot(ctx, resource, name)
+	if err != nil {
+		return fmt.Errorf("failed to roll back snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Snapshot fetches the named snapshot of the given resource.
+func (l *Linstor) Snapshot(ctx context.Context, resource, name string) (*common.Snapshot, error) {
+	snap, err := l.Resources.GetSnapshot(ctx, resource, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch snapshot: %w", err)
+	}
+
+	result := snapshotFromLinstor(snap)
+	return &result, nil
+}
+
+// restoreSnapshot restores the volume definitions and resources of a snapshot
+// into the resource definition target, which must already exist and be empty.
+// The resources are placed on the nodes the snapshot was taken on.
+func (l *Linstor) restoreSnapshot(ctx context.Context, src SnapshotSource, target string) error {
+	err := l.Resources.RestoreVolumeDefinitionSnapshot(ctx, src.Resource, src.Snapshot, client.SnapshotRestore{
+		ToResource: target,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore volume definitions from snapshot: %w", err)
+	}
+
+	err = l.Resources.RestoreSnapshot(ctx, src.Resource, src.Snapshot, client.SnapshotRestore{
+		ToResource: target,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore resources from snapshot: %w", err)
+	}
+
+	return nil
+}
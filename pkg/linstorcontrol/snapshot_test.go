@@ -0,0 +1,66 @@
+package linstorcontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func TestSnapshotFromLinstor(t *testing.T) {
+	t.Parallel()
+
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(2 * time.Second)
+
+	cases := []struct {
+		name     string
+		snapshot client.Snapshot
+		expected common.Snapshot
+	}{{
+		name: "no node data",
+		snapshot: client.Snapshot{
+			Name:  "snap1",
+			Nodes: []string{"node-a"},
+		},
+		expected: common.Snapshot{
+			Name:  "snap1",
+			Nodes: []string{"node-a"},
+		},
+	}, {
+		name: "earliest timestamp and sorted volumes",
+		snapshot: client.Snapshot{
+			Name:  "snap2",
+			Nodes: []string{"node-a", "node-b"},
+			VolumeDefinitions: []client.SnapshotVolumeDefinition{
+				{VolumeNumber: 1, SizeKib: 1024},
+				{VolumeNumber: 0, SizeKib: 65536},
+			},
+			Snapshots: []client.SnapshotNode{
+				{NodeName: "node-b", CreateTimestamp: &client.TimeStampMs{Time: second}},
+				{NodeName: "node-a", CreateTimestamp: &client.TimeStampMs{Time: first}},
+				{NodeName: "node-c"},
+			},
+		},
+		expected: common.Snapshot{
+			Name:      "snap2",
+			CreatedAt: first,
+			Nodes:     []string{"node-a", "node-b"},
+			Volumes: []common.VolumeConfig{
+				{Number: 0, SizeKiB: 65536},
+				{Number: 1, SizeKiB: 1024},
+			},
+		},
+	}}
+
+	for _, tcase := range cases {
+		tcase := tcase
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tcase.expected, snapshotFromLinstor(tcase.snapshot))
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// Package maintenance moves all gateway resources off a node and keeps them
+// away from it, so that the node can be taken down for maintenance.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	apiconsts "github.com/LINBIT/golinstor"
+	"github.com/LINBIT/golinstor/client"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/linstorcontrol"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+// DefaultResourceTimeout is the time to wait for a single resource to move
+// to another node.
+const DefaultResourceTimeout = 30 * time.Second
+
+type Maintenance struct {
+	cli *linstorcontrol.Linstor
+}
+
+func New(controllers []string) (*Maintenance, error) {
+	cli, err := linstorcontrol.Default(controllers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create linstor client: %w", err)
+	}
+	return &Maintenance{cli}, nil
+}
+
+// ResourceResult describes what happened to a single resource during an
+// evacuation or resume.
+type ResourceResult struct {
+	// Resource is the name of the LINSTOR resource.
+	Resource string `json:"resource"`
+	// Config is the id of the drbd-reactor configuration of the resource.
+	Config string `json:"config"`
+	// MigratedTo is the node the resource was moved to, if it was running
+	// on the evacuated node.
+	MigratedTo string `json:"migrated_to,omitempty"`
+	// Deactivated is set if the resource was deactivated on the node.
+	Deactivated bool `json:"deactivated,omitempty"`
+	// Activated is set if the resource was activated again on the node.
+	Activated bool   `json:"activated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the outcome of an evacuation or resume.
+type Report struct {
+	Node      string           `json:"node"`
+	Resources []ResourceResult `json:"resources"`
+}
+
+// Failed reports whether any resource could not be handled.
+func (r *Report) Failed() bool {
+	for _, res := range r.Resources {
+		if res.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeStatus describes whether a node is evacuated.
+type NodeStatus struct {
+	Node      string `json:"node"`
+	Evacuated bool   `json:"evacuated"`
+}
+
+// Nodes returns the evacuation status of all LINSTOR nodes, sorted by name.
+func (m *Maintenance) Nodes(ctx context.Context) ([]NodeStatus, error) {
+	nodes, err := m.cli.Nodes.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+
+	result := make([]NodeStatus, 0, len(nodes))
+	for _, node := range nodes {
+		result = append(result, NodeStatus{Node: node.Name, Evacuated: common.NodeEvacuated(node)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Node < result[j].Node
+	})
+
+	return result, nil
+}
+
+// resourceOnNode is a gateway resource with a replica on the node.
+type resourceOnNode struct {
+	cfg       *reactor.PromoterConfig
+	id        string
+	name      string
+	resources []client.ResourceWithVolumes
+	inUse     bool
+	inactive  bool
+}
+
+// resourcesOnNode finds all gateway resources with a replica on node.
+func (m *Maintenance) resourcesOnNode(ctx context.Context, node string) ([]resourceOnNode, error) {
+	cfgs, paths, err := reactor.ListConfigs(ctx, m.cli.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []resourceOnNode
+	for i := range cfgs {
+		name, _ := cfgs[i].FirstResource()
+		if name == "" {
+			continue
+		}
+
+		resources, err := m.cli.Resources.GetResourceView(ctx, &client.ListOpts{Resource: []string{name}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch resources of %s: %w", name, err)
+		}
+
+		idx := slices.IndexFunc(resources, func(r client.ResourceWithVolumes) bool { return r.NodeName == node })
+		if idx < 0 {
+			continue
+		}
+
+		state := resources[idx].State
+		result = append(result, resourceOnNode{
+			cfg:       &cfgs[i],
+			id:        reactor.ConfigID(paths[i]),
+			name:      name,
+			resources: resources,
+			inUse:     state != nil && state.InUse != nil && *state.InUse,
+			inactive:  slices.Contains(resources[idx].Flags, apiconsts.FlagRscInactive),
+		})
+	}
+
+	return result, nil
+}
+
+// pickTarget returns the node other than node that drbd-reactor would choose
+// for the resource, or "" if there is none. Nodes are tried in the preferred
+// order of the resource, forbidden and evacuated nodes are never used.
+func pickTarget(resources []client.ResourceWithVolumes, node string, meta reactor.PromoterMetadata, evacuated []string) string {
+	var candidates []string
+	for _, resource := range resources {
+		n := resource.NodeName
+		if n == node || slices.Contains(meta.ForbiddenNodes, n) || slices.Contains(evacuated, n) {
+			continue
+		}
+		if common.ResourceHealthyOn(resources, n) {
+			candidates = append(candidates, n)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	rank := func(n string) int {
+		if i := slices.Index(meta.PreferredNodes, n); i >= 0 {
+			return i
+		}
+		return len(meta.PreferredNodes)
+	}
+
+	sort.Strings(candidates)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return rank(candidates[i]) < rank(candidates[j])
+	})
+	return candidates[0]
+}
+
+// Evacuate moves every gateway resource that runs on node to another node and
+// deactivates the gateway resources on node, so that drbd-reactor cannot
+// promote them there until Resume is called.
+//
+// If any running resource has no healthy replica on another node that is
+// neither one of its forbidden nodes nor evacuated itself, nothing is changed
+// and a ValidationError is returned.
+// If the node does not exist, nil is returned.
+func (m *Maintenance) Evacuate(ctx context.Context, node string, resourceTimeout time.Duration) (*Report, error) {
+	if resourceTimeout == 0 {
+		resourceTimeout = DefaultResourceTimeout
+	}
+
+	_, err := m.cli.Nodes.Get(ctx, node)
+	if errors.Is(err, client.NotFoundError) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch node: %w", err)
+	}
+
+	onNode, err := m.resourcesOnNode(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	evacuated, err := common.EvacuatedNodes(ctx, m.cli.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, len(onNode))
+	var stuck []string
+	for i := range onNode {
+		if !onNode[i].inUse {
+			continue
+		}
+
+		targets[i] = pickTarget(onNode[i].resources, node, onNode[i].cfg.Metadata, evacuated)
+		if targets[i] == "" {
+			stuck = append(stuck, onNode[i].name)
+		}
+	}
+
+	if len(stuck) > 0 {
+		return nil, common.ValidationError(fmt.Sprintf("no healthy node that is neither forbidden nor evacuated to move %s to", strings.Join(stuck, ", ")))
+	}
+
+	err = m.cli.Nodes.Modify(ctx, node, client.NodeModify{
+		GenericPropsModify: client.GenericPropsModify{OverrideProps: map[string]string{common.EvacuatedProp: "true"}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark node as evacuated: %w", err)
+	}
+
+	report := &Report{Node: node}
+	for i := range onNode {
+		rsc := &onNode[i]
+		result := ResourceResult{Resource: rsc.name, Config: rsc.id}
+
+		if targets[i] != "" {
+			log.WithFields(log.Fields{"resource": rsc.name, "from": node, "to": targets[i]}).Info("migrating resource")
+
+			waitCtx, cancel := context.WithTimeout(ctx, resourceTimeout)
+			err := common.MigrateResource(waitCtx, m.cli.Client, rsc.cfg, rsc.id, targets[i])
+			cancel()
+			if err != nil {
+				result.Error = fmt.Sprintf("failed to migrate to %s: %v", targets[i], err)
+				report.Resources = append(report.Resources, result)
+				continue
+			}
+			result.MigratedTo = targets[i]
+		}
+
+		if rsc.inactive {
+			report.Resources = append(report.Resources, result)
+			continue
+		}
+
+		log.WithFields(log.Fields{"resource": rsc.name, "node": node}).Info("deactivating resource")
+
+		err := m.cli.Resources.Deactivate(ctx, rsc.name, node)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to deactivate: %v", err)
+		} else {
+			result.Deactivated = true
+		}
+		report.Resources = append(report.Resources, result)
+	}
+
+	return report, nil
+}
+
+// Resume activates the gateway resources on node again and removes the
+// evacuation mark, so that drbd-reactor may promote them there again.
+// Resources are not moved back to the node.
+// If the node does not exist, nil is returned.
+func (m *Maintenance) Resume(ctx context.Context, node string) (*Report, error) {
+	_, err := m.cli.Nodes.Get(ctx, node)
+	if errors.Is(err, client.NotFoundError) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch node: %w", err)
+	}
+
+	onNode, err := m.resourcesOnNode(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Node: node}
+	for i := range onNode {
+		rsc := &onNode[i]
+
+		if !rsc.inactive {
+			continue
+		}
+
+		log.WithFields(log.Fields{"resource": rsc.name, "node": node}).Info("activating resource")
+
+		result := ResourceResult{Resource: rsc.name, Config: rsc.id}
+		err := m.cli.Resources.Activate(ctx, rsc.name, node)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to activate: %v", err)
+		} else {
+			result.Activated = true
+		}
+		report.Resources = append(report.Resources, result)
+	}
+
+	if report.Failed() {
+		// keep the mark, so that resume can be run again
+		return report, nil
+	}
+
+	err = m.cli.Nodes.Modify(ctx, node, client.NodeModify{
+		GenericPropsModify: client.GenericPropsModify{DeleteProps: []string{common.EvacuatedProp}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove evacuation mark: %w", err)
+	}
+
+	return report, nil
+}
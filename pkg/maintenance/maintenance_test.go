@@ -0,0 +1,70 @@
+package maintenance
+
+import (
+	"testing"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+func TestPickTarget(t *testing.T) {
+	t.Parallel()
+
+	resource := func(node string, state string) client.ResourceWithVolumes {
+		return client.ResourceWithVolumes{
+			Resource: client.Resource{NodeName: node},
+			Volumes:  []client.Volume{{State: client.VolumeState{DiskState: state}}},
+		}
+	}
+
+	testcases := []struct {
+		name      string
+		resources []client.ResourceWithVolumes
+		meta      reactor.PromoterMetadata
+		evacuated []string
+		expected  string
+	}{{
+		name:      "first healthy node",
+		resources: []client.ResourceWithVolumes{resource("node3", "UpToDate"), resource("node1", "UpToDate"), resource("node2", "UpToDate")},
+		expected:  "node2",
+	}, {
+		name:      "skip unhealthy nodes",
+		resources: []client.ResourceWithVolumes{resource("node1", "UpToDate"), resource("node2", "Outdated"), resource("node3", "UpToDate")},
+		expected:  "node3",
+	}, {
+		name:      "only diskless",
+		resources: []client.ResourceWithVolumes{resource("node1", "UpToDate"), resource("node2", "Diskless")},
+		expected:  "",
+	}, {
+		name:      "preferred node",
+		resources: []client.ResourceWithVolumes{resource("node1", "UpToDate"), resource("node2", "UpToDate"), resource("node3", "UpToDate")},
+		meta:      reactor.PromoterMetadata{PreferredNodes: []string{"node1", "node3"}},
+		expected:  "node3",
+	}, {
+		name:      "skip forbidden node",
+		resources: []client.ResourceWithVolumes{resource("node1", "UpToDate"), resource("node2", "UpToDate"), resource("node3", "UpToDate")},
+		meta:      reactor.PromoterMetadata{ForbiddenNodes: []string{"node2"}},
+		expected:  "node3",
+	}, {
+		name:      "skip evacuated node",
+		resources: []client.ResourceWithVolumes{resource("node1", "UpToDate"), resource("node2", "UpToDate"), resource("node3", "UpToDate")},
+		evacuated: []string{"node2"},
+		expected:  "node3",
+	}, {
+		name:      "only forbidden nodes",
+		resources: []client.ResourceWithVolumes{resource("node1", "UpToDate"), resource("node2", "UpToDate"), resource("node3", "Outdated")},
+		meta:      reactor.PromoterMetadata{ForbiddenNodes: []string{"node2"}},
+		expected:  "",
+	}}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tcase.expected, pickTarget(tcase.resources, "node1", tcase.meta, tcase.evacuated))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ArchiveVersion is the version of the archive format written by this
+// version of LINSTOR Gateway.
+const ArchiveVersion = 1
+
+// Archive is a full export of the gateway resources in a cluster, used for
+// backups and for moving the configuration to a new LINSTOR cluster.
+//
+// The resources are stored in the same form as in a Manifest, so an archive
+// can be applied like one. Configs holds the drbd-reactor configuration files
+// as stored in LINSTOR, for reference.
+type Archive struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Manifest
+	Configs []ConfigFile `json:"configs,omitempty"`
+	// Redacted is set if secrets were left out of the archive.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// ConfigFile is a raw drbd-reactor configuration file.
+type ConfigFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// ParseArchive decodes an archive written by export.
+func ParseArchive(data []byte) (*Archive, error) {
+	a := &Archive{}
+	err := json.Unmarshal(data, a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode archive: %w", err)
+	}
+
+	if a.Version == 0 {
+		return nil, fmt.Errorf("not a LINSTOR Gateway archive: missing version")
+	}
+
+	if a.Version > ArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d, this version of LINSTOR Gateway supports up to version %d", a.Version, ArchiveVersion)
+	}
+
+	err = a.checkUnique()
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
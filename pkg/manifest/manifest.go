@@ -0,0 +1,134 @@
+// Package manifest reads and writes files that describe a set of gateway
+// resources, so that they can be kept in version control and applied to a
+// cluster.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+)
+
+// Manifest describes the desired state of all gateway resources in a
+// cluster. The resources have the same shape as in the REST API.
+type Manifest struct {
+	ISCSI  []*iscsi.ResourceConfig  `json:"iscsi,omitempty"`
+	NFS    []*nfs.ResourceConfig    `json:"nfs,omitempty"`
+	NVMeoF []*nvmeof.ResourceConfig `json:"nvme-of,omitempty"`
+}
+
+// Format is the file format of a manifest.
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// FormatFromPath guesses the format of a manifest from its file name. Files
+// ending in ".toml" are TOML, everything else is read as YAML, which includes
+// JSON.
+func FormatFromPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return FormatTOML
+	}
+	return FormatYAML
+}
+
+// Parse decodes a manifest in the given format.
+func Parse(data []byte, format Format) (*Manifest, error) {
+	var raw map[string]interface{}
+	switch format {
+	case FormatYAML:
+		err := yaml.Unmarshal(data, &raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse yaml: %w", err)
+		}
+	case FormatTOML:
+		tree, err := toml.LoadBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse toml: %w", err)
+		}
+		raw = tree.ToMap()
+	default:
+		return nil, fmt.Errorf("unknown manifest format %q", format)
+	}
+
+	// go through JSON, so that the resources are decoded exactly as in the
+	// REST API
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert manifest: %w", err)
+	}
+
+	m := &Manifest{}
+	err = json.Unmarshal(encoded, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	err = m.checkUnique()
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// StripClusterPrivateVolumes removes the cluster private volume from all
+// resources. It is added whenever a resource is created, so it must not be
+// part of the desired state.
+func (m *Manifest) StripClusterPrivateVolumes() {
+	isPrivate := func(vol common.VolumeConfig) bool { return vol.Number == 0 }
+
+	for _, rsc := range m.ISCSI {
+		rsc.Volumes = slices.DeleteFunc(rsc.Volumes, isPrivate)
+	}
+	for _, rsc := range m.NFS {
+		rsc.Volumes = slices.DeleteFunc(rsc.Volumes, func(vol nfs.VolumeConfig) bool { return isPrivate(vol.VolumeConfig) })
+	}
+	for _, rsc := range m.NVMeoF {
+		rsc.Volumes = slices.DeleteFunc(rsc.Volumes, isPrivate)
+	}
+}
+
+// checkUnique makes sure that no resource appears twice in the manifest.
+func (m *Manifest) checkUnique() error {
+	seen := make(map[string]bool)
+	check := func(kind, id string) error {
+		key := kind + "/" + id
+		if seen[key] {
+			return fmt.Errorf("%s resource %s is listed more than once", kind, id)
+		}
+		seen[key] = true
+		return nil
+	}
+
+	for _, rsc := range m.ISCSI {
+		if err := check("iscsi", rsc.IQN.String()); err != nil {
+			return err
+		}
+	}
+	for _, rsc := range m.NFS {
+		if err := check("nfs", rsc.Name); err != nil {
+			return err
+		}
+	}
+	for _, rsc := range m.NVMeoF {
+		if err := check("nvme-of", rsc.NQN.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package manifest_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+	"github.com/LINBIT/linstor-gateway/pkg/manifest"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		data        string
+		format      manifest.Format
+		expectError bool
+		check       func(t *testing.T, m *manifest.Manifest)
+	}{
+		{
+			name: "yaml",
+			data: `
+iscsi:
+  - iqn: iqn.2019-08.com.linbit:example
+    service_ips: ["192.168.127.1/24"]
+    volumes:
+      - number: 1
+        size_kib: 1048576
+nfs:
+  - name: export
+    service_ip: 192.168.127.2/24
+nvme-of:
+  - nqn: nqn.2021-08.com.linbit:nvme:example
+    service_ips: ["192.168.127.3/24"]
+`,
+			format: manifest.FormatYAML,
+			check: func(t *testing.T, m *manifest.Manifest) {
+				if assert.Len(t, m.ISCSI, 1) {
+					assert.Equal(t, "iqn.2019-08.com.linbit:example", m.ISCSI[0].IQN.String())
+					assert.Equal(t, "192.168.127.1/24", m.ISCSI[0].ServiceIPs[0].String())
+					assert.Equal(t, uint64(1048576), m.ISCSI[0].Volumes[0].SizeKiB)
+				}
+				if assert.Len(t, m.NFS, 1) {
+					assert.Equal(t, "export", m.NFS[0].Name)
+				}
+				if assert.Len(t, m.NVMeoF, 1) {
+					assert.Equal(t, "nqn.2021-08.com.linbit:nvme:example", m.NVMeoF[0].NQN.String())
+				}
+			},
+		},
+		{
+			name: "toml",
+			data: `
+[[nfs]]
+name = "export"
+service_ip = "192.168.127.2/24"
+`,
+			format: manifest.FormatTOML,
+			check: func(t *testing.T, m *manifest.Manifest) {
+				assert.Empty(t, m.ISCSI)
+				if assert.Len(t, m.NFS, 1) {
+					assert.Equal(t, "192.168.127.2/24", m.NFS[0].ServiceIP.String())
+				}
+			},
+		},
+		{
+			name: "duplicate",
+			data: `
+nfs:
+  - name: export
+  - name: export
+`,
+			format:      manifest.FormatYAML,
+			expectError: true,
+		},
+		{
+			name: "invalid iqn",
+			data: `
+iscsi:
+  - iqn: not-an-iqn
+`,
+			format:      manifest.FormatYAML,
+			expectError: true,
+		},
+	}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := manifest.Parse([]byte(tcase.data), tcase.format)
+			if tcase.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			if assert.NoError(t, err) {
+				tcase.check(t, m)
+			}
+		})
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, manifest.FormatTOML, manifest.FormatFromPath("targets.toml"))
+	assert.Equal(t, manifest.FormatYAML, manifest.FormatFromPath("targets.yaml"))
+	assert.Equal(t, manifest.FormatYAML, manifest.FormatFromPath("targets.json"))
+}
+
+func TestParseArchive(t *testing.T) {
+	t.Parallel()
+
+	a, err := manifest.ParseArchive([]byte(`{"version": 1, "nfs": [{"name": "export"}], "configs": [{"path": "/etc/drbd-reactor.d/linstor-gateway-nfs-export.toml", "content": "[[promoter]]"}]}`))
+	if assert.NoError(t, err) {
+		assert.Len(t, a.NFS, 1)
+		assert.Len(t, a.Configs, 1)
+	}
+
+	_, err = manifest.ParseArchive([]byte(`{"nfs": [{"name": "export"}]}`))
+	assert.Error(t, err)
+
+	_, err = manifest.ParseArchive([]byte(`{"version": 99}`))
+	assert.Error(t, err)
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	iqn, err := iscsi.NewIqn("iqn.2019-08.com.linbit:example")
+	require.NoError(t, err)
+	nqn, err := nvmeof.NewNqn("linbit:nvme:example")
+	require.NoError(t, err)
+	ip, err := common.ServiceIPFromString("192.168.127.1/24")
+	require.NoError(t, err)
+
+	// the resources as the server lists them, including the cluster private
+	// volume
+	exported := &manifest.Archive{
+		Version: manifest.ArchiveVersion,
+		Manifest: manifest.Manifest{
+			ISCSI: []*iscsi.ResourceConfig{{
+				IQN:           iqn,
+				ServiceIPs:    []common.IpCidr{ip},
+				ResourceGroup: "rg",
+				Volumes:       []common.VolumeConfig{common.ClusterPrivateVolume(), {Number: 1, SizeKiB: 1024}},
+			}},
+			NFS: []*nfs.ResourceConfig{{
+				Name:          "export",
+				ServiceIP:     ip,
+				ResourceGroup: "rg",
+				Volumes: []nfs.VolumeConfig{
+					{VolumeConfig: common.ClusterPrivateVolume()},
+					{VolumeConfig: common.VolumeConfig{Number: 1, SizeKiB: 1024}, ExportPath: "/"},
+				},
+			}},
+			NVMeoF: []*nvmeof.ResourceConfig{{
+				NQN:           nqn,
+				ServiceIPs:    []common.IpCidr{ip},
+				ResourceGroup: "rg",
+				Volumes:       []common.VolumeConfig{common.ClusterPrivateVolume(), {Number: 1, SizeKiB: 1024}},
+			}},
+		},
+	}
+	exported.StripClusterPrivateVolumes()
+
+	data, err := json.Marshal(exported)
+	require.NoError(t, err)
+
+	imported, err := manifest.ParseArchive(data)
+	require.NoError(t, err)
+
+	// importing creates the resources, which adds the cluster private volume
+	// again
+	require.Len(t, imported.ISCSI, 1)
+	target := imported.ISCSI[0]
+	assert.Equal(t, []common.VolumeConfig{{Number: 1, SizeKiB: 1024}}, target.Volumes)
+	target.Volumes = append([]common.VolumeConfig{common.ClusterPrivateVolume()}, target.Volumes...)
+	target.FillDefaults()
+	assert.NoError(t, target.Valid())
+
+	require.Len(t, imported.NFS, 1)
+	export := imported.NFS[0]
+	require.Len(t, export.Volumes, 1)
+	assert.Equal(t, 1, export.Volumes[0].Number)
+	export.Volumes = append([]nfs.VolumeConfig{{VolumeConfig: common.ClusterPrivateVolume()}}, export.Volumes...)
+	export.FillDefaults()
+	assert.NoError(t, export.Valid())
+
+	require.Len(t, imported.NVMeoF, 1)
+	subsys := imported.NVMeoF[0]
+	assert.Equal(t, []common.VolumeConfig{{Number: 1, SizeKiB: 1024}}, subsys.Volumes)
+	subsys.Volumes = append([]common.VolumeConfig{common.ClusterPrivateVolume()}, subsys.Volumes...)
+	subsys.FillDefaults()
+	assert.NoError(t, subsys.Valid())
+}
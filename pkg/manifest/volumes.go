@@ -0,0 +1,68 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+// VolumeChange is a change to a user volume of an existing resource. Updates
+// cannot change volumes, so these are made through the calls that add and
+// resize volumes instead.
+type VolumeChange struct {
+	// Volume is the volume as listed in the manifest.
+	Volume common.VolumeConfig
+	// CurrentSizeKiB is the size of the existing volume, or 0 if the
+	// volume has to be added.
+	CurrentSizeKiB uint64
+}
+
+// VolumeChanges returns the changes needed to make the user volumes of an
+// existing resource match desired, ordered by volume number. Volumes can only
+// be added or grown; an error describing the first other difference is
+// returned if there is one. If desired lists no volumes at all, the volumes
+// are left alone.
+func VolumeChanges(current, desired []common.VolumeConfig) ([]VolumeChange, error) {
+	if len(desired) == 0 {
+		return nil, nil
+	}
+
+	sizes := make(map[int]uint64)
+	for _, vol := range current {
+		if vol.Number != 0 {
+			sizes[vol.Number] = vol.SizeKiB
+		}
+	}
+
+	listed := make(map[int]bool)
+	var changes []VolumeChange
+	for _, vol := range desired {
+		if vol.Number == 0 {
+			continue
+		}
+		listed[vol.Number] = true
+
+		size, ok := sizes[vol.Number]
+		switch {
+		case !ok:
+			changes = append(changes, VolumeChange{Volume: vol})
+		case vol.SizeKiB < size:
+			return nil, fmt.Errorf("volume %d cannot shrink from %d KiB to %d KiB", vol.Number, size, vol.SizeKiB)
+		case vol.SizeKiB > size:
+			changes = append(changes, VolumeChange{Volume: vol, CurrentSizeKiB: size})
+		}
+	}
+
+	for nr := range sizes {
+		if !listed[nr] {
+			return nil, fmt.Errorf("volume %d is not listed, but volumes are never deleted by apply", nr)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Volume.Number < changes[j].Volume.Number
+	})
+
+	return changes, nil
+}
@@ -0,0 +1,67 @@
+package manifest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/manifest"
+)
+
+func TestVolumeChanges(t *testing.T) {
+	t.Parallel()
+
+	current := []common.VolumeConfig{
+		{Number: 0, SizeKiB: 65536},
+		{Number: 1, SizeKiB: 1048576},
+		{Number: 2, SizeKiB: 1048576},
+	}
+
+	m, err := manifest.Parse([]byte(`
+iscsi:
+  - iqn: iqn.2019-08.com.linbit:example
+    service_ips: ["192.168.127.1/24"]
+    volumes:
+      - number: 1
+        size_kib: 2097152
+      - number: 2
+        size_kib: 1048576
+      - number: 3
+        size_kib: 4096
+`), manifest.FormatYAML)
+	require.NoError(t, err)
+
+	changes, err := manifest.VolumeChanges(current, m.ISCSI[0].Volumes)
+	assert.NoError(t, err)
+	assert.Equal(t, []manifest.VolumeChange{
+		{Volume: common.VolumeConfig{Number: 1, SizeKiB: 2097152}, CurrentSizeKiB: 1048576},
+		{Volume: common.VolumeConfig{Number: 3, SizeKiB: 4096}},
+	}, changes)
+
+	testcases := []struct {
+		name    string
+		desired []common.VolumeConfig
+	}{{
+		name:    "shrink",
+		desired: []common.VolumeConfig{{Number: 1, SizeKiB: 4096}, {Number: 2, SizeKiB: 1048576}},
+	}, {
+		name:    "delete",
+		desired: []common.VolumeConfig{{Number: 1, SizeKiB: 1048576}},
+	}}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := manifest.VolumeChanges(current, tcase.desired)
+			assert.Error(t, err)
+		})
+	}
+
+	changes, err = manifest.VolumeChanges(current, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, changes, "no volumes listed")
+}
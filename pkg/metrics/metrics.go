@@ -0,0 +1,83 @@
+// Package metrics holds the Prometheus metrics of the LINSTOR Gateway server.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "linstor_gateway"
+
+// Registry holds all metrics served on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts the requests to the REST API by route and
+	// status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of REST API requests, by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	// HTTPDuration measures how long the REST API takes to answer.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to answer REST API requests, by route and method.",
+		// starting resources can take a while
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"route", "method"})
+
+	// LinstorErrors counts failed requests to the LINSTOR controller.
+	LinstorErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "linstor_api_errors_total",
+		Help:      "Number of failed requests to the LINSTOR controller, by status code or \"connection\" if no response was received.",
+	}, []string{"code"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		LinstorErrors,
+	)
+}
+
+// Transport counts the failed requests made through next in LinstorErrors.
+// A nil next means http.DefaultTransport.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &linstorTransport{next: next}
+}
+
+type linstorTransport struct {
+	next http.RoundTripper
+}
+
+func (t *linstorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		// cancelled requests are not a problem of LINSTOR
+		if req.Context().Err() == nil {
+			LinstorErrors.WithLabelValues("connection").Inc()
+		}
+		return resp, err
+	}
+
+	// 404 is how LINSTOR reports that something does not exist, which is
+	// expected in many places.
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+		LinstorErrors.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	}
+
+	return resp, nil
+}
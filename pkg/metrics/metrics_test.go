@@ -0,0 +1,51 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/metrics"
+)
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: metrics.Transport(nil)}
+	get := func(path string) {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	before500 := testutil.ToFloat64(metrics.LinstorErrors.WithLabelValues("500"))
+	before404 := testutil.ToFloat64(metrics.LinstorErrors.WithLabelValues("404"))
+	beforeConn := testutil.ToFloat64(metrics.LinstorErrors.WithLabelValues("connection"))
+
+	get("/ok")
+	get("/missing")
+	get("/broken")
+	get("/broken")
+
+	server.Close()
+	_, err := client.Get(server.URL)
+	assert.Error(t, err)
+
+	assert.Equal(t, before500+2, testutil.ToFloat64(metrics.LinstorErrors.WithLabelValues("500")))
+	assert.Equal(t, before404, testutil.ToFloat64(metrics.LinstorErrors.WithLabelValues("404")), "not found is not an error")
+	assert.Equal(t, beforeConn+1, testutil.ToFloat64(metrics.LinstorErrors.WithLabelValues("connection")))
+}
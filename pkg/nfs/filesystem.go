@@ -0,0 +1,85 @@
+package nfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+// makeFilesystem creates a new filesystem of type fsType on device, and hands
+// its root directory to owner. Any data on the device is lost.
+func makeFilesystem(ctx context.Context, fsType, device string, owner common.UserGroup) error {
+	var force string
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		force = "-F"
+	case "xfs":
+		force = "-f"
+	default:
+		return fmt.Errorf("creating filesystem of type %q is not supported", fsType)
+	}
+
+	err := run(ctx, "mkfs", "-t", fsType, "-q", force, device)
+	if err != nil {
+		return err
+	}
+
+	if owner.User == "" && owner.Group == "" {
+		return nil
+	}
+
+	mountPoint, err := os.MkdirTemp("", "linstor-gateway-mkfs-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary mount point: %w", err)
+	}
+	defer os.Remove(mountPoint)
+
+	err = run(ctx, "mount", "-t", fsType, device, mountPoint)
+	if err != nil {
+		return err
+	}
+
+	chownErr := run(ctx, "chown", owner.User+":"+owner.Group, mountPoint)
+
+	err = run(ctx, "umount", mountPoint)
+	if err != nil {
+		return err
+	}
+
+	return chownErr
+}
+
+// growFilesystem grows the filesystem mounted at mountPoint (backed by
+// device) to fill the whole device. The filesystem has to be mounted on
+// this node.
+func growFilesystem(ctx context.Context, fsType, device, mountPoint string) error {
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		return run(ctx, "resize2fs", device)
+	case "xfs":
+		return run(ctx, "xfs_growfs", mountPoint)
+	default:
+		return fmt.Errorf("growing filesystem of type %q is not supported", fsType)
+	}
+}
+
+// run executes the given command, and returns an error including the output
+// of the command if it fails.
+func run(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+
+	log.WithField("cmd", cmd.String()).Debug("running command")
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
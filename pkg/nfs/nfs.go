@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"time"
 
+	apiconsts "github.com/LINBIT/golinstor"
 	"github.com/LINBIT/golinstor/client"
 	log "github.com/sirupsen/logrus"
 
@@ -136,6 +137,81 @@ func (n *NFS) checkExistingConfigs(ctx context.Context, newRsc *ResourceConfig)
 // described in rsc. It automatically prepends a "cluster private volume" to the
 // list of volumes, so volume numbers must start at 1.
 func (n *NFS) Create(ctx context.Context, rsc *ResourceConfig) (*ResourceConfig, error) {
+	return n.create(ctx, rsc, nil)
+}
+
+// Clone creates a new NFS export from a snapshot of the export src. rsc
+// describes the new export: its name and service IP must be set, all other
+// settings are copied from src unless given. The volumes of the new export
+// are restored from the snapshot and keep the export paths they have in src,
+// so any volumes in rsc are ignored. The filesystems of the clone keep the
+// UUIDs of src, which is why XFS volumes are mounted with "nouuid".
+// If snapshot is empty, a new snapshot of src is taken first. Such a snapshot
+// is not deleted afterwards, as some storage backends need it for as long as
+// the clone exists.
+func (n *NFS) Clone(ctx context.Context, src string, snapshot string, rsc *ResourceConfig) (*ResourceConfig, error) {
+	if rsc.Name == src {
+		return nil, common.ValidationError("clone must have a different name than its source")
+	}
+
+	srcCfg, err := n.Get(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	if srcCfg == nil {
+		return nil, nil
+	}
+
+	var snap *common.Snapshot
+	if snapshot == "" {
+		snap, err = n.CreateSnapshot(ctx, src, "")
+	} else {
+		snap, err = n.cli.Snapshot(ctx, src, snapshot)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rsc.AllowedIPs == nil {
+		rsc.AllowedIPs = srcCfg.AllowedIPs
+	}
+	if rsc.ResourceGroup == "" {
+		rsc.ResourceGroup = srcCfg.ResourceGroup
+	}
+	if rsc.Implementation == "" {
+		rsc.Implementation = srcCfg.Implementation
+	}
+
+	rsc.Volumes = snapshotVolumes(snap, srcCfg.Volumes)
+
+	return n.create(ctx, rsc, &linstorcontrol.SnapshotSource{Resource: src, Snapshot: snap.Name})
+}
+
+// snapshotVolumes returns the volume configuration of an export restored from
+// snap. Export paths and file systems are taken from the matching volumes in
+// orig.
+func snapshotVolumes(snap *common.Snapshot, orig []VolumeConfig) []VolumeConfig {
+	var result []VolumeConfig
+	for _, vol := range snap.UserVolumes() {
+		volCfg := VolumeConfig{VolumeConfig: vol, ExportPath: fmt.Sprintf("/vol%d", vol.Number)}
+		for _, origVol := range orig {
+			if origVol.Number == vol.Number {
+				volCfg.ExportPath = origVol.ExportPath
+				volCfg.FileSystem = origVol.FileSystem
+				volCfg.FileSystemRootOwner = origVol.FileSystemRootOwner
+			}
+		}
+
+		result = append(result, volCfg)
+	}
+
+	return result
+}
+
+// create creates an NFS export as described in rsc. If from is not nil, the
+// volumes are restored from that snapshot.
+func (n *NFS) create(ctx context.Context, rsc *ResourceConfig, from *linstorcontrol.SnapshotSource) (*ResourceConfig, error) {
 	rsc.FillDefaults()
 
 	// prepend cluster private volume; it should always be the first volume and have number 0
@@ -170,6 +246,7 @@ func (n *NFS) Create(ctx context.Context, rsc *ResourceConfig) (*ResourceConfig,
 		ResourceGroup: rsc.ResourceGroup,
 		Volumes:       volumes,
 		GrossSize:     rsc.GrossSize,
+		FromSnapshot:  from,
 	}, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create linstor resource: %w", err)
@@ -285,6 +362,36 @@ func (n *NFS) Stop(ctx context.Context, name string, resourceTimeout time.Durati
 	return n.Get(ctx, name)
 }
 
+// Migrate moves a running export to the given node, for example to free the
+// current node for maintenance. It returns once the export is running on the
+// new node again.
+// If the export does not exist, nil is returned.
+func (n *NFS) Migrate(ctx context.Context, name string, node string, resourceTimeout time.Duration) (*ResourceConfig, error) {
+	if resourceTimeout == 0 {
+		resourceTimeout = DefaultResourceTimeout
+	}
+
+	id := fmt.Sprintf(IDFormat, name)
+	cfg, _, err := reactor.FindConfig(ctx, n.cli.Client, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the resource configuration: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, resourceTimeout)
+	defer cancel()
+
+	err = common.MigrateResource(waitCtx, n.cli.Client, cfg, id, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate export: %w", err)
+	}
+
+	return n.Get(ctx, name)
+}
+
 func (n *NFS) List(ctx context.Context) ([]*ResourceConfig, error) {
 	cfgs, paths, err := reactor.ListConfigs(ctx, n.cli.Client)
 	if err != nil {
@@ -349,6 +456,228 @@ func (n *NFS) Delete(ctx context.Context, name string, resourceTimeout time.Dura
 	return nil
 }
 
+// AddVolume adds a new volume to an existing NFS resource and exports it.
+// LINSTOR does not create a filesystem on volumes that are added to an already
+// deployed resource, so the filesystem is created here instead.
+//
+// Creating the filesystem requires the new volume to be accessible, so the
+// resource must be started, and this must run on the node that is currently
+// primary. Otherwise, an error wrapping common.ErrNotActiveHere is returned.
+func (n *NFS) AddVolume(ctx context.Context, name string, volCfg *VolumeConfig) (*ResourceConfig, error) {
+	cfg, path, err := reactor.FindConfig(ctx, n.cli.Client, fmt.Sprintf(IDFormat, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, resourceGroup, volumeDefinitions, resources, err := cfg.DeployedResources(ctx, n.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	deployedCfg, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+
+	for i := range deployedCfg.Volumes {
+		if deployedCfg.Volumes[i].Number == volCfg.Number {
+			if deployedCfg.Volumes[i].SizeKiB != volCfg.SizeKiB {
+				return nil, errors.New(fmt.Sprintf("existing volume has differing size %d != %d", deployedCfg.Volumes[i].SizeKiB, volCfg.SizeKiB))
+			}
+
+			deployedCfg.Status = linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+			return deployedCfg, nil
+		}
+	}
+
+	status := linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+	if status.Service != common.ServiceStateStarted {
+		return nil, fmt.Errorf("cannot add volume while service is stopped; the filesystem can only be created while the resource is active: %w", common.ErrNotActiveHere)
+	}
+
+	if !common.IsLocalNode(status.Primary) {
+		return nil, fmt.Errorf("the resource is active on node %s; volumes can only be added by the LINSTOR Gateway server running on that node: %w", status.Primary, common.ErrNotActiveHere)
+	}
+
+	volCfg.ExportPath = rootedPath(volCfg.ExportPath)
+	deployedCfg.Volumes = append(deployedCfg.Volumes, *volCfg)
+
+	err = deployedCfg.Valid()
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	// Leave the filesystem properties off the new volume definition for now, so
+	// that LINSTOR does not try to handle the filesystem itself.
+	volumes := make([]common.VolumeConfig, len(deployedCfg.Volumes))
+	for i := range deployedCfg.Volumes {
+		volumes[i] = deployedCfg.Volumes[i].VolumeConfig
+		if volumes[i].Number == volCfg.Number {
+			volumes[i].FileSystem = ""
+		}
+	}
+
+	resourceDefinition, resourceGroup, resources, err = n.cli.EnsureResource(ctx, linstorcontrol.Resource{
+		Name:          deployedCfg.Name,
+		ResourceGroup: deployedCfg.ResourceGroup,
+		Volumes:       volumes,
+		GrossSize:     deployedCfg.GrossSize,
+	}, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile linstor resource: %w", err)
+	}
+
+	defer func() {
+		// if we fail beyond this point, roll back by deleting the created volume definition
+		if err != nil {
+			log.Debugf("Rollback: deleting just created volume definition %d of %s", volCfg.Number, name)
+			err := n.cli.ResourceDefinitions.DeleteVolumeDefinition(ctx, name, volCfg.Number)
+			if err != nil {
+				log.Warnf("Failed to roll back created volume definition: %v", err)
+			}
+		}
+	}()
+
+	var device string
+	for _, rsc := range resources {
+		if rsc.NodeName != status.Primary {
+			continue
+		}
+		for _, v := range rsc.Volumes {
+			if int(v.VolumeNumber) == volCfg.Number {
+				device = common.DevicePath(v)
+			}
+		}
+	}
+	if device == "" {
+		err = fmt.Errorf("volume %d is not deployed on node %s", volCfg.Number, status.Primary)
+		return nil, err
+	}
+
+	err = makeFilesystem(ctx, volCfg.FileSystem, device, volCfg.FileSystemRootOwner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filesystem: %w", err)
+	}
+
+	err = n.cli.ResourceDefinitions.ModifyVolumeDefinition(ctx, name, volCfg.Number, client.VolumeDefinitionModify{
+		GenericPropsModify: client.GenericPropsModify{
+			OverrideProps: map[string]string{
+				apiconsts.NamespcFilesystem + "/" + apiconsts.KeyFsType:  volCfg.FileSystem,
+				apiconsts.NamespcFilesystem + "/" + apiconsts.KeyFsUser:  volCfg.FileSystemRootOwner.User,
+				apiconsts.NamespcFilesystem + "/" + apiconsts.KeyFsGroup: volCfg.FileSystemRootOwner.Group,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set filesystem properties: %w", err)
+	}
+
+	cfg, err = deployedCfg.ToPromoter(resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert resource to promoter configuration: %w", err)
+	}
+
+	err = reactor.EnsureConfig(ctx, n.cli.Client, cfg, deployedCfg.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update config: %w", err)
+	}
+
+	deployedCfg.Status = linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+
+	return deployedCfg, nil
+}
+
+// ResizeVolume grows the volume with the given number to newSizeKiB, then
+// grows the filesystem on it to match. The export stays available while it
+// is resized.
+//
+// The filesystem can only be grown while it is mounted, so the resource must
+// be started, and this must run on the node that is currently primary.
+// Otherwise, an error wrapping common.ErrNotActiveHere is returned.
+func (n *NFS) ResizeVolume(ctx context.Context, name string, volNr int, newSizeKiB uint64) (*ResourceConfig, error) {
+	cfg, path, err := reactor.FindConfig(ctx, n.cli.Client, fmt.Sprintf(IDFormat, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, resourceGroup, volumeDefinitions, resources, err := cfg.DeployedResources(ctx, n.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	deployedCfg, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+
+	if volNr == 0 {
+		return nil, common.ValidationError("cannot resize volume 0; it is the reserved cluster-private/system volume")
+	}
+
+	var vol *VolumeConfig
+	for i := range deployedCfg.Volumes {
+		if deployedCfg.Volumes[i].Number == volNr {
+			vol = &deployedCfg.Volumes[i]
+			break
+		}
+	}
+	if vol == nil {
+		return nil, fmt.Errorf("volume %d on resource %q: %w", volNr, name, common.ErrVolumeNotFound)
+	}
+
+	if newSizeKiB < vol.SizeKiB {
+		return nil, common.ValidationError(fmt.Sprintf("cannot shrink volume from %d KiB to %d KiB", vol.SizeKiB, newSizeKiB))
+	}
+
+	status := linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+	if status.Service != common.ServiceStateStarted {
+		return nil, fmt.Errorf("cannot resize volume while service is stopped; the filesystem can only be grown while it is mounted: %w", common.ErrNotActiveHere)
+	}
+
+	if !common.IsLocalNode(status.Primary) {
+		return nil, fmt.Errorf("the filesystem is mounted on node %s; it can only be grown by the LINSTOR Gateway server running on that node: %w", status.Primary, common.ErrNotActiveHere)
+	}
+
+	var device string
+	for _, rsc := range resources {
+		if rsc.NodeName != status.Primary {
+			continue
+		}
+		for _, v := range rsc.Volumes {
+			if int(v.VolumeNumber) == volNr {
+				device = common.DevicePath(v)
+			}
+		}
+	}
+	if device == "" {
+		return nil, fmt.Errorf("volume %d is not deployed on node %s", volNr, status.Primary)
+	}
+
+	if newSizeKiB > vol.SizeKiB {
+		err = n.cli.ResourceDefinitions.ModifyVolumeDefinition(ctx, name, volNr, client.VolumeDefinitionModify{
+			SizeKib: newSizeKiB,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resize volume definition: %w", err)
+		}
+	}
+
+	err = growFilesystem(ctx, vol.FileSystem, device, ExportPath(deployedCfg, vol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to grow filesystem: %w", err)
+	}
+
+	return n.Get(ctx, name)
+}
+
 func (n *NFS) DeleteVolume(ctx context.Context, name string, lun int) (*ResourceConfig, error) {
 	cfg, path, err := reactor.FindConfig(ctx, n.cli.Client, fmt.Sprintf(IDFormat, name))
 	if err != nil {
@@ -403,3 +732,231 @@ func (n *NFS) DeleteVolume(ctx context.Context, name string, lun int) (*Resource
 
 	return n.Get(ctx, name)
 }
+
+// Patch applies the changes described in patch to an existing export. The
+// promoter config is rewritten, so the export does not have to be deleted and
+// recreated. drbd-reactor restarts the services of a promoter whose config
+// changed, so clients see a short interruption on the node the export is
+// active on. An empty list of allowed IPs allows all clients.
+// If the export does not exist, nil is returned.
+func (n *NFS) Patch(ctx context.Context, name string, patch *ResourcePatch) (*ResourceConfig, error) {
+	return n.modify(ctx, name, func(rsc *ResourceConfig) error {
+		if patch.AllowedIPs != nil {
+			rsc.AllowedIPs = *patch.AllowedIPs
+		}
+		rsc.FillDefaults()
+		return nil
+	})
+}
+
+// modify applies fn to the configuration of an existing export and updates
+// the promoter config accordingly. It returns nil if the export does not
+// exist.
+func (n *NFS) modify(ctx context.Context, name string, fn func(rsc *ResourceConfig) error) (*ResourceConfig, error) {
+	cfg, path, err := reactor.FindConfig(ctx, n.cli.Client, fmt.Sprintf(IDFormat, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, resourceGroup, volumeDefinitions, resources, err := cfg.DeployedResources(ctx, n.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	deployedCfg, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+
+	err = fn(deployedCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = deployedCfg.Valid()
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	cfg, err = deployedCfg.ToPromoter(resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert resource to promoter configuration: %w", err)
+	}
+
+	err = reactor.EnsureConfig(ctx, n.cli.Client, cfg, deployedCfg.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update config: %w", err)
+	}
+
+	deployedCfg.Status = linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+
+	return deployedCfg, nil
+}
+
+// CreateSnapshot takes a snapshot of all volumes of the export. If snapshot
+// is empty, a name is generated from the current time.
+func (n *NFS) CreateSnapshot(ctx context.Context, name string, snapshot string) (*common.Snapshot, error) {
+	return n.cli.CreateSnapshot(ctx, name, snapshot)
+}
+
+// ListSnapshots lists all snapshots of the export, oldest first.
+func (n *NFS) ListSnapshots(ctx context.Context, name string) ([]common.Snapshot, error) {
+	return n.cli.Snapshots(ctx, name)
+}
+
+// DeleteSnapshot deletes the named snapshot of the export.
+func (n *NFS) DeleteSnapshot(ctx context.Context, name string, snapshot string) error {
+	return n.cli.DeleteSnapshot(ctx, name, snapshot)
+}
+
+// RollbackSnapshot resets the export to the state of the named snapshot.
+// The export is stopped for the rollback. If it was running before, it is
+// started again afterwards.
+func (n *NFS) RollbackSnapshot(ctx context.Context, name string, snapshot string, resourceTimeout time.Duration) (*ResourceConfig, error) {
+	cfg, err := n.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	// make sure the snapshot exists before the export is stopped
+	_, err = n.cli.Snapshot(ctx, name, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	wasRunning := cfg.Status.Service == common.ServiceStateStarted
+
+	cfg, err = n.Stop(ctx, name, resourceTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop export: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	rollbackErr := n.cli.RollbackSnapshot(ctx, name, snapshot)
+	if !wasRunning {
+		if rollbackErr != nil {
+			return nil, rollbackErr
+		}
+		return n.Get(ctx, name)
+	}
+
+	// start again even if the rollback failed, so that the export is not left stopped
+	cfg, err = n.Start(ctx, name, resourceTimeout)
+	if rollbackErr != nil {
+		return nil, rollbackErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start export: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// SnapshotSchedule returns the snapshot schedule of the export, or nil if none
+// is set.
+func (n *NFS) SnapshotSchedule(ctx context.Context, name string) (*common.SnapshotSchedule, error) {
+	return n.cli.SnapshotSchedule(ctx, name)
+}
+
+// SetSnapshotSchedule sets or replaces the snapshot schedule of the export.
+func (n *NFS) SetSnapshotSchedule(ctx context.Context, name string, schedule common.SnapshotSchedule) error {
+	return n.cli.SetSnapshotSchedule(ctx, name, schedule)
+}
+
+// DeleteSnapshotSchedule removes the snapshot schedule of the export.
+func (n *NFS) DeleteSnapshotSchedule(ctx context.Context, name string) error {
+	return n.cli.DeleteSnapshotSchedule(ctx, name)
+}
+
+// Replication returns the replication settings of the export, or nil if it
+// is not replicated.
+func (n *NFS) Replication(ctx context.Context, name string) (*common.Replication, error) {
+	return n.cli.Replication(ctx, name)
+}
+
+// SetReplication sets or replaces the replication settings of the export.
+func (n *NFS) SetReplication(ctx context.Context, name string, replication common.Replication) error {
+	return n.cli.SetReplication(ctx, name, replication)
+}
+
+// DeleteReplication stops replicating the export.
+func (n *NFS) DeleteReplication(ctx context.Context, name string) error {
+	return n.cli.DeleteReplication(ctx, name)
+}
+
+// ShipSnapshot immediately ships a snapshot of the export to the remote it
+// is replicated to. It returns the name of the shipped snapshot.
+func (n *NFS) ShipSnapshot(ctx context.Context, name string) (string, error) {
+	replication, err := n.cli.Replication(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	if replication == nil {
+		return "", common.ValidationError("export is not replicated")
+	}
+
+	return n.cli.ShipSnapshot(ctx, name, replication.Remote)
+}
+
+// PromoteDR brings up an export that was replicated to this cluster from
+// another one. If snapshot is empty, the latest shipped snapshot is used.
+// The export keeps its name, volumes, export paths and allowed IPs. As the
+// remote site usually uses a different network, the service IP and resource
+// group can be overridden via rsc. Replication settings are not carried over
+// to the promoted export.
+// If no replica of the export exists, nil is returned.
+func (n *NFS) PromoteDR(ctx context.Context, name string, snapshot string, rsc *ResourceConfig) (*ResourceConfig, error) {
+	replica, err := n.cli.FindDRReplica(ctx, name, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if replica == nil {
+		return nil, nil
+	}
+
+	replicated, err := FromPromoter(replica.Config, &replica.Definition, replica.VolumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse replicated configuration: %w", err)
+	}
+
+	if replicated.Name != name {
+		return nil, fmt.Errorf("replica contains configuration for %s instead of %s", replicated.Name, name)
+	}
+
+	if rsc.ServiceIP.IP() != nil {
+		replicated.ServiceIP = rsc.ServiceIP
+	}
+	if rsc.ResourceGroup != "" {
+		replicated.ResourceGroup = rsc.ResourceGroup
+	}
+	replicated.ResourceTimeout = rsc.ResourceTimeout
+	replicated.Volumes = snapshotVolumes(&replica.Snapshot, replicated.Volumes)
+
+	result, err := n.create(ctx, replicated, &linstorcontrol.SnapshotSource{
+		Resource: replica.Definition.Name,
+		Snapshot: replica.Snapshot.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = n.cli.DeleteReplication(ctx, name)
+	if err != nil {
+		log.WithError(err).Warn("failed to remove replication settings from promoted export")
+	}
+
+	return result, nil
+}
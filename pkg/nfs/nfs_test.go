@@ -0,0 +1,135 @@
+package nfs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/icza/gog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/linstorcontrol"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+func TestAddVolumeRollback(t *testing.T) {
+	t.Parallel()
+
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	rsc := &ResourceConfig{
+		Name:          "example",
+		ServiceIP:     common.ServiceIPFromParts(net.IP{192, 168, 127, 1}, 24),
+		ResourceGroup: "rg1",
+		Volumes: []VolumeConfig{
+			{VolumeConfig: common.ClusterPrivateVolume()},
+			{VolumeConfig: common.VolumeConfig{Number: 1, SizeKiB: 1024, FileSystem: "ext4"}, ExportPath: "/"},
+		},
+		Implementation: ImplementationKernel,
+	}
+
+	// the export is active on this node, but LINSTOR fails to deploy the new
+	// volume, so that it cannot be formatted
+	resources := []client.ResourceWithVolumes{{
+		Resource: client.Resource{
+			Name:     rsc.Name,
+			NodeName: hostname,
+			State:    &client.ResourceState{InUse: gog.Ptr(true)},
+		},
+		Volumes: []client.Volume{
+			{VolumeNumber: 0, DevicePath: "/dev/drbd1000", State: client.VolumeState{DiskState: "UpToDate"}},
+			{VolumeNumber: 1, DevicePath: "/dev/drbd1001", State: client.VolumeState{DiskState: "UpToDate"}},
+		},
+	}}
+	definitions := []client.VolumeDefinition{
+		{VolumeNumber: gog.Ptr(int32(0)), SizeKib: 64 * 1024, Props: filesystemProps(rsc.Volumes[0])},
+		{VolumeNumber: gog.Ptr(int32(1)), SizeKib: 1024, Props: filesystemProps(rsc.Volumes[1])},
+	}
+
+	promoter, err := rsc.ToPromoter(resources)
+	require.NoError(t, err)
+	content, err := reactor.EncodeConfig(promoter)
+	require.NoError(t, err)
+	path := reactor.ConfigPath(rsc.ID())
+
+	var mu sync.Mutex
+	var created []client.VolumeDefinitionCreate
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var reply interface{}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/files":
+			reply = []map[string]string{{"path": path, "content": base64.StdEncoding.EncodeToString(content)}}
+		case "GET /v1/resource-definitions/example":
+			reply = client.ResourceDefinition{
+				Name:              rsc.Name,
+				ResourceGroupName: rsc.ResourceGroup,
+				Props:             map[string]string{"files" + path: "True"},
+			}
+		case "GET /v1/resource-groups/rg1":
+			reply = client.ResourceGroup{Name: rsc.ResourceGroup}
+		case "GET /v1/resource-definitions/example/volume-definitions":
+			reply = definitions
+		case "GET /v1/view/resources":
+			reply = resources
+		case "GET /v1/nodes":
+			reply = []client.Node{{Name: hostname}}
+		case "POST /v1/resource-definitions/example/volume-definitions":
+			var vd client.VolumeDefinitionCreate
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&vd))
+			created = append(created, vd)
+			reply = []client.ApiCallRc{}
+		case "POST /v1/resource-groups", "POST /v1/resource-definitions", "POST /v1/resource-definitions/example/autoplace":
+			reply = []client.ApiCallRc{}
+		case "DELETE /v1/resource-definitions/example/volume-definitions/2", "DELETE /v1/resource-definitions/example":
+			deleted = append(deleted, r.URL.Path)
+			reply = []client.ApiCallRc{}
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(reply))
+	}))
+	t.Cleanup(server.Close)
+
+	base, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	cli, err := client.NewClient(client.BaseURL(base))
+	require.NoError(t, err)
+	n := &NFS{cli: &linstorcontrol.Linstor{Client: cli}}
+
+	cfg, err := n.AddVolume(context.Background(), rsc.Name, &VolumeConfig{
+		VolumeConfig: common.VolumeConfig{Number: 2, SizeKiB: 2048, FileSystem: "ext4"},
+		ExportPath:   "/projects",
+	})
+	assert.ErrorContains(t, err, "volume 2 is not deployed")
+	assert.Nil(t, cfg)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var createdNumbers []int32
+	for _, vd := range created {
+		createdNumbers = append(createdNumbers, *vd.VolumeDefinition.VolumeNumber)
+		if *vd.VolumeDefinition.VolumeNumber == 2 {
+			assert.Empty(t, vd.VolumeDefinition.Props, "LINSTOR must not handle the filesystem of the new volume")
+		}
+	}
+	assert.Contains(t, createdNumbers, int32(2))
+	assert.Equal(t, []string{"/v1/resource-definitions/example/volume-definitions/2"}, deleted, "only the new volume definition is rolled back")
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -73,6 +74,19 @@ type ResourceConfig struct {
 	// Implementation selects which NFS server runs the export. Either
 	// ImplementationKernel (the default) or ImplementationGanesha.
 	Implementation string `json:"implementation,omitempty"`
+	// PreferredNodes are tried first, in order, when drbd-reactor picks a
+	// node to start the export on. The export is never migrated or evacuated to
+	// ForbiddenNodes, but drbd-reactor cannot exclude nodes entirely: on a
+	// failover, they are used if no other node can run the export.
+	// Use "linstor-gateway node evacuate" to keep a node free completely.
+	PreferredNodes []string `json:"preferred_nodes,omitempty"`
+	ForbiddenNodes []string `json:"forbidden_nodes,omitempty"`
+}
+
+// ResourcePatch describes a change to an existing export. Fields that are nil
+// are left unchanged.
+type ResourcePatch struct {
+	AllowedIPs *[]common.IpCidr `json:"allowed_ips,omitempty"`
 }
 
 const (
@@ -81,7 +95,10 @@ const (
 )
 
 func FromPromoter(cfg *reactor.PromoterConfig, definition *client.ResourceDefinition, volumeDefinition []client.VolumeDefinition) (*ResourceConfig, error) {
-	r := &ResourceConfig{}
+	r := &ResourceConfig{
+		PreferredNodes: cfg.Metadata.PreferredNodes,
+		ForbiddenNodes: cfg.Metadata.ForbiddenNodes,
+	}
 
 	var rscCfg *reactor.PromoterResourceConfig
 	r.Name, rscCfg = cfg.FirstResource()
@@ -405,6 +422,11 @@ func (r *ResourceConfig) Valid() error {
 		return common.ValidationError("nfs export paths must be unique")
 	}
 
+	err := common.ValidNodePreferences(r.PreferredNodes, r.ForbiddenNodes)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -421,6 +443,10 @@ func (r *ResourceConfig) Matches(o *ResourceConfig) bool {
 		return false
 	}
 
+	if !slices.Equal(r.PreferredNodes, o.PreferredNodes) || !slices.Equal(r.ForbiddenNodes, o.ForbiddenNodes) {
+		return false
+	}
+
 	if r.Implementation != o.Implementation {
 		return false
 	}
@@ -494,16 +520,23 @@ func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*r
 
 		dirPath := ExportPath(r, &resVol)
 
+		attributes := map[string]string{
+			"device":    common.DevicePath(vol),
+			"directory": dirPath,
+			"fstype":    resVol.FileSystem,
+			"run_fsck":  "no",
+		}
+		if resVol.FileSystem == "xfs" {
+			// A clone restored from a snapshot has the same filesystem UUID
+			// as its source, and XFS refuses to mount both on the same node.
+			attributes["options"] = "nouuid"
+		}
+
 		agents = append(agents,
 			&reactor.ResourceAgent{
-				Type: "ocf:heartbeat:Filesystem",
-				Name: fmt.Sprintf(fsAgentName, vol.VolumeNumber),
-				Attributes: map[string]string{
-					"device":    common.DevicePath(vol),
-					"directory": dirPath,
-					"fstype":    resVol.FileSystem,
-					"run_fsck":  "no",
-				},
+				Type:       "ocf:heartbeat:Filesystem",
+				Name:       fmt.Sprintf(fsAgentName, vol.VolumeNumber),
+				Attributes: attributes,
 			},
 		)
 	}
@@ -598,10 +631,13 @@ func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*r
 				StopServicesOnExit:  true,
 				OnDrbdDemoteFailure: "reboot-immediate",
 				TargetAs:            "BindsTo",
+				PreferredNodes:      common.PromoterNodeOrder(r.PreferredNodes, r.ForbiddenNodes, deployment),
 			},
 		},
 		Metadata: reactor.PromoterMetadata{
 			LinstorGatewaySchemaVersion: CurrentVersion,
+			PreferredNodes:              r.PreferredNodes,
+			ForbiddenNodes:              r.ForbiddenNodes,
 		},
 	}, nil
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/icza/gog"
 	"github.com/pelletier/go-toml"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/LINBIT/linstor-gateway/pkg/common"
 	"github.com/LINBIT/linstor-gateway/pkg/reactor"
@@ -377,3 +378,130 @@ func TestParseRootOwner(t *testing.T) {
 		})
 	}
 }
+
+func TestXFSCloneSameNode(t *testing.T) {
+	t.Parallel()
+	newExport := func(name string) *ResourceConfig {
+		return &ResourceConfig{
+			Name:          name,
+			ServiceIP:     common.ServiceIPFromParts(net.IP{192, 168, 127, 1}, 24),
+			AllowedIPs:    []common.IpCidr{common.ServiceIPFromParts(net.IP{192, 168, 127, 0}, 24)},
+			ResourceGroup: "rg1",
+			Volumes: []VolumeConfig{
+				{VolumeConfig: common.ClusterPrivateVolume()},
+				{VolumeConfig: common.VolumeConfig{Number: 1, SizeKiB: 1024, FileSystem: "xfs"}, ExportPath: "/"},
+			},
+			Implementation: ImplementationKernel,
+		}
+	}
+
+	// the clone is restored from a snapshot of the source, so both are
+	// deployed on the same nodes and their filesystems share one UUID
+	for _, rsc := range []*ResourceConfig{newExport("source"), newExport("clone")} {
+		encoded, err := rsc.ToPromoter([]client.ResourceWithVolumes{
+			{Volumes: []client.Volume{
+				{VolumeNumber: 0, DevicePath: "/dev/drbd1000", Props: filesystemProps(rsc.Volumes[0])},
+				{VolumeNumber: 1, DevicePath: "/dev/drbd1001", Props: filesystemProps(rsc.Volumes[1])},
+			}},
+		})
+		assert.NoError(t, err)
+
+		_, rscCfg := encoded.FirstResource()
+		var found bool
+		for _, entry := range rscCfg.Start {
+			agent, ok := entry.(*reactor.ResourceAgent)
+			if !ok || agent.Type != "ocf:heartbeat:Filesystem" || agent.Attributes["device"] != "/dev/drbd1001" {
+				continue
+			}
+			found = true
+			assert.Equal(t, "nouuid", agent.Attributes["options"], "%s must be mountable next to a filesystem with the same UUID", rsc.Name)
+		}
+		assert.True(t, found, "no Filesystem agent for volume 1 of %s", rsc.Name)
+	}
+}
+
+func TestToPromoterAddedVolume(t *testing.T) {
+	t.Parallel()
+
+	testcases := []string{ImplementationKernel, ImplementationGanesha}
+
+	for i := range testcases {
+		implementation := testcases[i]
+		t.Run(implementation, func(t *testing.T) {
+			t.Parallel()
+
+			rsc := &ResourceConfig{
+				Name:      "example",
+				ServiceIP: common.ServiceIPFromParts(net.IP{192, 168, 127, 1}, 24),
+				AllowedIPs: []common.IpCidr{
+					common.ServiceIPFromParts(net.IP{192, 168, 127, 0}, 24),
+				},
+				ResourceGroup: "rg1",
+				Volumes: []VolumeConfig{
+					{VolumeConfig: common.ClusterPrivateVolume()},
+					{VolumeConfig: common.VolumeConfig{Number: 1, SizeKiB: 1024, FileSystem: "ext4"}, ExportPath: "/"},
+				},
+				Implementation: implementation,
+			}
+
+			// as added by AddVolume
+			rsc.Volumes = append(rsc.Volumes, VolumeConfig{
+				VolumeConfig: common.VolumeConfig{Number: 2, SizeKiB: 2048, FileSystem: "xfs"},
+				ExportPath:   rootedPath("projects"),
+			})
+
+			encoded, err := rsc.ToPromoter([]client.ResourceWithVolumes{
+				{Volumes: []client.Volume{
+					{VolumeNumber: 0, DevicePath: "/dev/drbd1000", Props: filesystemProps(rsc.Volumes[0])},
+					{VolumeNumber: 1, DevicePath: "/dev/drbd1001", Props: filesystemProps(rsc.Volumes[1])},
+					{VolumeNumber: 2, DevicePath: "/dev/drbd1002", Props: filesystemProps(rsc.Volumes[2])},
+				}},
+			})
+			require.NoError(t, err)
+
+			directory := "/srv/gateway-exports/example/projects"
+			var mount, export *reactor.ResourceAgent
+			_, rscCfg := encoded.FirstResource()
+			for _, entry := range rscCfg.Start {
+				agent, ok := entry.(*reactor.ResourceAgent)
+				if !ok {
+					continue
+				}
+				switch {
+				case agent.Type == "ocf:heartbeat:Filesystem" && agent.Name == "fs_2":
+					mount = agent
+				case agent.Type == "ocf:heartbeat:exportfs" && agent.Name == "export_2_0",
+					agent.Type == "ocf:heartbeat:ganesha-nfs":
+					export = agent
+				}
+			}
+
+			require.NotNil(t, mount, "no Filesystem agent for the added volume")
+			assert.Equal(t, "/dev/drbd1002", mount.Attributes["device"])
+			assert.Equal(t, directory, mount.Attributes["directory"])
+			assert.Equal(t, "xfs", mount.Attributes["fstype"])
+
+			require.NotNil(t, export, "the added volume is not exported")
+			switch implementation {
+			case ImplementationKernel:
+				assert.Equal(t, directory, export.Attributes["directory"])
+				assert.Equal(t, "192.168.127.0/24", export.Attributes["clientspec"])
+			case ImplementationGanesha:
+				assert.Equal(t, "/srv/gateway-exports/example;"+directory, export.Attributes["export_path"])
+				assert.Equal(t, "1;2", export.Attributes["export_id"])
+			}
+
+			decoded, err := FromPromoter(
+				encoded,
+				&client.ResourceDefinition{ResourceGroupName: "rg1"},
+				[]client.VolumeDefinition{
+					{VolumeNumber: gog.Ptr(int32(0)), SizeKib: 64 * 1024, Props: filesystemProps(rsc.Volumes[0])},
+					{VolumeNumber: gog.Ptr(int32(1)), SizeKib: 1024, Props: filesystemProps(rsc.Volumes[1])},
+					{VolumeNumber: gog.Ptr(int32(2)), SizeKib: 2048, Props: filesystemProps(rsc.Volumes[2])},
+				},
+			)
+			require.NoError(t, err)
+			assert.Equal(t, rsc.Volumes, decoded.Volumes)
+		})
+	}
+}
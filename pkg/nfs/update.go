@@ -0,0 +1,191 @@
+package nfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/linstorcontrol"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+// UpdateResult is the outcome of an Update.
+type UpdateResult struct {
+	// Config is the configuration of the export after the update.
+	Config *ResourceConfig `json:"config"`
+	// Diff shows the changes to the drbd-reactor configuration. It is
+	// empty if nothing changes.
+	Diff string `json:"diff"`
+	// Restart is set if the export has to be stopped and started again to
+	// apply the change.
+	Restart bool `json:"restart"`
+	// ResourceGroup is the LINSTOR resource group the export is moved to. It
+	// is empty if the resource group does not change.
+	ResourceGroup string `json:"resource_group,omitempty"`
+}
+
+// Update changes an existing export to match desired, without deleting and
+// recreating it. The export is identified by the name of desired.
+//
+// Changes to the service IP or the implementation require the export to be
+// stopped and started again, which is done automatically if it is running.
+// Everything else is only written to the promoter config; drbd-reactor then
+// restarts the services of the export on the node it is active on.
+// A new resource group is set on the LINSTOR resource definition; the
+// resources that are already placed are not moved.
+// If dryRun is set, the changes are only computed, but not applied.
+// If the export does not exist, nil is returned.
+func (n *NFS) Update(ctx context.Context, desired *ResourceConfig, dryRun bool) (*UpdateResult, error) {
+	cfg, path, err := reactor.FindConfig(ctx, n.cli.Client, desired.ID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, resourceGroup, volumeDefinitions, resources, err := cfg.DeployedResources(ctx, n.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	current, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+	current.Status = linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+
+	next, err := mergeUpdate(current, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	err = next.Valid()
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	if needsRestart(current, next) {
+		// the checks for new exports cover a new service IP or implementation
+		_, _, err = n.checkExistingConfigs(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	newCfg, err := next.ToPromoter(resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert resource to promoter configuration: %w", err)
+	}
+
+	diff, err := reactor.Diff(cfg, newCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UpdateResult{
+		Config:  next,
+		Diff:    diff,
+		Restart: diff != "" && current.Status.Service == common.ServiceStateStarted && needsRestart(current, next),
+	}
+
+	if next.ResourceGroup != current.ResourceGroup {
+		result.ResourceGroup = next.ResourceGroup
+	}
+
+	if dryRun || (diff == "" && result.ResourceGroup == "") {
+		return result, nil
+	}
+
+	if result.ResourceGroup != "" {
+		err = n.cli.SetResourceGroup(ctx, next.Name, next.ResourceGroup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Restart {
+		_, err = n.Stop(ctx, next.Name, next.ResourceTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stop export: %w", err)
+		}
+	}
+
+	if diff != "" {
+		err = reactor.EnsureConfig(ctx, n.cli.Client, newCfg, next.ID())
+		if err != nil {
+			return nil, fmt.Errorf("failed to update config: %w", err)
+		}
+	}
+
+	if result.Restart {
+		_, err = n.Start(ctx, next.Name, next.ResourceTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start export: %w", err)
+		}
+	}
+
+	result.Config, err = n.Get(ctx, next.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// mergeUpdate returns the configuration that results from applying desired to
+// current.
+func mergeUpdate(current, desired *ResourceConfig) (*ResourceConfig, error) {
+	next := *desired
+	next.Status = common.ResourceStatus{}
+
+	if next.ResourceGroup == "" {
+		next.ResourceGroup = current.ResourceGroup
+	}
+
+	if len(next.Volumes) != 0 && !sameVolumes(next.Volumes, current.Volumes) {
+		return nil, common.ValidationError("volumes cannot be changed by an update, add or resize them instead")
+	}
+	next.Volumes = current.Volumes
+	next.GrossSize = current.GrossSize
+
+	next.FillDefaults()
+
+	return &next, nil
+}
+
+// sameVolumes reports whether a and b describe the same user volumes with the
+// same export paths.
+func sameVolumes(a, b []VolumeConfig) bool {
+	toCommon := func(volumes []VolumeConfig) []common.VolumeConfig {
+		result := make([]common.VolumeConfig, len(volumes))
+		for i := range volumes {
+			result[i] = volumes[i].VolumeConfig
+		}
+		return result
+	}
+
+	if !common.SameUserVolumes(toCommon(a), toCommon(b)) {
+		return false
+	}
+
+	paths := make(map[int]string)
+	for _, vol := range b {
+		paths[vol.Number] = rootedPath(vol.ExportPath)
+	}
+
+	for _, vol := range a {
+		if vol.Number != 0 && paths[vol.Number] != rootedPath(vol.ExportPath) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// needsRestart reports whether changing a running export from current to
+// next requires it to be stopped and started again.
+func needsRestart(current, next *ResourceConfig) bool {
+	return current.Implementation != next.Implementation || current.ServiceIP.String() != next.ServiceIP.String()
+}
@@ -0,0 +1,24 @@
+package nvmeof
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// nvmetSubsystemsDir is where the nvmet configfs keeps the subsystems.
+var nvmetSubsystemsDir = "/sys/kernel/config/nvmet/subsystems"
+
+// revalidateNamespace makes nvmet re-read the size of the device backing the
+// namespace nsid of the subsystem nqn, and notify connected hosts about the
+// change. It has to run on the node where the subsystem is active.
+func revalidateNamespace(nqn Nqn, nsid int) error {
+	path := filepath.Join(nvmetSubsystemsDir, nqn.String(), "namespaces", strconv.Itoa(nsid), "revalidate_size")
+	err := os.WriteFile(path, []byte("1"), 0)
+	if err != nil {
+		return fmt.Errorf("failed to revalidate size of namespace %d: %w", nsid, err)
+	}
+
+	return nil
+}
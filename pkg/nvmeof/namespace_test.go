@@ -0,0 +1,30 @@
+package nvmeof
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevalidateNamespace(t *testing.T) {
+	nvmetSubsystemsDir = t.TempDir()
+
+	nqn, err := NewNqn("linbit:nvme:example")
+	require.NoError(t, err)
+
+	nsDir := filepath.Join(nvmetSubsystemsDir, nqn.String(), "namespaces", "1")
+	require.NoError(t, os.MkdirAll(nsDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(nsDir, "revalidate_size"), nil, 0644))
+
+	err = revalidateNamespace(nqn, 1)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(nsDir, "revalidate_size"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(content))
+
+	err = revalidateNamespace(nqn, 2)
+	assert.Error(t, err)
+}
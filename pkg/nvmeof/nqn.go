@@ -65,3 +65,42 @@ type malformedNqn string
 func (m malformedNqn) Error() string {
 	return fmt.Sprintf("NQN '%s' malformed, expected <vendor>:nvme:<subsystem>", string(m))
 }
+
+// HostNqn is the NQN of an NVMe host. Unlike the subsystem Nqn, it does not
+// follow the <vendor>:nvme:<subsystem> convention, e.g. the default host NQN
+// is nqn.2014-08.org.nvmexpress:uuid:<uuid>.
+type HostNqn string
+
+// maxNqnLength is the maximum length of an NQN according to the NVMe
+// specification.
+const maxNqnLength = 223
+
+func NewHostNqn(s string) (HostNqn, error) {
+	var n HostNqn
+	err := n.UnmarshalText([]byte(s))
+	if err != nil {
+		return "", err
+	}
+
+	return n, nil
+}
+
+func (n *HostNqn) UnmarshalText(text []byte) error {
+	s := string(text)
+	if !strings.HasPrefix(s, "nqn.") || len(s) > maxNqnLength || strings.ContainsAny(s, " \t\n") {
+		return malformedHostNqn(s)
+	}
+
+	*n = HostNqn(s)
+	return nil
+}
+
+func (n HostNqn) String() string {
+	return string(n)
+}
+
+type malformedHostNqn string
+
+func (m malformedHostNqn) Error() string {
+	return fmt.Sprintf("host NQN '%s' malformed, expected nqn.<date>.<reverse domain>:<name> of at most %d characters", string(m), maxNqnLength)
+}
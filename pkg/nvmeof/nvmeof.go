@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/google/go-cmp/cmp"
 	"path/filepath"
+	"slices"
 	"sort"
 	"time"
 
@@ -92,6 +93,65 @@ func (n *NVMeoF) getExistingDeployment(ctx context.Context, rsc *ResourceConfig,
 // described in rsc. It automatically prepends a "cluster private volume" to the
 // list of volumes, so volume numbers must start at 1.
 func (n *NVMeoF) Create(ctx context.Context, rsc *ResourceConfig) (*ResourceConfig, error) {
+	return n.create(ctx, rsc, nil)
+}
+
+// Clone creates a new NVMe-oF target from a snapshot of the target src. rsc
+// describes the new target: its NQN and service IP must be set, the resource
+// group is copied from src unless given. The volumes of the new target are
+// restored from the snapshot, so any volumes in rsc are ignored.
+// If snapshot is empty, a new snapshot of src is taken first. Such a snapshot
+// is not deleted afterwards, as some storage backends need it for as long as
+// the clone exists.
+func (n *NVMeoF) Clone(ctx context.Context, src Nqn, snapshot string, rsc *ResourceConfig) (*ResourceConfig, error) {
+	if rsc.NQN.Subsystem() == src.Subsystem() {
+		return nil, common.ValidationError("clone must have a different name than its source")
+	}
+
+	srcCfg, err := n.Get(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	if srcCfg == nil {
+		return nil, nil
+	}
+
+	var snap *common.Snapshot
+	if snapshot == "" {
+		snap, err = n.CreateSnapshot(ctx, src, "")
+	} else {
+		snap, err = n.cli.Snapshot(ctx, src.Subsystem(), snapshot)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rsc.ResourceGroup == "" {
+		rsc.ResourceGroup = srcCfg.ResourceGroup
+	}
+	if rsc.Transport == "" {
+		rsc.Transport = srcCfg.Transport
+	}
+	if rsc.Port == 0 {
+		rsc.Port = srcCfg.Port
+	}
+	if rsc.AllowedHosts == nil {
+		rsc.AllowedHosts = srcCfg.AllowedHosts
+		if rsc.HostKeys == nil {
+			rsc.HostKeys = srcCfg.HostKeys
+			rsc.InlineSecrets = srcCfg.InlineSecrets
+		}
+	}
+
+	rsc.Volumes = snap.UserVolumes()
+
+	return n.create(ctx, rsc, &linstorcontrol.SnapshotSource{Resource: src.Subsystem(), Snapshot: snap.Name})
+}
+
+// create creates an NVMe-oF target as described in rsc. If from is not nil,
+// the volumes are restored from that snapshot.
+func (n *NVMeoF) create(ctx context.Context, rsc *ResourceConfig, from *linstorcontrol.SnapshotSource) (*ResourceConfig, error) {
 	rsc.FillDefaults()
 
 	// prepend cluster private volume; it should always be the first volume and have number 0
@@ -114,8 +174,10 @@ func (n *NVMeoF) Create(ctx context.Context, rsc *ResourceConfig) (*ResourceConf
 		p := paths[i]
 		name, _ := c.FirstResource()
 
-		if err := common.CheckIPCollision(c, rsc.ServiceIP.IP()); err != nil {
-			return nil, fmt.Errorf("invalid configuration: %w", err)
+		for _, ip := range rsc.ServiceIPs {
+			if err := common.CheckIPCollision(c, ip.IP()); err != nil {
+				return nil, fmt.Errorf("invalid configuration: %w", err)
+			}
 		}
 
 		// while looking for ip collisions, filter out any existing config with
@@ -141,6 +203,7 @@ func (n *NVMeoF) Create(ctx context.Context, rsc *ResourceConfig) (*ResourceConf
 		ResourceGroup: rsc.ResourceGroup,
 		Volumes:       rsc.Volumes,
 		GrossSize:     rsc.GrossSize,
+		FromSnapshot:  from,
 	}, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create linstor resource: %w", err)
@@ -255,6 +318,36 @@ func (n *NVMeoF) Stop(ctx context.Context, nqn Nqn, resourceTimeout time.Duratio
 	return n.Get(ctx, nqn)
 }
 
+// Migrate moves a running target to the given node, for example to free the
+// current node for maintenance. It returns once the target is running on the
+// new node again.
+// If the target does not exist, nil is returned.
+func (n *NVMeoF) Migrate(ctx context.Context, nqn Nqn, node string, resourceTimeout time.Duration) (*ResourceConfig, error) {
+	if resourceTimeout == 0 {
+		resourceTimeout = DefaultResourceTimeout
+	}
+
+	id := fmt.Sprintf(IDFormat, nqn.Subsystem())
+	cfg, _, err := reactor.FindConfig(ctx, n.cli.Client, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the resource configuration: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, resourceTimeout)
+	defer cancel()
+
+	err = common.MigrateResource(waitCtx, n.cli.Client, cfg, id, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate target: %w", err)
+	}
+
+	return n.Get(ctx, nqn)
+}
+
 func (n *NVMeoF) List(ctx context.Context) ([]*ResourceConfig, error) {
 	cfgs, paths, err := reactor.ListConfigs(ctx, n.cli.Client)
 	if err != nil {
@@ -388,6 +481,171 @@ func (n *NVMeoF) AddVolume(ctx context.Context, nqn Nqn, volCfg *common.VolumeCo
 	return deployedCfg, nil
 }
 
+// ResizeVolume grows the namespace nsid of the target to newSizeKiB. The
+// LINSTOR volume definition is resized and DRBD grows the device online, so
+// this works while the target is running. nvmet does not notice the new size
+// of the device by itself, so the namespace is revalidated afterwards, which
+// also notifies connected hosts.
+//
+// Revalidating the namespace requires access to the nvmet configfs, so while
+// the target is running, this must run on the node where it is active.
+// Otherwise, an error wrapping common.ErrNotActiveHere is returned.
+func (n *NVMeoF) ResizeVolume(ctx context.Context, nqn Nqn, nsid int, newSizeKiB uint64) (*ResourceConfig, error) {
+	cfg, path, err := reactor.FindConfig(ctx, n.cli.Client, fmt.Sprintf(IDFormat, nqn.Subsystem()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, resourceGroup, volumeDefinitions, resources, err := cfg.DeployedResources(ctx, n.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	deployedCfg, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+
+	vol, err := common.ValidResize(deployedCfg.Volumes, nsid, newSizeKiB)
+	if err != nil {
+		return nil, fmt.Errorf("target %q: %w", nqn, err)
+	}
+
+	if newSizeKiB == vol.SizeKiB {
+		return n.Get(ctx, nqn)
+	}
+
+	status := linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+	running := status.Service == common.ServiceStateStarted
+	if running && !common.IsLocalNode(status.Primary) {
+		return nil, fmt.Errorf("the target is active on node %s; volumes can only be resized by the LINSTOR Gateway server running on that node: %w", status.Primary, common.ErrNotActiveHere)
+	}
+
+	err = n.cli.ResourceDefinitions.ModifyVolumeDefinition(ctx, nqn.Subsystem(), nsid, client.VolumeDefinitionModify{
+		SizeKib: newSizeKiB,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resize volume definition: %w", err)
+	}
+
+	if running {
+		err = revalidateNamespace(nqn, nsid)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return n.Get(ctx, nqn)
+}
+
+// SetAllowedHosts replaces the list of hosts that may connect to the target.
+// An empty list allows any host to connect. Keys of hosts that are no longer
+// allowed are removed.
+func (n *NVMeoF) SetAllowedHosts(ctx context.Context, nqn Nqn, hosts []HostNqn) (*ResourceConfig, error) {
+	return n.modify(ctx, nqn, func(rsc *ResourceConfig) error {
+		rsc.AllowedHosts = hosts
+		for host := range rsc.HostKeys {
+			if !slices.Contains(hosts, host) {
+				delete(rsc.HostKeys, host)
+			}
+		}
+		return nil
+	})
+}
+
+// SetHostKey sets the DH-HMAC-CHAP secrets host has to use to connect to the
+// target, replacing any previous secrets of that host. The host has to be
+// one of the allowed hosts of the target, and the target has to use inline
+// secrets.
+func (n *NVMeoF) SetHostKey(ctx context.Context, nqn Nqn, host HostNqn, key HostKey) (*ResourceConfig, error) {
+	return n.modify(ctx, nqn, func(rsc *ResourceConfig) error {
+		if !rsc.InlineSecrets {
+			return errExternalKeys(rsc, host)
+		}
+		if rsc.HostKeys == nil {
+			rsc.HostKeys = make(map[HostNqn]HostKey)
+		}
+		rsc.HostKeys[host] = key
+		return nil
+	})
+}
+
+// DeleteHostKey removes the DH-HMAC-CHAP secrets of host, so that it can
+// connect without authentication again.
+func (n *NVMeoF) DeleteHostKey(ctx context.Context, nqn Nqn, host HostNqn) (*ResourceConfig, error) {
+	return n.modify(ctx, nqn, func(rsc *ResourceConfig) error {
+		if !rsc.InlineSecrets {
+			return errExternalKeys(rsc, host)
+		}
+		delete(rsc.HostKeys, host)
+		return nil
+	})
+}
+
+// errExternalKeys tells how to change the key of host on a target that reads
+// its keys from a file on each node, which cannot be written from here.
+func errExternalKeys(rsc *ResourceConfig, host HostNqn) error {
+	i := slices.Index(rsc.AllowedHosts, host)
+	if i < 0 {
+		return common.ValidationError(fmt.Sprintf("host %s is not an allowed host of the target", host))
+	}
+
+	return common.ValidationError(fmt.Sprintf("the keys of target %s are read from %s on each node: change entry %d of OCF_RESKEY_dhchap_keys and OCF_RESKEY_dhchap_ctrl_keys there and restart the target, or recreate the target with inline secrets",
+		rsc.NQN, rsc.SecretsPath(), i+1))
+}
+
+// modify applies fn to the configuration of an existing target and updates
+// the promoter config accordingly. It returns nil if the target does not
+// exist.
+func (n *NVMeoF) modify(ctx context.Context, nqn Nqn, fn func(rsc *ResourceConfig) error) (*ResourceConfig, error) {
+	cfg, path, err := reactor.FindConfig(ctx, n.cli.Client, fmt.Sprintf(IDFormat, nqn.Subsystem()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing config: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	resourceDefinition, resourceGroup, volumeDefinitions, resources, err := cfg.DeployedResources(ctx, n.cli.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing deployment: %w", err)
+	}
+
+	deployedCfg, err := FromPromoter(cfg, resourceDefinition, volumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("unknown existing reactor config: %w", err)
+	}
+
+	err = fn(deployedCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = deployedCfg.Valid()
+	if err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	cfg, err = deployedCfg.ToPromoter(resources)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert resource to promoter configuration: %w", err)
+	}
+
+	err = reactor.EnsureConfig(ctx, n.cli.Client, cfg, fmt.Sprintf(IDFormat, nqn.Subsystem()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update config: %w", err)
+	}
+
+	deployedCfg.Status = linstorcontrol.StatusFromResources(path, resourceDefinition, resourceGroup, resources)
+
+	return deployedCfg, nil
+}
+
 func (n *NVMeoF) DeleteVolume(ctx context.Context, nqn Nqn, nsid int) (*ResourceConfig, error) {
 	cfg, path, err := reactor.FindConfig(ctx, n.cli.Client, fmt.Sprintf(IDFormat, nqn.Subsystem()))
 	if err != nil {
@@ -466,3 +724,174 @@ func (n *NVMeoF) DeleteVolume(ctx context.Context, nqn Nqn, nsid int) (*Resource
 
 	return n.Get(ctx, nqn)
 }
+
+// CreateSnapshot takes a snapshot of all volumes of the target. If name is
+// empty, a name is generated from the current time.
+func (n *NVMeoF) CreateSnapshot(ctx context.Context, nqn Nqn, name string) (*common.Snapshot, error) {
+	return n.cli.CreateSnapshot(ctx, nqn.Subsystem(), name)
+}
+
+// ListSnapshots lists all snapshots of the target, oldest first.
+func (n *NVMeoF) ListSnapshots(ctx context.Context, nqn Nqn) ([]common.Snapshot, error) {
+	return n.cli.Snapshots(ctx, nqn.Subsystem())
+}
+
+// DeleteSnapshot deletes the named snapshot of the target.
+func (n *NVMeoF) DeleteSnapshot(ctx context.Context, nqn Nqn, name string) error {
+	return n.cli.DeleteSnapshot(ctx, nqn.Subsystem(), name)
+}
+
+// RollbackSnapshot resets the target to the state of the named snapshot.
+// The target is stopped for the rollback. If it was running before, it is
+// started again afterwards.
+func (n *NVMeoF) RollbackSnapshot(ctx context.Context, nqn Nqn, name string, resourceTimeout time.Duration) (*ResourceConfig, error) {
+	cfg, err := n.Get(ctx, nqn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	// make sure the snapshot exists before the target is stopped
+	_, err = n.cli.Snapshot(ctx, nqn.Subsystem(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	wasRunning := cfg.Status.Service == common.ServiceStateStarted
+
+	cfg, err = n.Stop(ctx, nqn, resourceTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop target: %w", err)
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	rollbackErr := n.cli.RollbackSnapshot(ctx, nqn.Subsystem(), name)
+	if !wasRunning {
+		if rollbackErr != nil {
+			return nil, rollbackErr
+		}
+		return n.Get(ctx, nqn)
+	}
+
+	// start again even if the rollback failed, so that the target is not left stopped
+	cfg, err = n.Start(ctx, nqn, resourceTimeout)
+	if rollbackErr != nil {
+		return nil, rollbackErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start target: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// SnapshotSchedule returns the snapshot schedule of the target, or nil if none
+// is set.
+func (n *NVMeoF) SnapshotSchedule(ctx context.Context, nqn Nqn) (*common.SnapshotSchedule, error) {
+	return n.cli.SnapshotSchedule(ctx, nqn.Subsystem())
+}
+
+// SetSnapshotSchedule sets or replaces the snapshot schedule of the target.
+func (n *NVMeoF) SetSnapshotSchedule(ctx context.Context, nqn Nqn, schedule common.SnapshotSchedule) error {
+	return n.cli.SetSnapshotSchedule(ctx, nqn.Subsystem(), schedule)
+}
+
+// DeleteSnapshotSchedule removes the snapshot schedule of the target.
+func (n *NVMeoF) DeleteSnapshotSchedule(ctx context.Context, nqn Nqn) error {
+	return n.cli.DeleteSnapshotSchedule(ctx, nqn.Subsystem())
+}
+
+// Replication returns the replication settings of the target, or nil if it
+// is not replicated.
+func (n *NVMeoF) Replication(ctx context.Context, nqn Nqn) (*common.Replication, error) {
+	return n.cli.Replication(ctx, nqn.Subsystem())
+}
+
+// SetReplication sets or replaces the replication settings of the target.
+func (n *NVMeoF) SetReplication(ctx context.Context, nqn Nqn, replication common.Replication) error {
+	return n.cli.SetReplication(ctx, nqn.Subsystem(), replication)
+}
+
+// DeleteReplication stops replicating the target.
+func (n *NVMeoF) DeleteReplication(ctx context.Context, nqn Nqn) error {
+	return n.cli.DeleteReplication(ctx, nqn.Subsystem())
+}
+
+// ShipSnapshot immediately ships a snapshot of the target to the remote it
+// is replicated to. It returns the name of the shipped snapshot.
+func (n *NVMeoF) ShipSnapshot(ctx context.Context, nqn Nqn) (string, error) {
+	replication, err := n.cli.Replication(ctx, nqn.Subsystem())
+	if err != nil {
+		return "", err
+	}
+
+	if replication == nil {
+		return "", common.ValidationError("target is not replicated")
+	}
+
+	return n.cli.ShipSnapshot(ctx, nqn.Subsystem(), replication.Remote)
+}
+
+// PromoteDR brings up a target that was replicated to this cluster from
+// another one. If snapshot is empty, the latest shipped snapshot is used.
+// The target keeps its NQN, namespaces and allowed hosts. As the remote site
+// usually uses a different network, the service IP and resource group can be
+// overridden via rsc. Replication settings are not carried over to the
+// promoted target.
+// The DH-HMAC-CHAP keys are not replicated. They are read from the secrets
+// file on the nodes of this cluster, unless rsc has InlineSecrets set and
+// contains them.
+// If no replica of the target exists, nil is returned.
+func (n *NVMeoF) PromoteDR(ctx context.Context, nqn Nqn, snapshot string, rsc *ResourceConfig) (*ResourceConfig, error) {
+	replica, err := n.cli.FindDRReplica(ctx, nqn.Subsystem(), snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if replica == nil {
+		return nil, nil
+	}
+
+	replicated, err := FromPromoter(replica.Config, &replica.Definition, replica.VolumeDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse replicated configuration: %w", err)
+	}
+
+	if replicated.NQN.Subsystem() != nqn.Subsystem() {
+		return nil, fmt.Errorf("replica contains configuration for %s instead of %s", replicated.NQN, nqn)
+	}
+
+	if len(rsc.ServiceIPs) > 0 {
+		replicated.ServiceIPs = rsc.ServiceIPs
+	}
+	if rsc.ResourceGroup != "" {
+		replicated.ResourceGroup = rsc.ResourceGroup
+	}
+	if rsc.InlineSecrets {
+		replicated.InlineSecrets = true
+		replicated.HostKeys = rsc.HostKeys
+	}
+	replicated.ResourceTimeout = rsc.ResourceTimeout
+	replicated.Volumes = replica.Snapshot.UserVolumes()
+
+	result, err := n.create(ctx, replicated, &linstorcontrol.SnapshotSource{
+		Resource: replica.Definition.Name,
+		Snapshot: replica.Snapshot.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = n.cli.DeleteReplication(ctx, nqn.Subsystem())
+	if err != nil {
+		log.WithError(err).Warn("failed to remove replication settings from promoted target")
+	}
+
+	return result, nil
+}
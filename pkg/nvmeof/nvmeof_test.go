@@ -1,15 +1,20 @@
 package nvmeof_test
 
 import (
+	"encoding/json"
 	"github.com/icza/gog"
 	"net"
+	"strings"
 	"testing"
 
 	"github.com/LINBIT/golinstor/client"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/LINBIT/linstor-gateway/ocf"
 	"github.com/LINBIT/linstor-gateway/pkg/common"
 	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
 )
 
 func TestResource_RoundTrip(t *testing.T) {
@@ -22,7 +27,57 @@ func TestResource_RoundTrip(t *testing.T) {
 				{Number: 2, SizeKiB: 1024},
 			},
 			ResourceGroup: "rg1",
-			ServiceIP:     common.ServiceIPFromParts(net.IP{192, 168, 127, 1}, 24),
+			ServiceIPs:    []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)},
+			Transport:     nvmeof.TransportTCP,
+			Port:          nvmeof.DefaultPort,
+		},
+		{
+			NQN: nvmeof.Nqn{"nqn.com.example.test", "multipath-resource"},
+			Volumes: []common.VolumeConfig{
+				{Number: 2, SizeKiB: 1024},
+			},
+			ResourceGroup: "rg1",
+			ServiceIPs: []common.IpCidr{
+				common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24),
+				common.ServiceIPFromParts(net.ParseIP("192.168.128.1"), 24),
+			},
+			Transport: nvmeof.TransportTCP,
+			Port:      nvmeof.DefaultPort,
+			AllowedHosts: []nvmeof.HostNqn{
+				"nqn.2014-08.org.nvmexpress:uuid:4c4c4544-0051-3510-8057-b7c04f325a32",
+				"nqn.2014-08.com.example:host2",
+				"nqn.2014-08.com.example:host3",
+			},
+			HostKeys: map[nvmeof.HostNqn]nvmeof.HostKey{
+				"nqn.2014-08.com.example:host2": {Key: "DHHC-1:00:ia5fOr0Ns4fqc/N3tuvPSBnDiFC2MgoVhSXLatQ1/o5Fjw4M:"},
+				"nqn.2014-08.com.example:host3": {
+					Key:           "DHHC-1:01:GsYNC3kxsQlvTyLa7ijsv3Bu7cgR8WqDdnRHOfCmkXBmNqdS:",
+					ControllerKey: "DHHC-1:00:dLmhn7Bxcub1pGH8OJM9Z2mzvSMrNRcWJ3LBgoZr6r/U1rhJ:",
+				},
+			},
+			InlineSecrets: true,
+		},
+		{
+			NQN: nvmeof.Nqn{"nqn.com.example.test", "rdma-resource"},
+			Volumes: []common.VolumeConfig{
+				{Number: 2, SizeKiB: 1024},
+			},
+			ResourceGroup: "rg1",
+			ServiceIPs:    []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("10.10.0.1"), 16)},
+			Transport:     nvmeof.TransportRDMA,
+			Port:          4421,
+		},
+		{
+			NQN: nvmeof.Nqn{"nqn.com.example.test", "placed-resource"},
+			Volumes: []common.VolumeConfig{
+				{Number: 2, SizeKiB: 1024},
+			},
+			ResourceGroup:  "rg1",
+			ServiceIPs:     []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)},
+			Transport:      nvmeof.TransportTCP,
+			Port:           nvmeof.DefaultPort,
+			PreferredNodes: []string{"node2", "node1"},
+			ForbiddenNodes: []string{"node3"},
 		},
 	}
 
@@ -45,9 +100,364 @@ func TestResource_RoundTrip(t *testing.T) {
 			)
 			assert.NoError(t, err)
 			assert.Equal(t, tcase.NQN, decoded.NQN)
-			assert.Equal(t, tcase.ServiceIP.String(), decoded.ServiceIP.String())
+			assert.Equal(t, tcase.ServiceIPs, decoded.ServiceIPs)
+			assert.Equal(t, tcase.Transport, decoded.Transport)
+			assert.Equal(t, tcase.Port, decoded.Port)
+			assert.Equal(t, tcase.AllowedHosts, decoded.AllowedHosts)
+			assert.Equal(t, tcase.HostKeys, decoded.HostKeys)
+			assert.Equal(t, tcase.InlineSecrets, decoded.InlineSecrets)
 			assert.Equal(t, tcase.Volumes, decoded.Volumes)
 			assert.Equal(t, tcase.ResourceGroup, decoded.ResourceGroup)
+			assert.Equal(t, tcase.PreferredNodes, decoded.PreferredNodes)
+			assert.Equal(t, tcase.ForbiddenNodes, decoded.ForbiddenNodes)
 		})
 	}
 }
+
+func TestResource_CloneIdentity(t *testing.T) {
+	t.Parallel()
+
+	// A clone restored from a snapshot has the same volumes as its source,
+	// but a different NQN and a newly created LINSTOR resource.
+	identity := func(nqn nvmeof.Nqn, rscUuid string) (string, string) {
+		rsc := nvmeof.ResourceConfig{
+			NQN: nqn,
+			Volumes: []common.VolumeConfig{
+				common.ClusterPrivateVolume(),
+				{Number: 1, SizeKiB: 1024},
+			},
+			ServiceIPs: []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)},
+			Transport:  nvmeof.TransportTCP,
+			Port:       nvmeof.DefaultPort,
+		}
+
+		cfg, err := rsc.ToPromoter([]client.ResourceWithVolumes{{
+			Resource: client.Resource{Name: nqn.Subsystem(), Uuid: rscUuid},
+			Volumes: []client.Volume{
+				{VolumeNumber: 0, DevicePath: "/dev/drbd1000"},
+				{VolumeNumber: 1, DevicePath: "/dev/drbd1001", Uuid: rscUuid + "-1"},
+			},
+		}})
+		assert.NoError(t, err)
+
+		var serial, nsUuid string
+		_, rscCfg := cfg.FirstResource()
+		for _, entry := range rscCfg.Start {
+			agent, ok := entry.(*reactor.ResourceAgent)
+			if !ok {
+				continue
+			}
+			switch agent.Type {
+			case "ocf:heartbeat:nvmet-subsystem":
+				serial = agent.Attributes["serial"]
+			case "ocf:heartbeat:nvmet-namespace":
+				nsUuid = agent.Attributes["uuid"]
+			}
+		}
+
+		assert.NotEmpty(t, serial)
+		assert.NotEmpty(t, nsUuid)
+		return serial, nsUuid
+	}
+
+	srcSerial, srcUuid := identity(nvmeof.Nqn{"nqn.com.example.test", "source"}, "5c6d3f9e-8a4e-4b6c-9c1f-2b7f0e1d3a01")
+	cloneSerial, cloneUuid := identity(nvmeof.Nqn{"nqn.com.example.test", "clone"}, "0e4b9d2a-7f13-4c85-a6e2-91d8c3b5f702")
+
+	assert.NotEqual(t, srcSerial, cloneSerial)
+	assert.NotEqual(t, srcUuid, cloneUuid)
+}
+
+func TestFromPromoter_SingleServiceIP(t *testing.T) {
+	t.Parallel()
+
+	// the layout of configs created before multiple service IPs were supported
+	cfg := &reactor.PromoterConfig{
+		Resources: map[string]reactor.PromoterResourceConfig{
+			"example": {
+				Start: []reactor.StartEntry{
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:portblock", Name: "portblock", Attributes: map[string]string{"ip": "192.168.127.1", "portno": "4420", "action": "block", "protocol": "tcp"}},
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:Filesystem", Name: "fs_cluster_private", Attributes: map[string]string{"device": "/dev/drbd1000", "directory": "/srv/ha/internal/example", "fstype": "ext4"}},
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:IPaddr2", Name: "service_ip", Attributes: map[string]string{"ip": "192.168.127.1", "cidr_netmask": "24"}},
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:nvmet-subsystem", Name: "subsys", Attributes: map[string]string{"nqn": "nqn.com.example.test:nvme:example", "serial": "0123456789abcdef"}},
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:nvmet-namespace", Name: "ns_1", Attributes: map[string]string{"nqn": "nqn.com.example.test:nvme:example", "namespace_id": "1"}},
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:nvmet-port", Name: "port", Attributes: map[string]string{"nqns": "nqn.com.example.test:nvme:example", "addr": "192.168.127.1", "type": "tcp"}},
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:portblock", Name: "portunblock", Attributes: map[string]string{"ip": "192.168.127.1", "portno": "4420", "action": "unblock", "protocol": "tcp"}},
+				},
+			},
+		},
+		Metadata: reactor.PromoterMetadata{LinstorGatewaySchemaVersion: 1},
+	}
+
+	decoded, err := nvmeof.FromPromoter(cfg, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, nvmeof.Nqn{"nqn.com.example.test", "example"}, decoded.NQN)
+	assert.Equal(t, []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)}, decoded.ServiceIPs)
+	assert.Equal(t, nvmeof.TransportTCP, decoded.Transport)
+	assert.Equal(t, nvmeof.DefaultPort, decoded.Port)
+}
+
+func TestResourceConfig_ValidHostKeys(t *testing.T) {
+	t.Parallel()
+
+	const key = "DHHC-1:00:ia5fOr0Ns4fqc/N3tuvPSBnDiFC2MgoVhSXLatQ1/o5Fjw4M:"
+
+	testcases := []struct {
+		name        string
+		keys        map[nvmeof.HostNqn]nvmeof.HostKey
+		external    bool
+		expectError bool
+	}{
+		{
+			name: "valid",
+			keys: map[nvmeof.HostNqn]nvmeof.HostKey{"nqn.2014-08.com.example:host1": {Key: key, ControllerKey: key}},
+		},
+		{
+			name:        "host not allowed",
+			keys:        map[nvmeof.HostNqn]nvmeof.HostKey{"nqn.2014-08.com.example:other": {Key: key}},
+			expectError: true,
+		},
+		{
+			name:        "malformed key",
+			keys:        map[nvmeof.HostNqn]nvmeof.HostKey{"nqn.2014-08.com.example:host1": {Key: "secret"}},
+			expectError: true,
+		},
+		{
+			name:        "malformed controller key",
+			keys:        map[nvmeof.HostNqn]nvmeof.HostKey{"nqn.2014-08.com.example:host1": {Key: key, ControllerKey: "DHHC-1:07:abc:"}},
+			expectError: true,
+		},
+		{
+			name:        "key without inline secrets",
+			keys:        map[nvmeof.HostNqn]nvmeof.HostKey{"nqn.2014-08.com.example:host1": {Key: key}},
+			external:    true,
+			expectError: true,
+		},
+		{
+			name:     "external secrets",
+			external: true,
+		},
+	}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			rsc := nvmeof.ResourceConfig{
+				NQN:           nvmeof.Nqn{"nqn.com.example.test", "example"},
+				ServiceIPs:    []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)},
+				Transport:     nvmeof.TransportTCP,
+				Port:          nvmeof.DefaultPort,
+				AllowedHosts:  []nvmeof.HostNqn{"nqn.2014-08.com.example:host1"},
+				HostKeys:      tcase.keys,
+				InlineSecrets: !tcase.external,
+			}
+
+			err := rsc.Valid()
+			if tcase.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	const key = "DHHC-1:00:ia5fOr0Ns4fqc/N3tuvPSBnDiFC2MgoVhSXLatQ1/o5Fjw4M:"
+
+	rsc := nvmeof.ResourceConfig{
+		NQN:           nvmeof.Nqn{"nqn.com.example.test", "example"},
+		ServiceIPs:    []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)},
+		Transport:     nvmeof.TransportTCP,
+		Port:          nvmeof.DefaultPort,
+		AllowedHosts:  []nvmeof.HostNqn{"nqn.2014-08.com.example:host1"},
+		HostKeys:      map[nvmeof.HostNqn]nvmeof.HostKey{"nqn.2014-08.com.example:host1": {Key: key, ControllerKey: key}},
+		InlineSecrets: true,
+	}
+
+	cfg, err := rsc.ToPromoter([]client.ResourceWithVolumes{{Volumes: []client.Volume{{VolumeNumber: 0}}}})
+	require.NoError(t, err)
+	assert.True(t, nvmeof.RedactPromoter(cfg))
+	assert.False(t, nvmeof.RedactPromoter(cfg))
+
+	encoded, err := reactor.EncodeConfig(cfg)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "DHHC-1")
+
+	rsc.Redact()
+	assert.Equal(t, map[nvmeof.HostNqn]nvmeof.HostKey{"nqn.2014-08.com.example:host1": {}}, rsc.HostKeys)
+}
+
+func TestResourceConfig_MatchesHosts(t *testing.T) {
+	t.Parallel()
+
+	host1 := nvmeof.HostNqn("nqn.2014-08.org.nvmexpress:uuid:host1")
+	host2 := nvmeof.HostNqn("nqn.2014-08.org.nvmexpress:uuid:host2")
+	base := nvmeof.ResourceConfig{
+		NQN:           nvmeof.Nqn{"nqn.com.example.test", "example-resource"},
+		ResourceGroup: "rg1",
+		ServiceIPs:    []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)},
+		AllowedHosts:  []nvmeof.HostNqn{host1, host2},
+		InlineSecrets: true,
+		HostKeys:      map[nvmeof.HostNqn]nvmeof.HostKey{host1: {Key: "DHHC-1:00:key1:"}},
+	}
+
+	same := base
+	same.AllowedHosts = []nvmeof.HostNqn{host2, host1}
+	assert.True(t, base.Matches(&same))
+
+	otherHosts := base
+	otherHosts.AllowedHosts = []nvmeof.HostNqn{host1}
+	assert.False(t, base.Matches(&otherHosts))
+
+	otherKeys := base
+	otherKeys.HostKeys = map[nvmeof.HostNqn]nvmeof.HostKey{host1: {Key: "DHHC-1:00:key2:"}}
+	assert.False(t, base.Matches(&otherKeys))
+
+	noKeys := base
+	noKeys.HostKeys = nil
+	assert.False(t, base.Matches(&noKeys))
+}
+
+func TestResourceConfig_JSONServiceIP(t *testing.T) {
+	t.Parallel()
+
+	ip1 := common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)
+	ip2 := common.ServiceIPFromParts(net.ParseIP("192.168.128.1"), 24)
+
+	var legacy nvmeof.ResourceConfig
+	err := json.Unmarshal([]byte(`{"nqn": "linbit:nvme:example", "service_ip": "192.168.127.1/24"}`), &legacy)
+	require.NoError(t, err)
+	assert.Equal(t, []common.IpCidr{ip1}, legacy.ServiceIPs)
+
+	var both nvmeof.ResourceConfig
+	err = json.Unmarshal([]byte(`{"service_ip": "192.168.127.1/24", "service_ips": ["192.168.128.1/24"]}`), &both)
+	require.NoError(t, err)
+	assert.Equal(t, []common.IpCidr{ip2}, both.ServiceIPs)
+
+	encoded, err := json.Marshal(&nvmeof.ResourceConfig{ServiceIPs: []common.IpCidr{ip1, ip2}})
+	require.NoError(t, err)
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(encoded, &raw))
+	assert.Equal(t, "192.168.127.1/24", raw["service_ip"])
+	assert.Equal(t, []any{"192.168.127.1/24", "192.168.128.1/24"}, raw["service_ips"])
+
+	var decoded nvmeof.ResourceConfig
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, []common.IpCidr{ip1, ip2}, decoded.ServiceIPs)
+}
+
+// heartbeatParameters are the parameters of the agents from the
+// resource-agents package that are used for NVMe-oF targets.
+var heartbeatParameters = map[string][]string{
+	"ocf:heartbeat:Filesystem":      {"device", "directory", "fstype", "options", "statusfile_prefix", "run_fsck", "fast_stop", "force_clones", "force_unmount", "term_signals"},
+	"ocf:heartbeat:portblock":       {"protocol", "portno", "action", "ip", "reset_local_on_unblock_stop", "tickle_dir", "sync_script", "direction"},
+	"ocf:heartbeat:IPaddr2":         {"ip", "nic", "cidr_netmask", "broadcast", "iflabel"},
+	"ocf:heartbeat:nvmet-subsystem": {"nqn", "serial", "allowed_hosts"},
+	"ocf:heartbeat:nvmet-namespace": {"nqn", "namespace_id", "backing_path", "uuid", "nguid"},
+	"ocf:heartbeat:nvmet-port":      {"port_id", "type", "addr", "svcid", "addr_fam", "nqns"},
+}
+
+func TestToPromoter_AgentParameters(t *testing.T) {
+	t.Parallel()
+
+	const key = "DHHC-1:00:ia5fOr0Ns4fqc/N3tuvPSBnDiFC2MgoVhSXLatQ1/o5Fjw4M:"
+	host1 := nvmeof.HostNqn("nqn.2014-08.com.example:host1")
+	host2 := nvmeof.HostNqn("nqn.2014-08.com.example:host2")
+	rsc := nvmeof.ResourceConfig{
+		NQN:           nvmeof.Nqn{"nqn.com.example.test", "example"},
+		Volumes:       []common.VolumeConfig{common.ClusterPrivateVolume(), {Number: 1, SizeKiB: 1024}},
+		ServiceIPs:    []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)},
+		Transport:     nvmeof.TransportTCP,
+		Port:          nvmeof.DefaultPort,
+		AllowedHosts:  []nvmeof.HostNqn{host1, host2},
+		HostKeys:      map[nvmeof.HostNqn]nvmeof.HostKey{host2: {Key: key, ControllerKey: key}},
+		InlineSecrets: true,
+	}
+
+	cfg, err := rsc.ToPromoter([]client.ResourceWithVolumes{{
+		Resource: client.Resource{Name: "example"},
+		Volumes:  []client.Volume{{VolumeNumber: 0, DevicePath: "/dev/drbd1000"}, {VolumeNumber: 1, DevicePath: "/dev/drbd1001"}},
+	}})
+	require.NoError(t, err)
+
+	_, rscCfg := cfg.FirstResource()
+	var auth *reactor.ResourceAgent
+	for _, entry := range rscCfg.Start {
+		agent, ok := entry.(*reactor.ResourceAgent)
+		if !ok {
+			continue
+		}
+
+		supported, ok := heartbeatParameters[agent.Type]
+		if vendorAgent, found := strings.CutPrefix(agent.Type, "ocf:"+ocf.Vendor+":"); found {
+			supported, err = ocf.Parameters(vendorAgent)
+			require.NoError(t, err)
+			ok = true
+			auth = agent
+		}
+		require.True(t, ok, "unknown agent %s", agent.Type)
+
+		for key := range agent.Attributes {
+			assert.Contains(t, supported, key, "agent %s does not support parameter %s", agent.Type, key)
+		}
+	}
+
+	require.NotNil(t, auth)
+	assert.Equal(t, ";"+key, auth.Attributes["dhchap_keys"])
+	assert.Equal(t, ";"+key, auth.Attributes["dhchap_ctrl_keys"])
+}
+
+func TestToPromoter_ExternalKeys(t *testing.T) {
+	t.Parallel()
+
+	rsc := nvmeof.ResourceConfig{
+		NQN:          nvmeof.Nqn{"nqn.com.example.test", "example"},
+		ServiceIPs:   []common.IpCidr{common.ServiceIPFromParts(net.ParseIP("192.168.127.1"), 24)},
+		Transport:    nvmeof.TransportTCP,
+		Port:         nvmeof.DefaultPort,
+		AllowedHosts: []nvmeof.HostNqn{"nqn.2014-08.com.example:host1"},
+	}
+
+	cfg, err := rsc.ToPromoter([]client.ResourceWithVolumes{{Volumes: []client.Volume{{VolumeNumber: 0}}}})
+	require.NoError(t, err)
+
+	// the keys come from the secrets file of the auth agent
+	_, rscCfg := cfg.FirstResource()
+	var found bool
+	for _, entry := range rscCfg.Start {
+		agent, ok := entry.(*reactor.ResourceAgent)
+		if ok && agent.Name == "auth" {
+			found = true
+			assert.Equal(t, map[string]string{"nqn": "nqn.com.example.test:nvme:example", "allowed_hosts": "nqn.2014-08.com.example:host1"}, agent.Attributes)
+		}
+	}
+	assert.True(t, found)
+	assert.Equal(t, "/etc/linstor-gateway/secrets/auth_example", rsc.SecretsPath())
+}
+
+func TestFromPromoter_LegacyHostKeys(t *testing.T) {
+	t.Parallel()
+
+	// before the nvmet-auth agent, the keys were given to the subsystem agent
+	cfg := &reactor.PromoterConfig{
+		Resources: map[string]reactor.PromoterResourceConfig{
+			"example": {
+				Start: []reactor.StartEntry{
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:IPaddr2", Name: "service_ip0", Attributes: map[string]string{"ip": "192.168.127.1", "cidr_netmask": "24"}},
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:nvmet-subsystem", Name: "subsys", Attributes: map[string]string{"nqn": "nqn.com.example.test:nvme:example", "serial": "0123456789abcdef", "allowed_hosts": "nqn.2014-08.com.example:host1", "allow_any_host": "0", "dhchap_keys": "DHHC-1:00:key:"}},
+					&reactor.ResourceAgent{Type: "ocf:heartbeat:nvmet-port", Name: "port0", Attributes: map[string]string{"nqns": "nqn.com.example.test:nvme:example", "addr": "192.168.127.1", "type": "rdma"}},
+				},
+			},
+		},
+		Metadata: reactor.PromoterMetadata{LinstorGatewaySchemaVersion: 2},
+	}
+
+	decoded, err := nvmeof.FromPromoter(cfg, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[nvmeof.HostNqn]nvmeof.HostKey{"nqn.2014-08.com.example:host1": {Key: "DHHC-1:00:key:"}}, decoded.HostKeys)
+	assert.True(t, decoded.InlineSecrets)
+}
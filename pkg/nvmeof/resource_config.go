@@ -3,10 +3,14 @@ package nvmeof
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,24 +20,68 @@ import (
 	"github.com/google/uuid"
 	"github.com/icza/gog"
 
+	"github.com/LINBIT/linstor-gateway/ocf"
 	"github.com/LINBIT/linstor-gateway/pkg/common"
 	"github.com/LINBIT/linstor-gateway/pkg/reactor"
 )
 
 const (
 	DefaultPort            = 4420
-	CurrentVersion         = 1
+	DefaultTransport       = TransportTCP
+	CurrentVersion         = 2
 	DefaultResourceTimeout = 30 * time.Second
 )
 
+const (
+	TransportTCP  = "tcp"
+	TransportRDMA = "rdma"
+)
+
+// HostKey holds the DH-HMAC-CHAP secrets for one host, in the
+// "DHHC-1:<hash>:<base64>:" representation generated by
+// "nvme gen-dhchap-key".
+type HostKey struct {
+	// Key is the secret the host authenticates with.
+	Key string `json:"key"`
+	// ControllerKey is the secret the target authenticates with, for
+	// bidirectional authentication. Optional.
+	ControllerKey string `json:"controller_key,omitempty"`
+}
+
+// regexDHChapKey matches the secret representation described in the NVMe
+// base specification: a hash identifier followed by the base64 encoded
+// secret and its CRC-32.
+var regexDHChapKey = regexp.MustCompile(`^DHHC-1:0[0-3]:[A-Za-z0-9+/]{44,92}={0,2}:$`)
+
+func validDHChapKey(key string) bool {
+	return regexDHChapKey.MatchString(key)
+}
+
 type ResourceConfig struct {
 	NQN             Nqn                   `json:"nqn"`
-	ServiceIP       common.IpCidr         `json:"service_ip"`
+	ServiceIPs      []common.IpCidr       `json:"service_ips"`
+	Transport       string                `json:"transport,omitempty"`
+	Port            int                   `json:"port,omitempty"`
+	AllowedHosts    []HostNqn             `json:"allowed_hosts,omitempty"`
+	HostKeys        map[HostNqn]HostKey   `json:"host_keys,omitempty"`
 	ResourceGroup   string                `json:"resource_group"`
 	Volumes         []common.VolumeConfig `json:"volumes"`
 	Status          common.ResourceStatus `json:"status"`
 	GrossSize       bool                  `json:"gross_size"`
 	ResourceTimeout time.Duration         `json:"resource_timeout,omitempty"`
+	// InlineSecrets stores the DH-HMAC-CHAP keys in the promoter
+	// configuration, which is readable by every LINSTOR user. By default,
+	// the keys are kept out of LINSTOR: the target reads them from
+	// SecretsPath on each node, which has to be provisioned by the
+	// administrator, and HostKeys must be left empty.
+	InlineSecrets bool `json:"inline_secrets,omitempty"`
+	// PreferredNodes are tried first, in order, when drbd-reactor picks a
+	// node to start the target on. The target is never migrated or evacuated to
+	// ForbiddenNodes, but drbd-reactor cannot exclude nodes entirely: on a
+	// failover, they are used if no other node can run the target.
+	// Use "linstor-gateway node evacuate" to keep a node free completely.
+	PreferredNodes []string `json:"preferred_nodes,omitempty"`
+	ForbiddenNodes []string `json:"forbidden_nodes,omitempty"`
 }
 
 func (r *ResourceConfig) VolumeConfig(number int) *common.Volume {
@@ -66,15 +114,81 @@ func (r *ResourceConfig) ID() string {
 	return fmt.Sprintf(IDFormat, r.NQN.Subsystem())
 }
 
-func parseIP(startEntries []reactor.StartEntry, index int) (common.IpCidr, error) {
-	ipAgent, ok := startEntries[index].(*reactor.ResourceAgent)
-	if !ok {
-		return common.IpCidr{}, fmt.Errorf("expected a resource agent at index %d, got a systemd service", index)
+// SecretsPath returns the path of the file holding the DH-HMAC-CHAP keys of
+// the target if InlineSecrets is not set. The file is loaded as systemd
+// EnvironmentFile by common.SecretsDropIn, so the keys are given to the
+// nvmet-auth agent as OCF_RESKEY_dhchap_keys and OCF_RESKEY_dhchap_ctrl_keys
+// (';'-separated, in the order of the allowed hosts).
+func (r *ResourceConfig) SecretsPath() string {
+	return filepath.Join(common.SecretsDir, "auth_"+r.NQN.Subsystem())
+}
+
+// legacyResourceConfig is the JSON form of a ResourceConfig. Before targets
+// could have multiple service IPs, the only one was given as "service_ip".
+// That key is still accepted if "service_ips" is missing, and set to the
+// first service IP for older clients.
+type legacyResourceConfig struct {
+	plainResourceConfig
+	ServiceIP *common.IpCidr `json:"service_ip,omitempty"`
+}
+
+// plainResourceConfig has the fields, but not the methods of ResourceConfig,
+// so that it is encoded without recursing into MarshalJSON.
+type plainResourceConfig ResourceConfig
+
+func (r ResourceConfig) MarshalJSON() ([]byte, error) {
+	out := legacyResourceConfig{plainResourceConfig: plainResourceConfig(r)}
+	if len(r.ServiceIPs) > 0 {
+		out.ServiceIP = &r.ServiceIPs[0]
 	}
-	if ipAgent.Type != "ocf:heartbeat:IPaddr2" {
-		return common.IpCidr{}, fmt.Errorf("expected 'ocf:heartbeat:IPaddr2' agent, got '%s' instead", ipAgent.Type)
+	return json.Marshal(out)
+}
+
+func (r *ResourceConfig) UnmarshalJSON(b []byte) error {
+	in := legacyResourceConfig{plainResourceConfig: plainResourceConfig(*r)}
+	err := json.Unmarshal(b, &in)
+	if err != nil {
+		return err
 	}
 
+	*r = ResourceConfig(in.plainResourceConfig)
+	if len(r.ServiceIPs) == 0 && in.ServiceIP != nil {
+		r.ServiceIPs = []common.IpCidr{*in.ServiceIP}
+	}
+	return nil
+}
+
+// Redact removes all secrets from the configuration, so that it can be
+// shown to users. The hosts that have keys are kept.
+func (r *ResourceConfig) Redact() {
+	if r.HostKeys != nil {
+		redacted := make(map[HostNqn]HostKey, len(r.HostKeys))
+		for host := range r.HostKeys {
+			redacted[host] = HostKey{}
+		}
+		r.HostKeys = redacted
+	}
+}
+
+// RedactPromoter removes the DH-HMAC-CHAP keys from the promoter config of an
+// NVMe-oF target. It reports whether anything was removed.
+func RedactPromoter(cfg *reactor.PromoterConfig) bool {
+	return common.RedactSecrets(cfg)
+}
+
+const (
+	agentTypePortblock      = "ocf:heartbeat:portblock"
+	agentTypeIPaddr2        = "ocf:heartbeat:IPaddr2"
+	agentTypeNvmetSubsystem = "ocf:heartbeat:nvmet-subsystem"
+	agentTypeNvmetPort      = "ocf:heartbeat:nvmet-port"
+	// the nvmet-subsystem agent creates the allowed hosts, but cannot set
+	// their keys, which is done by our own agent after it
+	agentTypeNvmetAuth = "ocf:" + ocf.Vendor + ":nvmet-auth"
+)
+
+const minAgentEntries = 3 // service_ip, subsys, port
+
+func parseIP(ipAgent *reactor.ResourceAgent) (common.IpCidr, error) {
 	ip := net.ParseIP(ipAgent.Attributes["ip"])
 	if ip == nil {
 		return common.IpCidr{}, fmt.Errorf("malformed ip %s", ipAgent.Attributes["ip"])
@@ -88,48 +202,166 @@ func parseIP(startEntries []reactor.StartEntry, index int) (common.IpCidr, error
 	return common.ServiceIPFromParts(ip, prefixLength), nil
 }
 
-func parseNQN(startEntries []reactor.StartEntry, index int) (Nqn, error) {
-	subsysAgent, ok := startEntries[index].(*reactor.ResourceAgent)
-	if !ok {
-		return Nqn{}, fmt.Errorf("expected a resource agent at index %d, got a systemd service", index)
+// parseHostKeys parses the ';'-separated key lists of the nvmet-auth agent. Both lists have one (possibly empty) entry per allowed host.
+func parseHostKeys(hosts []HostNqn, rawKeys, rawCtrlKeys string) (map[HostNqn]HostKey, error) {
+	if rawKeys == "" {
+		return nil, nil
 	}
-	if subsysAgent.Type != "ocf:heartbeat:nvmet-subsystem" {
-		return Nqn{}, errors.New(fmt.Sprintf("expected 'ocf:heartbeat:nvmet-subsystem' agent, got '%s' instead", subsysAgent.Type))
+
+	keys := strings.Split(rawKeys, ";")
+	if len(keys) != len(hosts) {
+		return nil, fmt.Errorf("malformed configuration: got %d dhchap keys for %d allowed hosts", len(keys), len(hosts))
 	}
 
-	return NewNqn(subsysAgent.Attributes["nqn"])
+	ctrlKeys := make([]string, len(hosts))
+	if rawCtrlKeys != "" {
+		ctrlKeys = strings.Split(rawCtrlKeys, ";")
+		if len(ctrlKeys) != len(hosts) {
+			return nil, fmt.Errorf("malformed configuration: got %d dhchap controller keys for %d allowed hosts", len(ctrlKeys), len(hosts))
+		}
+	}
+
+	result := make(map[HostNqn]HostKey)
+	for i, host := range hosts {
+		if keys[i] == "" {
+			continue
+		}
+
+		result[host] = HostKey{Key: keys[i], ControllerKey: ctrlKeys[i]}
+	}
+
+	return result, nil
 }
 
-func FromPromoter(cfg *reactor.PromoterConfig, definition *client.ResourceDefinition, volumeDefinition []client.VolumeDefinition) (*ResourceConfig, error) {
-	r := &ResourceConfig{}
+func parsePromoterConfig(cfg *reactor.PromoterConfig) (*ResourceConfig, error) {
+	r := &ResourceConfig{
+		PreferredNodes: cfg.Metadata.PreferredNodes,
+		ForbiddenNodes: cfg.Metadata.ForbiddenNodes,
+	}
 
 	_, rscCfg := cfg.FirstResource()
 	if rscCfg == nil {
 		return nil, fmt.Errorf("promoter config without resource")
 	}
 
-	if definition != nil {
-		r.ResourceGroup = definition.ResourceGroupName
-	}
-
 	if len(cfg.Resources) != 1 {
 		return nil, errors.New(fmt.Sprintf("promoter config without exactly 1 resource (has %d)", len(cfg.Resources)))
 	}
 
-	if len(rscCfg.Start) < 5 {
-		return nil, errors.New(fmt.Sprintf("config has too few agent entries, expected at least 3, got %d", len(rscCfg.Start)))
+	if len(rscCfg.Start) < minAgentEntries {
+		return nil, errors.New(fmt.Sprintf("config has too few agent entries, expected at least %d, got %d",
+			minAgentEntries, len(rscCfg.Start)))
 	}
 
 	var err error
-	r.ServiceIP, err = parseIP(rscCfg.Start, 2)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse service IP: %w", err)
+	var numPortblocks, numPortunblocks, numPorts int
+	var foundSubsystem bool
+	for _, entry := range rscCfg.Start {
+		agent, ok := entry.(*reactor.ResourceAgent)
+		if !ok {
+			continue
+		}
+
+		switch agent.Type {
+		case agentTypePortblock:
+			switch agent.Attributes["action"] {
+			case "block":
+				numPortblocks++
+			case "unblock":
+				numPortunblocks++
+			}
+		case agentTypeIPaddr2:
+			ip, err := parseIP(agent)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse service IP: %w", err)
+			}
+
+			r.ServiceIPs = append(r.ServiceIPs, ip)
+		case agentTypeNvmetSubsystem:
+			r.NQN, err = NewNqn(agent.Attributes["nqn"])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse NQN: %w", err)
+			}
+			foundSubsystem = true
+
+			for _, allowed := range strings.Fields(agent.Attributes["allowed_hosts"]) {
+				host, err := NewHostNqn(allowed)
+				if err != nil {
+					return nil, fmt.Errorf("got malformed host nqn %s for allowed hosts: %w", allowed, err)
+				}
+				r.AllowedHosts = append(r.AllowedHosts, host)
+			}
+
+			// targets created before the nvmet-auth agent had their keys
+			// here, the auth agent comes later and takes precedence
+			r.HostKeys, err = parseHostKeys(r.AllowedHosts, agent.Attributes["dhchap_keys"], agent.Attributes["dhchap_ctrl_keys"])
+			if err != nil {
+				return nil, err
+			}
+
+		case agentTypeNvmetAuth:
+			r.HostKeys, err = parseHostKeys(r.AllowedHosts, agent.Attributes["dhchap_keys"], agent.Attributes["dhchap_ctrl_keys"])
+			if err != nil {
+				return nil, err
+			}
+		case agentTypeNvmetPort:
+			numPorts++
+
+			r.Transport = agent.Attributes["type"]
+			if r.Transport == "" {
+				r.Transport = DefaultTransport
+			}
+
+			r.Port = DefaultPort
+			if svcid := agent.Attributes["svcid"]; svcid != "" {
+				r.Port, err = strconv.Atoi(svcid)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse port: %w", err)
+				}
+			}
+		}
+	}
+
+	if !foundSubsystem {
+		return nil, fmt.Errorf("malformed configuration: missing %s agent", agentTypeNvmetSubsystem)
 	}
 
-	r.NQN, err = parseNQN(rscCfg.Start, 3)
+	// targets created before external secrets were the default have their
+	// keys in the config, but no marker
+	r.InlineSecrets = !cfg.Metadata.ExternalSecrets && len(r.HostKeys) > 0
+
+	if numPortblocks != numPortunblocks {
+		return nil, fmt.Errorf("malformed configuration: got a different number of portblock and portunblock agents")
+	}
+
+	// ports are only blocked for tcp, see ToPromoter
+	expectedPortblocks := len(r.ServiceIPs)
+	if r.Transport != TransportTCP {
+		expectedPortblocks = 0
+	}
+
+	if numPortblocks != expectedPortblocks {
+		return nil, fmt.Errorf("malformed configuration: got %d portblock agents for %d IPaddr2 agents with transport %s",
+			numPortblocks, len(r.ServiceIPs), r.Transport)
+	}
+
+	if numPorts != len(r.ServiceIPs) {
+		return nil, fmt.Errorf("malformed configuration: got a different number of nvmet-port agents than IPaddr2 agents")
+	}
+
+	return r, nil
+}
+
+func FromPromoter(cfg *reactor.PromoterConfig, definition *client.ResourceDefinition, volumeDefinition []client.VolumeDefinition) (*ResourceConfig, error) {
+	r, err := parsePromoterConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse NQN: %w", err)
+		return nil, fmt.Errorf("failed to parse promoter config: %w", err)
 	}
+
+	if definition != nil {
+		r.ResourceGroup = definition.ResourceGroupName
+	}
+
 	for _, vd := range volumeDefinition {
 		if vd.VolumeNumber == nil {
 			vd.VolumeNumber = gog.Ptr(int32(0))
@@ -143,6 +375,39 @@ func FromPromoter(cfg *reactor.PromoterConfig, definition *client.ResourceDefini
 	return r, nil
 }
 
+// authAgent returns the nvmet-auth agent that sets the DH-HMAC-CHAP keys of
+// the allowed hosts.
+func (r *ResourceConfig) authAgent(allowedHosts []string) *reactor.ResourceAgent {
+	authAttrs := map[string]string{
+		"nqn":           r.NQN.String(),
+		"allowed_hosts": strings.Join(allowedHosts, " "),
+	}
+	if r.InlineSecrets && len(r.HostKeys) > 0 {
+		// one entry per allowed host, in the same order as "allowed_hosts"
+		keys := make([]string, len(r.AllowedHosts))
+		ctrlKeys := make([]string, len(r.AllowedHosts))
+		var anyCtrlKey bool
+		for i, host := range r.AllowedHosts {
+			keys[i] = r.HostKeys[host].Key
+			ctrlKeys[i] = r.HostKeys[host].ControllerKey
+			if ctrlKeys[i] != "" {
+				anyCtrlKey = true
+			}
+		}
+
+		authAttrs["dhchap_keys"] = strings.Join(keys, ";")
+		if anyCtrlKey {
+			authAttrs["dhchap_ctrl_keys"] = strings.Join(ctrlKeys, ";")
+		}
+	}
+
+	return &reactor.ResourceAgent{
+		Type:       agentTypeNvmetAuth,
+		Name:       "auth",
+		Attributes: authAttrs,
+	}
+}
+
 func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*reactor.PromoterConfig, error) {
 	if len(deployment) == 0 {
 		return nil, errors.New("resource config is missing deployment information")
@@ -157,34 +422,62 @@ func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*r
 	// volume 0 is reserved as the "cluster private" volume
 	deployedClusterPrivateVol := deployedRes.Volumes[0]
 
-	agents := []reactor.StartEntry{
-		&reactor.ResourceAgent{
-			Type: "ocf:heartbeat:portblock",
-			Name: fmt.Sprintf("portblock"),
+	// portblock works on tcp connections, there is nothing to block for rdma
+	blockedIPs := r.ServiceIPs
+	if r.Transport != TransportTCP {
+		blockedIPs = nil
+	}
+
+	var agents []reactor.StartEntry
+
+	for i, ip := range blockedIPs {
+		agents = append(agents, &reactor.ResourceAgent{
+			Type: agentTypePortblock,
+			Name: fmt.Sprintf("pblock%d", i),
 			Attributes: map[string]string{
-				"ip":       r.ServiceIP.IP().String(),
-				"portno":   strconv.Itoa(DefaultPort),
+				"ip":       ip.IP().String(),
+				"portno":   strconv.Itoa(r.Port),
 				"action":   "block",
 				"protocol": "tcp",
 			},
-		},
-		common.ClusterPrivateVolumeAgent(deployedClusterPrivateVol, r.NQN.Subsystem()),
-		&reactor.ResourceAgent{
-			Type: "ocf:heartbeat:IPaddr2",
-			Name: "service_ip",
-			Attributes: map[string]string{
-				"ip":           r.ServiceIP.IP().String(),
-				"cidr_netmask": strconv.Itoa(r.ServiceIP.Prefix()),
-			},
-		},
-		&reactor.ResourceAgent{
-			Type: "ocf:heartbeat:nvmet-subsystem",
-			Name: "subsys",
+		})
+	}
+
+	agents = append(agents, common.ClusterPrivateVolumeAgent(deployedClusterPrivateVol, r.NQN.Subsystem()))
+
+	for i, ip := range r.ServiceIPs {
+		agents = append(agents, &reactor.ResourceAgent{
+			Type: agentTypeIPaddr2,
+			Name: fmt.Sprintf("service_ip%d", i),
 			Attributes: map[string]string{
-				"nqn":    r.NQN.String(),
-				"serial": serial,
+				"ip":           ip.IP().String(),
+				"cidr_netmask": strconv.Itoa(ip.Prefix()),
 			},
-		},
+		})
+	}
+
+	subsysAttrs := map[string]string{
+		"nqn":    r.NQN.String(),
+		"serial": serial,
+	}
+	allowedHostStrings := make([]string, 0, len(r.AllowedHosts))
+	for i := range r.AllowedHosts {
+		allowedHostStrings = append(allowedHostStrings, r.AllowedHosts[i].String())
+	}
+	if len(r.AllowedHosts) > 0 {
+		subsysAttrs["allowed_hosts"] = strings.Join(allowedHostStrings, " ")
+	}
+
+	agents = append(agents, &reactor.ResourceAgent{
+		Type:       agentTypeNvmetSubsystem,
+		Name:       "subsys",
+		Attributes: subsysAttrs,
+	})
+
+	// with external secrets, the keys are only known to the agent, so it
+	// is needed whenever hosts could have keys
+	if len(r.HostKeys) > 0 || (!r.InlineSecrets && len(r.AllowedHosts) > 0) {
+		agents = append(agents, r.authAgent(allowedHostStrings))
 	}
 
 	for i := 1; i < len(deployedRes.Volumes); i++ {
@@ -221,19 +514,23 @@ func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*r
 		})
 	}
 
-	agents = append(agents, &reactor.ResourceAgent{Type: "ocf:heartbeat:nvmet-port", Name: "port", Attributes: map[string]string{"nqns": r.NQN.String(), "addr": r.ServiceIP.IP().String(), "type": "tcp"}})
+	for i, ip := range r.ServiceIPs {
+		agents = append(agents, &reactor.ResourceAgent{Type: agentTypeNvmetPort, Name: fmt.Sprintf("port%d", i), Attributes: map[string]string{"nqns": r.NQN.String(), "addr": ip.IP().String(), "type": r.Transport, "svcid": strconv.Itoa(r.Port)}})
+	}
 
-	agents = append(agents, &reactor.ResourceAgent{
-		Type: "ocf:heartbeat:portblock",
-		Name: "portunblock",
-		Attributes: map[string]string{
-			"ip":         r.ServiceIP.IP().String(),
-			"portno":     strconv.Itoa(DefaultPort),
-			"action":     "unblock",
-			"protocol":   "tcp",
-			"tickle_dir": filepath.Join(common.ClusterPrivateVolumeMountPath, deployedRes.Name),
-		},
-	})
+	for i, ip := range blockedIPs {
+		agents = append(agents, &reactor.ResourceAgent{
+			Type: agentTypePortblock,
+			Name: fmt.Sprintf("portunblock%d", i),
+			Attributes: map[string]string{
+				"ip":         ip.IP().String(),
+				"portno":     strconv.Itoa(r.Port),
+				"action":     "unblock",
+				"protocol":   "tcp",
+				"tickle_dir": filepath.Join(common.ClusterPrivateVolumeMountPath, deployedRes.Name),
+			},
+		})
+	}
 
 	return &reactor.PromoterConfig{
 		Resources: map[string]reactor.PromoterResourceConfig{
@@ -243,10 +540,14 @@ func (r *ResourceConfig) ToPromoter(deployment []client.ResourceWithVolumes) (*r
 				StopServicesOnExit:  true,
 				OnDrbdDemoteFailure: "reboot-immediate",
 				TargetAs:            "Requires",
+				PreferredNodes:      common.PromoterNodeOrder(r.PreferredNodes, r.ForbiddenNodes, deployment),
 			},
 		},
 		Metadata: reactor.PromoterMetadata{
 			LinstorGatewaySchemaVersion: CurrentVersion,
+			ExternalSecrets:             !r.InlineSecrets,
+			PreferredNodes:              r.PreferredNodes,
+			ForbiddenNodes:              r.ForbiddenNodes,
 		},
 	}, nil
 }
@@ -256,7 +557,21 @@ func (r *ResourceConfig) Matches(o *ResourceConfig) bool {
 		return false
 	}
 
-	if r.ServiceIP.String() != o.ServiceIP.String() {
+	if len(r.ServiceIPs) != len(o.ServiceIPs) {
+		return false
+	}
+
+	for i := range r.ServiceIPs {
+		if r.ServiceIPs[i].String() != o.ServiceIPs[i].String() {
+			return false
+		}
+	}
+
+	if r.Transport != o.Transport {
+		return false
+	}
+
+	if r.Port != o.Port {
 		return false
 	}
 
@@ -264,6 +579,22 @@ func (r *ResourceConfig) Matches(o *ResourceConfig) bool {
 		return false
 	}
 
+	if r.InlineSecrets != o.InlineSecrets {
+		return false
+	}
+
+	if !sameHosts(r.AllowedHosts, o.AllowedHosts) {
+		return false
+	}
+
+	if !maps.Equal(r.HostKeys, o.HostKeys) {
+		return false
+	}
+
+	if !slices.Equal(r.PreferredNodes, o.PreferredNodes) || !slices.Equal(r.ForbiddenNodes, o.ForbiddenNodes) {
+		return false
+	}
+
 	if len(r.Volumes) != len(o.Volumes) {
 		return false
 	}
@@ -281,10 +612,21 @@ func (r *ResourceConfig) Matches(o *ResourceConfig) bool {
 	return true
 }
 
+// sameHosts reports whether a and b contain the same hosts, in any order.
+func sameHosts(a, b []HostNqn) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
+
 func (r *ResourceConfig) FillDefaults() {
 	if r.ResourceGroup == "" {
 		r.ResourceGroup = "DfltRscGrp"
 	}
+	if r.Transport == "" {
+		r.Transport = DefaultTransport
+	}
+	if r.Port == 0 {
+		r.Port = DefaultPort
+	}
 	if r.ResourceTimeout == 0 {
 		r.ResourceTimeout = DefaultResourceTimeout
 	}
@@ -295,12 +637,50 @@ func (r *ResourceConfig) Valid() error {
 		return common.ValidationError("nvme subsystem string to short (min. 2)")
 	}
 
-	if r.ServiceIP.IP() == nil {
-		return common.ValidationError("missing service ip")
+	if len(r.ServiceIPs) == 0 {
+		return common.ValidationError("missing service ips")
 	}
 
-	if r.ServiceIP.Mask == nil {
-		return common.ValidationError("missing service ip prefix length")
+	if r.Transport != TransportTCP && r.Transport != TransportRDMA {
+		return common.ValidationError(fmt.Sprintf("unknown transport %q (expected %q or %q)", r.Transport, TransportTCP, TransportRDMA))
+	}
+
+	if r.Port < 1 || r.Port > 65535 {
+		return common.ValidationError("port must be between 1 and 65535")
+	}
+
+	for i, ip := range r.ServiceIPs {
+		if ip.IP() == nil {
+			return common.ValidationError("missing service ip")
+		}
+
+		if ip.Mask == nil {
+			return common.ValidationError("missing service ip prefix length")
+		}
+
+		for _, other := range r.ServiceIPs[:i] {
+			if ip.IP().Equal(other.IP()) {
+				return common.ValidationError("service ips must be unique")
+			}
+		}
+	}
+
+	if !r.InlineSecrets && len(r.HostKeys) > 0 {
+		return common.ValidationError(fmt.Sprintf("dhchap keys are read from %s on each node and must not be given, unless inline secrets are enabled to store them in LINSTOR", r.SecretsPath()))
+	}
+
+	for host, key := range r.HostKeys {
+		if !slices.Contains(r.AllowedHosts, host) {
+			return common.ValidationError(fmt.Sprintf("host %s has a key, but is not an allowed host", host))
+		}
+
+		if !validDHChapKey(key.Key) {
+			return common.ValidationError(fmt.Sprintf("malformed dhchap key for host %s, expected DHHC-1:<hash>:<base64>:", host))
+		}
+
+		if key.ControllerKey != "" && !validDHChapKey(key.ControllerKey) {
+			return common.ValidationError(fmt.Sprintf("malformed dhchap controller key for host %s, expected DHHC-1:<hash>:<base64>:", host))
+		}
 	}
 
 	sort.Slice(r.Volumes, func(i, j int) bool {
@@ -322,5 +702,10 @@ func (r *ResourceConfig) Valid() error {
 		}
 	}
 
+	err := common.ValidNodePreferences(r.PreferredNodes, r.ForbiddenNodes)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,26 @@
+package prompt
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// ColorDiff colors the removed and added lines of a unified diff for display
+// on a terminal.
+func ColorDiff(diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			lines[i] = color.New(color.Bold).Sprint(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = color.CyanString("%s", line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = color.RedString("%s", line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = color.GreenString("%s", line)
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,131 @@
+package reactor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pelletier/go-toml"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// encode renders the config as TOML, without the header EncodeConfig adds,
+// so that two configs can be compared.
+func encode(cfg *PromoterConfig) (string, error) {
+	buffer := strings.Builder{}
+	encoder := toml.NewEncoder(&buffer).ArraysWithOneElementPerLine(true)
+
+	err := encoder.Encode(&Config{Promoter: []PromoterConfig{*cfg}})
+	if err != nil {
+		return "", fmt.Errorf("error encoding toml: %w", err)
+	}
+	return buffer.String(), nil
+}
+
+const (
+	redactedValue = "<redacted>"
+	changedValue  = "<redacted, changed>"
+)
+
+// maskSecrets returns copies of oldConfig and newConfig in which the resource
+// agent attributes named in secrets are replaced by placeholders. The
+// placeholder in the new config tells whether the value changed.
+func maskSecrets(oldConfig, newConfig *PromoterConfig, secrets []string) (*PromoterConfig, *PromoterConfig) {
+	if len(secrets) == 0 {
+		return oldConfig, newConfig
+	}
+
+	oldAgents := make(map[string]*ResourceAgent)
+	for _, rscCfg := range oldConfig.Resources {
+		for _, entry := range rscCfg.Start {
+			if agent, ok := entry.(*ResourceAgent); ok {
+				oldAgents[agent.Name] = agent
+			}
+		}
+	}
+
+	mask := func(cfg *PromoterConfig, isNew bool) *PromoterConfig {
+		masked := *cfg
+		masked.Resources = make(map[string]PromoterResourceConfig, len(cfg.Resources))
+		for name, rscCfg := range cfg.Resources {
+			start := make([]StartEntry, len(rscCfg.Start))
+			for i, entry := range rscCfg.Start {
+				agent, ok := entry.(*ResourceAgent)
+				if !ok {
+					start[i] = entry
+					continue
+				}
+
+				copied := *agent
+				copied.Attributes = make(map[string]string, len(agent.Attributes))
+				for key, value := range agent.Attributes {
+					copied.Attributes[key] = value
+				}
+				for _, key := range secrets {
+					value, ok := copied.Attributes[key]
+					if !ok {
+						continue
+					}
+					copied.Attributes[key] = redactedValue
+					if isNew {
+						old, ok := oldAgents[agent.Name]
+						if !ok || old.Attributes[key] != value {
+							copied.Attributes[key] = changedValue
+						}
+					}
+				}
+				start[i] = &copied
+			}
+			rscCfg.Start = start
+			masked.Resources[name] = rscCfg
+		}
+		return &masked
+	}
+
+	return mask(oldConfig, false), mask(newConfig, true)
+}
+
+// Diff renders the changes between oldConfig and newConfig line by line, with
+// removed lines prefixed by "-" and added lines by "+".
+// The values of resource agent attributes named in secrets are not shown,
+// only whether they changed. It returns an empty string if the configs are
+// equal.
+func Diff(oldConfig, newConfig *PromoterConfig, secrets ...string) (string, error) {
+	if cmp.Equal(oldConfig, newConfig) {
+		return "", nil
+	}
+
+	oldConfig, newConfig = maskSecrets(oldConfig, newConfig, secrets)
+
+	oldToml, err := encode(oldConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode old promoter config: %w", err)
+	}
+
+	newToml, err := encode(newConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode new promoter config: %w", err)
+	}
+
+	dmp := diffmatchpatch.New()
+	oldChars, newChars, lines := dmp.DiffLinesToChars(oldToml, newToml)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(oldChars, newChars, false), lines)
+
+	result := strings.Builder{}
+	result.WriteString("--- current\n+++ new\n")
+	for _, diff := range diffs {
+		prefix := " "
+		switch diff.Type {
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		}
+		for _, line := range strings.SplitAfter(diff.Text, "\n") {
+			if line != "" {
+				result.WriteString(prefix + line)
+			}
+		}
+	}
+	return result.String(), nil
+}
@@ -0,0 +1,70 @@
+package reactor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	config := func(ip string) *PromoterConfig {
+		return &PromoterConfig{
+			Resources: map[string]PromoterResourceConfig{
+				"example": {
+					Start: []StartEntry{
+						&ResourceAgent{Type: "ocf:heartbeat:IPaddr2", Name: "service_ip0", Attributes: map[string]string{"ip": ip, "cidr_netmask": "24"}},
+					},
+				},
+			},
+		}
+	}
+
+	diff, err := Diff(config("192.168.127.1"), config("192.168.127.1"))
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	diff, err = Diff(config("192.168.127.1"), config("192.168.127.2"))
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "--- current\n+++ new\n")
+	assert.Contains(t, diff, "\n-")
+	assert.Contains(t, diff, "192.168.127.1")
+	assert.Contains(t, diff, "\n+")
+	assert.Contains(t, diff, "192.168.127.2")
+	assert.NotContains(t, diff, "\x1b[")
+}
+
+func TestDiffSecrets(t *testing.T) {
+	t.Parallel()
+
+	config := func(user, password string) *PromoterConfig {
+		return &PromoterConfig{
+			Resources: map[string]PromoterResourceConfig{
+				"example": {
+					Start: []StartEntry{
+						&ResourceAgent{Type: "ocf:heartbeat:iSCSITarget", Name: "target", Attributes: map[string]string{"incoming_username": user, "incoming_password": password}},
+					},
+				},
+			},
+		}
+	}
+
+	oldConfig := config("user", "secret1")
+
+	diff, err := Diff(oldConfig, config("user2", "secret1"), "incoming_password")
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "user2")
+	assert.NotContains(t, diff, "secret1")
+	assert.NotContains(t, diff, changedValue)
+
+	diff, err = Diff(oldConfig, config("user", "secret2"), "incoming_password")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, diff)
+	assert.NotContains(t, diff, "secret1")
+	assert.NotContains(t, diff, "secret2")
+	assert.Contains(t, diff, changedValue)
+
+	// the configs themselves are left alone
+	assert.Equal(t, "secret1", oldConfig.Resources["example"].Start[0].(*ResourceAgent).Attributes["incoming_password"])
+}
@@ -29,6 +29,14 @@ type Config struct {
 // It stores fields specific to linstor-gateway.
 type PromoterMetadata struct {
 	LinstorGatewaySchemaVersion int `toml:"linstor-gateway-schema-version"`
+	// ExternalSecrets is set if the secrets of the resource are not part
+	// of the promoter config, but provisioned on the nodes separately.
+	ExternalSecrets bool `toml:"external-secrets,omitempty"`
+	// PreferredNodes and ForbiddenNodes are the node preferences as
+	// configured by the user. The order drbd-reactor uses is derived from
+	// them, see PromoterResourceConfig.PreferredNodes.
+	PreferredNodes []string `toml:"preferred-nodes,omitempty"`
+	ForbiddenNodes []string `toml:"forbidden-nodes,omitempty"`
 }
 
 // PromoterConfig is the configuration for drbd-reactors "promoter" plugin.
@@ -92,6 +100,7 @@ type PromoterResourceConfig struct {
 	OnDrbdDemoteFailure string       `toml:"on-drbd-demote-failure,omitempty"`
 	StopServicesOnExit  bool         `toml:"stop-services-on-exit,omitempty"`
 	TargetAs            string       `toml:"target-as,omitempty"`
+	PreferredNodes      []string     `toml:"preferred-nodes,omitempty"`
 }
 
 func (c *PromoterResourceConfig) UnmarshalTOML(data interface{}) error {
@@ -147,11 +156,24 @@ func (c *PromoterResourceConfig) UnmarshalTOML(data interface{}) error {
 			return fmt.Errorf("could not convert value %v to string (is type %T)", val, val)
 		}
 	}
+	if val, ok := d["preferred-nodes"]; ok {
+		nodes, nodesOk := val.([]interface{})
+		if !nodesOk {
+			return fmt.Errorf("could not convert value %v to slice (is type %T)", val, val)
+		}
+		for _, entry := range nodes {
+			node, ok := entry.(string)
+			if !ok {
+				return fmt.Errorf("could not convert value %v to string (is type %T)", entry, entry)
+			}
+			c.PreferredNodes = append(c.PreferredNodes, node)
+		}
+	}
 	return nil
 }
 
-// EnsureConfig ensures the given config is registered in LINSTOR and up-to-date.
-func EnsureConfig(ctx context.Context, cli *client.Client, cfg *PromoterConfig, id string) error {
+// EncodeConfig renders the given config as a drbd-reactor configuration file.
+func EncodeConfig(cfg *PromoterConfig) ([]byte, error) {
 	buffer := strings.Builder{}
 	buffer.WriteString("# Generated by LINSTOR Gateway at " + time.Now().String() + "\n")
 	buffer.WriteString("# DO NOT MODIFY!\n")
@@ -159,11 +181,37 @@ func EnsureConfig(ctx context.Context, cli *client.Client, cfg *PromoterConfig,
 
 	err := encoder.Encode(&Config{Promoter: []PromoterConfig{*cfg}})
 	if err != nil {
-		return fmt.Errorf("error encoding promoter config: %w", err)
+		return nil, fmt.Errorf("error encoding promoter config: %w", err)
+	}
+
+	return []byte(buffer.String()), nil
+}
+
+// DecodeConfig parses a drbd-reactor configuration file as written by
+// EncodeConfig.
+func DecodeConfig(content []byte) (*PromoterConfig, error) {
+	cfg := Config{}
+	err := toml.Unmarshal(content, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode promoter config: %w", err)
+	}
+
+	if len(cfg.Promoter) != 1 {
+		return nil, fmt.Errorf("expected exactly one promoter config, got %d", len(cfg.Promoter))
+	}
+
+	return &cfg.Promoter[0], nil
+}
+
+// EnsureConfig ensures the given config is registered in LINSTOR and up-to-date.
+func EnsureConfig(ctx context.Context, cli *client.Client, cfg *PromoterConfig, id string) error {
+	content, err := EncodeConfig(cfg)
+	if err != nil {
+		return err
 	}
 
 	path := ConfigPath(id)
-	err = cli.Controller.ModifyExternalFile(ctx, path, client.ExternalFile{Path: path, Content: []byte(buffer.String())})
+	err = cli.Controller.ModifyExternalFile(ctx, path, client.ExternalFile{Path: path, Content: content})
 	if err != nil {
 		return fmt.Errorf("error setting promoter config in linstor: %w", err)
 	}
@@ -248,6 +296,27 @@ func ListConfigs(ctx context.Context, cli *client.Client) ([]PromoterConfig, []s
 	return filterConfigs(files)
 }
 
+// ListConfigFiles fetches the raw configuration files of all promoters
+// registered with LINSTOR by LINSTOR Gateway.
+func ListConfigFiles(ctx context.Context, cli *client.Client) ([]client.ExternalFile, error) {
+	files, err := cli.Controller.GetExternalFiles(ctx, &client.ListOpts{Content: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch file list: %w", err)
+	}
+
+	result := make([]client.ExternalFile, 0, len(files))
+	for _, file := range files {
+		var name string
+		n, _ := fmt.Sscanf(file.Path, gatewayConfigPath, &name)
+		if n == 0 {
+			continue
+		}
+		result = append(result, file)
+	}
+
+	return result, nil
+}
+
 // FindConfig fetches the promoter config with the given id. It returns the
 // corresponding PromoterConfig as well as the path of the configuration file.
 //
@@ -286,3 +355,13 @@ func DeleteConfig(ctx context.Context, cli *client.Client, id string) error {
 func ConfigPath(id string) string {
 	return fmt.Sprintf(gatewayConfigPath, id)
 }
+
+// ConfigID is the inverse of ConfigPath. It returns "" if path is not the
+// path of a promoter config created by LINSTOR Gateway.
+func ConfigID(path string) string {
+	prefix, suffix, _ := strings.Cut(gatewayConfigPath, "%s")
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) || len(path) <= len(prefix)+len(suffix) {
+		return ""
+	}
+	return path[len(prefix) : len(path)-len(suffix)]
+}
@@ -146,6 +146,30 @@ func TestReactorConfig_UnmarshalText(t *testing.T) {
 				},
 			}},
 		},
+	}, {
+		name: "with preferred nodes",
+		cfg: `[[promoter]]
+[promoter.resources]
+  [promoter.resources.rsc1]
+    preferred-nodes = [ "node1", "node2" ]
+`,
+		expected: reactor.Config{
+			Promoter: []reactor.PromoterConfig{{
+				Resources: map[string]reactor.PromoterResourceConfig{
+					"rsc1": {
+						PreferredNodes: []string{"node1", "node2"},
+					},
+				},
+			}},
+		},
+	}, {
+		name: "unexpected preferred-nodes type",
+		cfg: `[[promoter]]
+[promoter.resources]
+  [promoter.resources.rsc1]
+    preferred-nodes = "node1"
+`,
+		wantErr: true,
 	}, {
 		name: "invalid ocf entry",
 		cfg: `[[promoter]]
@@ -70,3 +70,12 @@ func TestFilterConfigs(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigID(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "iscsi-target1", ConfigID(ConfigPath("iscsi-target1")))
+	assert.Equal(t, "", ConfigID(filepath.Join(promoterDir, "oops-not-the-right-pattern.toml")))
+	assert.Equal(t, "", ConfigID(ConfigPath("")))
+	assert.Equal(t, "", ConfigID("/some/other/file"))
+}
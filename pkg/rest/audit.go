@@ -0,0 +1,187 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/audit"
+)
+
+// maxRequestBodySize limits the body of every API request. Resource configs,
+// the largest bodies the API accepts, stay well below it.
+const maxRequestBodySize = 1 << 20
+
+// recordingBody keeps a copy of what is read from a request body, for the
+// audit log.
+type recordingBody struct {
+	io.ReadCloser
+	buf bytes.Buffer
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+// statusRecorder remembers the status code and error message of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	if s.status >= 400 && s.body.Len() < 4096 {
+		s.body.Write(b)
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed responses.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+type auditUserKey struct{}
+
+// noteAuditUser tells the audit middleware, which runs before the
+// authentication, who made the request.
+func noteAuditUser(ctx context.Context, user *User) {
+	if slot, ok := ctx.Value(auditUserKey{}).(**User); ok {
+		*slot = user
+	}
+}
+
+// auditMiddleware records every request that changes something in the audit
+// log, as well as every request that was rejected for missing or invalid
+// credentials. It also limits the size of all request bodies to
+// maxRequestBodySize.
+func (s *server) auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+		}
+
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+		// the body is recorded while the handler reads it, so that nothing
+		// is read before the request is authenticated
+		var recorded *recordingBody
+		if r.Body != nil && !readOnly {
+			recorded = &recordingBody{ReadCloser: r.Body}
+			r.Body = recorded
+		}
+
+		var user *User
+		r = r.WithContext(context.WithValue(r.Context(), auditUserKey{}, &user))
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if readOnly && rec.status != http.StatusUnauthorized {
+			return
+		}
+
+		var body []byte
+		if recorded != nil {
+			// record the part the handler did not read, unless the
+			// request was not authenticated
+			if rec.status != http.StatusUnauthorized {
+				_, _ = io.Copy(io.Discard, recorded)
+			}
+			body = recorded.buf.Bytes()
+		}
+
+		entry := audit.Entry{
+			Time:     start,
+			Remote:   r.RemoteAddr,
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Body:     audit.RedactBody(body),
+			Status:   rec.status,
+			Duration: time.Since(start),
+		}
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			entry.Remote = host
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				entry.Operation = r.Method + " " + tmpl
+			}
+		}
+		if user != nil {
+			entry.User = user.Name
+			entry.Role = user.Role.String()
+		} else if name, _, ok := r.BasicAuth(); ok {
+			// the user that tried to log in
+			entry.User = name
+		}
+		if entry.Status >= 400 {
+			var e Error
+			if err := json.Unmarshal(rec.body.Bytes(), &e); err == nil {
+				entry.Error = e.Message
+			} else {
+				entry.Error = http.StatusText(entry.Status)
+			}
+		}
+
+		s.audit.Record(entry)
+	})
+}
+
+func (s *server) AuditList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filter audit.Filter
+
+		query := r.URL.Query()
+		if since := query.Get("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				MustError(http.StatusBadRequest, w, "invalid since: %v", err)
+				return
+			}
+			filter.Since = t
+		}
+		if limit := query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 0 {
+				MustError(http.StatusBadRequest, w, "invalid limit %q", limit)
+				return
+			}
+			filter.Limit = n
+		}
+		filter.User = query.Get("user")
+
+		err := json.NewEncoder(w).Encode(s.audit.Recent(filter))
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
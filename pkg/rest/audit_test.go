@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/audit"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	t.Parallel()
+
+	auditLog, err := audit.New(audit.Config{})
+	require.NoError(t, err)
+
+	s := &server{router: mux.NewRouter(), audit: auditLog}
+	s.router.Use(s.auditMiddleware)
+	s.router.HandleFunc("/api/v2/iscsi", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}).Methods("GET", "POST")
+	s.router.HandleFunc("/api/v2/iscsi/{iqn}", func(w http.ResponseWriter, r *http.Request) {
+		MustError(http.StatusNotFound, w, "no resource found with iqn %s", mux.Vars(r)["iqn"])
+	}).Methods("DELETE")
+
+	do := func(method, path, body string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:4711"
+		s.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	do("GET", "/api/v2/iscsi", "")
+	do("POST", "/api/v2/iscsi", `{"iqn":"iqn.2019-08.com.linbit:example","password":"secret"}`)
+	do("DELETE", "/api/v2/iscsi/iqn.2019-08.com.linbit:example", "")
+	do("POST", "/api/v2/iscsi", `"`+strings.Repeat("x", maxRequestBodySize)+`"`)
+
+	entries := auditLog.Recent(audit.Filter{})
+	require.Len(t, entries, 3, "GET requests are not audited")
+
+	assert.Equal(t, "192.0.2.1", entries[0].Remote)
+	assert.Equal(t, "POST /api/v2/iscsi", entries[0].Operation)
+	assert.Equal(t, http.StatusCreated, entries[0].Status)
+	assert.JSONEq(t, `{"iqn":"iqn.2019-08.com.linbit:example","password":"REDACTED"}`, string(entries[0].Body))
+	assert.Empty(t, entries[0].Error)
+
+	assert.Equal(t, "DELETE /api/v2/iscsi/{iqn}", entries[1].Operation)
+	assert.Equal(t, "/api/v2/iscsi/iqn.2019-08.com.linbit:example", entries[1].Path)
+	assert.Equal(t, http.StatusNotFound, entries[1].Status)
+	assert.Equal(t, "no resource found with iqn iqn.2019-08.com.linbit:example", entries[1].Error)
+
+	// only up to the limit is read
+	assert.Equal(t, fmt.Sprintf(`"%d bytes, not recorded"`, maxRequestBodySize), string(entries[2].Body))
+}
+
+func TestAuditFailedLogin(t *testing.T) {
+	t.Parallel()
+
+	auditLog, err := audit.New(audit.Config{})
+	require.NoError(t, err)
+	auth, err := newAuthenticator(AuthConfig{Tokens: []TokenConfig{{Name: "ci", Token: "secret", Role: "operator"}}})
+	require.NoError(t, err)
+
+	s := &server{router: mux.NewRouter(), audit: auditLog, auth: auth}
+	s.router.Use(s.auditMiddleware)
+	s.router.Use(s.auth.middleware)
+	s.router.HandleFunc("/api/v2/iscsi", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET", "POST")
+
+	do := func(method, token string) {
+		req := httptest.NewRequest(method, "/api/v2/iscsi", strings.NewReader(`{"iqn":"iqn.2019-08.com.linbit:example"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		s.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	do("GET", "wrong")
+	do("GET", "secret")
+	do("POST", "secret")
+	do("POST", "wrong")
+
+	entries := auditLog.Recent(audit.Filter{})
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "GET", entries[0].Method)
+	assert.Equal(t, http.StatusUnauthorized, entries[0].Status)
+	assert.Empty(t, entries[0].User)
+
+	assert.Equal(t, "POST", entries[1].Method)
+	assert.Equal(t, "ci", entries[1].User)
+	assert.Equal(t, "operator", entries[1].Role)
+	assert.NotEmpty(t, entries[1].Body)
+
+	// the body of unauthenticated requests is never read
+	assert.Equal(t, http.StatusUnauthorized, entries[2].Status)
+	assert.Empty(t, entries[2].Body)
+}
@@ -0,0 +1,327 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role is the level of access granted to an authenticated user.
+type Role int
+
+const (
+	// RoleReadOnly may only look at resources.
+	RoleReadOnly Role = iota
+	// RoleOperator may additionally create, change, start and stop
+	// resources.
+	RoleOperator
+	// RoleAdmin may additionally delete resources and manage nodes.
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleReadOnly:
+		return "read-only"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+}
+
+// ParseRole converts the name of a role, as used in the config file, to a
+// Role.
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "read-only":
+		return RoleReadOnly, nil
+	case "operator":
+		return RoleOperator, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return 0, fmt.Errorf("unknown role %q (expected \"read-only\", \"operator\", or \"admin\")", s)
+	}
+}
+
+// TokenConfig is a static bearer token from the config file.
+type TokenConfig struct {
+	// Name identifies the token in logs.
+	Name  string `mapstructure:"name"`
+	Token string `mapstructure:"token"`
+	Role  string `mapstructure:"role"`
+}
+
+// AuthConfig is the "[server.auth]" section of the config file. If neither
+// tokens, an htpasswd file nor client certificate roles are configured,
+// authentication is disabled and every request is allowed.
+type AuthConfig struct {
+	Tokens []TokenConfig `mapstructure:"tokens"`
+	// Htpasswd is the path of an htpasswd file with bcrypt hashed
+	// passwords for HTTP basic authentication.
+	Htpasswd string `mapstructure:"htpasswd"`
+	// UserRoles maps htpasswd users to their role. Users that are not
+	// listed get DefaultRole.
+	UserRoles   map[string]string `mapstructure:"user_roles"`
+	DefaultRole string            `mapstructure:"default_role"`
+	// ClientCertRoles maps the common names of client certificates to
+	// their role. Client certificates are only used with mutual TLS.
+	ClientCertRoles map[string]string `mapstructure:"client_cert_roles"`
+}
+
+// User is the authenticated originator of a request.
+type User struct {
+	Name string
+	Role Role
+}
+
+type userKey struct{}
+
+// UserFromContext returns the user that made the request, or nil if
+// authentication is disabled.
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userKey{}).(*User)
+	return user
+}
+
+type token struct {
+	secret []byte
+	user   User
+}
+
+// authenticator checks the credentials of requests against the configured
+// tokens and htpasswd users.
+type authenticator struct {
+	tokens      []token
+	htpasswd    map[string][]byte
+	userRoles   map[string]Role
+	defaultRole Role
+	certRoles   map[string]Role
+}
+
+// newAuthenticator builds an authenticator from the config. It returns nil
+// if authentication is not configured.
+func newAuthenticator(cfg AuthConfig) (*authenticator, error) {
+	if len(cfg.Tokens) == 0 && cfg.Htpasswd == "" && len(cfg.ClientCertRoles) == 0 {
+		return nil, nil
+	}
+
+	a := &authenticator{userRoles: make(map[string]Role), certRoles: make(map[string]Role)}
+
+	for i, t := range cfg.Tokens {
+		if t.Token == "" {
+			return nil, fmt.Errorf("token %d: missing token", i+1)
+		}
+
+		role, err := ParseRole(t.Role)
+		if err != nil {
+			return nil, fmt.Errorf("token %d: %w", i+1, err)
+		}
+
+		name := t.Name
+		if name == "" {
+			name = fmt.Sprintf("token-%d", i+1)
+		}
+
+		a.tokens = append(a.tokens, token{secret: []byte(t.Token), user: User{Name: name, Role: role}})
+	}
+
+	if cfg.Htpasswd != "" {
+		users, err := readHtpasswd(cfg.Htpasswd)
+		if err != nil {
+			return nil, err
+		}
+		a.htpasswd = users
+
+		for user, r := range cfg.UserRoles {
+			role, err := ParseRole(r)
+			if err != nil {
+				return nil, fmt.Errorf("user %s: %w", user, err)
+			}
+			a.userRoles[user] = role
+		}
+
+		if cfg.DefaultRole != "" {
+			a.defaultRole, err = ParseRole(cfg.DefaultRole)
+			if err != nil {
+				return nil, fmt.Errorf("default role: %w", err)
+			}
+		}
+	}
+
+	for cn, r := range cfg.ClientCertRoles {
+		role, err := ParseRole(r)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate %s: %w", cn, err)
+		}
+		a.certRoles[cn] = role
+	}
+
+	return a, nil
+}
+
+// readHtpasswd reads an htpasswd file. Only bcrypt hashes are supported, as
+// the other formats are not considered secure.
+func readHtpasswd(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open htpasswd file: %w", err)
+	}
+	defer f.Close()
+
+	users := make(map[string][]byte)
+	scanner := bufio.NewScanner(f)
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%s:%d: expected USER:HASH", path, lineNr)
+		}
+
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("%s:%d: password of user %s is not hashed with bcrypt (use htpasswd -B)", path, lineNr, user)
+		}
+
+		users[user] = []byte(hash)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file: %w", err)
+	}
+
+	return users, nil
+}
+
+// authenticate returns the user the request belongs to, or nil if the
+// credentials are missing or wrong.
+func (a *authenticator) authenticate(r *http.Request) *User {
+	header := r.Header.Get("Authorization")
+
+	if secret, ok := strings.CutPrefix(header, "Bearer "); ok {
+		var found *User
+		for i := range a.tokens {
+			// compare against every token, so that the timing does not
+			// reveal which one matched
+			if subtle.ConstantTimeCompare(a.tokens[i].secret, []byte(secret)) == 1 {
+				found = &a.tokens[i].user
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		user := *found
+		return &user
+	}
+
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return a.authenticateCert(r)
+	}
+
+	if a.htpasswd == nil {
+		return nil
+	}
+
+	hash, ok := a.htpasswd[name]
+	if !ok {
+		return nil
+	}
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return nil
+	}
+
+	role, ok := a.userRoles[name]
+	if !ok {
+		role = a.defaultRole
+	}
+
+	return &User{Name: name, Role: role}
+}
+
+// authenticateCert returns the user of the verified client certificate, if
+// its common name has a role.
+func (a *authenticator) authenticateCert(r *http.Request) *User {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	role, ok := a.certRoles[cn]
+	if !ok {
+		return nil
+	}
+
+	return &User{Name: cn, Role: role}
+}
+
+// middleware rejects requests without valid credentials and stores the user
+// in the request context.
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// let CORS preflight requests through, they never carry credentials
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user := a.authenticate(r)
+		if user == nil {
+			log.WithFields(log.Fields{"remote": r.RemoteAddr, "path": r.URL.Path}).Warn("rejected unauthenticated request")
+			if a.htpasswd != nil {
+				w.Header().Add("WWW-Authenticate", `Basic realm="linstor-gateway"`)
+			}
+			w.Header().Add("WWW-Authenticate", `Bearer realm="linstor-gateway"`)
+			MustError(http.StatusUnauthorized, w, "missing or invalid credentials")
+			return
+		}
+
+		noteAuditUser(r.Context(), user)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// withRole only lets requests of users with at least the given role through.
+// If authentication is disabled, all requests are let through.
+func withRole(role Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := UserFromContext(r.Context())
+		if user != nil && user.Role < role {
+			MustError(http.StatusForbidden, w, "%s requires the %s role, but %s has the %s role", r.URL.Path, role, user.Name, user.Role)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// showSecrets reports whether the request asked for secrets to be included
+// in the response with "?show_secrets=true". Only admins may see secrets, so
+// for other users a 403 is sent and ok is false.
+func showSecrets(w http.ResponseWriter, r *http.Request) (show bool, ok bool) {
+	if r.URL.Query().Get("show_secrets") != "true" {
+		return false, true
+	}
+
+	user := UserFromContext(r.Context())
+	if user != nil && user.Role < RoleAdmin {
+		MustError(http.StatusForbidden, w, "show_secrets requires the %s role, but %s has the %s role", RoleAdmin, user.Name, user.Role)
+		return false, false
+	}
+
+	return true, true
+}
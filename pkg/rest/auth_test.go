@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestParseRole(t *testing.T) {
+	t.Parallel()
+
+	for _, role := range []Role{RoleReadOnly, RoleOperator, RoleAdmin} {
+		parsed, err := ParseRole(role.String())
+		assert.NoError(t, err)
+		assert.Equal(t, role, parsed)
+	}
+
+	_, err := ParseRole("root")
+	assert.Error(t, err)
+}
+
+func TestAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	err = os.WriteFile(htpasswd, []byte("# users\nalice:"+string(hash)+"\nbob:"+string(hash)+"\n"), 0600)
+	require.NoError(t, err)
+
+	auth, err := newAuthenticator(AuthConfig{
+		Tokens: []TokenConfig{
+			{Name: "monitoring", Token: "read-token", Role: "read-only"},
+			{Token: "admin-token", Role: "admin"},
+		},
+		Htpasswd:    htpasswd,
+		UserRoles:   map[string]string{"alice": "operator"},
+		DefaultRole: "read-only",
+	})
+	require.NoError(t, err)
+
+	handler := auth.middleware(withRole(RoleOperator, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	testcases := []struct {
+		name     string
+		setup    func(r *http.Request)
+		expected int
+	}{{
+		name:     "no credentials",
+		setup:    func(r *http.Request) {},
+		expected: http.StatusUnauthorized,
+	}, {
+		name:     "wrong token",
+		setup:    func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") },
+		expected: http.StatusUnauthorized,
+	}, {
+		name:     "token without role",
+		setup:    func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-token") },
+		expected: http.StatusForbidden,
+	}, {
+		name:     "token with role",
+		setup:    func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") },
+		expected: http.StatusNoContent,
+	}, {
+		name:     "wrong password",
+		setup:    func(r *http.Request) { r.SetBasicAuth("alice", "wrong") },
+		expected: http.StatusUnauthorized,
+	}, {
+		name:     "unknown user",
+		setup:    func(r *http.Request) { r.SetBasicAuth("mallory", "secret") },
+		expected: http.StatusUnauthorized,
+	}, {
+		name:     "user with role",
+		setup:    func(r *http.Request) { r.SetBasicAuth("alice", "secret") },
+		expected: http.StatusNoContent,
+	}, {
+		name:     "user with default role",
+		setup:    func(r *http.Request) { r.SetBasicAuth("bob", "secret") },
+		expected: http.StatusForbidden,
+	}}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/iscsi", nil)
+			tcase.setup(req)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tcase.expected, rec.Code)
+		})
+	}
+}
+
+func TestAuthenticatorDisabled(t *testing.T) {
+	t.Parallel()
+
+	auth, err := newAuthenticator(AuthConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, auth)
+
+	// without authentication, withRole lets everything through
+	rec := httptest.NewRecorder()
+	withRole(RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})(rec, httptest.NewRequest(http.MethodDelete, "/api/v2/iscsi/x", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestReadHtpasswd(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	err := os.WriteFile(path, []byte("alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0600)
+	require.NoError(t, err)
+
+	_, err = readHtpasswd(path)
+	assert.Error(t, err)
+}
+
+func TestShowSecrets(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		user     *User
+		query    string
+		show     bool
+		expected int
+	}{
+		{name: "not requested", user: &User{Name: "monitoring", Role: RoleReadOnly}, expected: http.StatusOK},
+		{name: "read-only", user: &User{Name: "monitoring", Role: RoleReadOnly}, query: "?show_secrets=true", expected: http.StatusForbidden},
+		{name: "operator", user: &User{Name: "ci", Role: RoleOperator}, query: "?show_secrets=true", expected: http.StatusForbidden},
+		{name: "admin", user: &User{Name: "root", Role: RoleAdmin}, query: "?show_secrets=true", show: true, expected: http.StatusOK},
+		{name: "no authentication", query: "?show_secrets=true", show: true, expected: http.StatusOK},
+	}
+
+	for _, tcase := range testcases {
+		tcase := tcase
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest("GET", "/api/v2/iscsi/iqn.2019-08.com.linbit:example"+tcase.query, nil)
+			if tcase.user != nil {
+				r = r.WithContext(context.WithValue(r.Context(), userKey{}, tcase.user))
+			}
+			w := httptest.NewRecorder()
+
+			show, ok := showSecrets(w, r)
+			assert.Equal(t, tcase.show, show)
+			assert.Equal(t, tcase.expected == http.StatusOK, ok)
+			assert.Equal(t, tcase.expected, w.Code)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/events"
+)
+
+// eventsKeepalive is the time after which an idle event stream gets a
+// comment, so that proxies do not close the connection.
+const eventsKeepalive = 30 * time.Second
+
+// resourceStatus returns the status of all gateway resources. It is the
+// source for the event watcher.
+func (s *server) resourceStatus(ctx context.Context) (map[events.Key]common.ResourceStatus, error) {
+	result := make(map[events.Key]common.ResourceStatus)
+
+	iscsis, err := s.iscsi.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list iSCSI resources: %w", err)
+	}
+	for _, rsc := range iscsis {
+		result[events.Key{Kind: "iscsi", Name: rsc.IQN.String()}] = rsc.Status
+	}
+
+	nfss, err := s.nfs.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NFS resources: %w", err)
+	}
+	for _, rsc := range nfss {
+		result[events.Key{Kind: "nfs", Name: rsc.Name}] = rsc.Status
+	}
+
+	nvmes, err := s.nvmeof.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NVMe-oF resources: %w", err)
+	}
+	for _, rsc := range nvmes {
+		result[events.Key{Kind: "nvme-of", Name: rsc.NQN.String()}] = rsc.Status
+	}
+
+	return result, nil
+}
+
+// Events streams changes of the gateway resources as server-sent events.
+func (s *server) Events() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+
+		ch, cancel := s.events.Subscribe()
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			log.WithError(err).Warn("event stream not supported by connection")
+			return
+		}
+
+		keepalive := time.NewTicker(eventsKeepalive)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepalive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+			case event, ok := <-ch:
+				if !ok {
+					// dropped by the watcher, the client has to reconnect
+					return
+				}
+				b, err := json.Marshal(event)
+				if err != nil {
+					log.WithError(err).Warn("failed to encode event")
+					continue
+				}
+				_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, b)
+				if err != nil {
+					return
+				}
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/events"
+)
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	key := events.Key{Kind: "nfs", Name: "export"}
+	var mu sync.Mutex
+	status := map[events.Key]common.ResourceStatus{}
+	source := func(ctx context.Context) (map[events.Key]common.ResourceStatus, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return status, nil
+	}
+
+	s := &server{events: events.NewWatcher(source, 10*time.Millisecond)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.events.Run(ctx)
+
+	// the metrics middleware wraps the response writer, which must not
+	// prevent flushing
+	srv := httptest.NewServer(metricsMiddleware(s.Events()))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	status = map[events.Key]common.ResourceStatus{key: {Service: common.ServiceStateStarted, Primary: "node1"}}
+	mu.Unlock()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	var received []string
+	for len(received) < 3 {
+		select {
+		case line := <-lines:
+			received = append(received, line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("incomplete event: %v", received)
+		}
+	}
+
+	assert.Equal(t, "id: 1", received[0])
+	assert.Equal(t, "event: resource-created", received[1])
+	require.True(t, strings.HasPrefix(received[2], "data: "))
+
+	var event events.Event
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(received[2], "data: ")), &event))
+	assert.Equal(t, key, event.Key)
+	assert.Equal(t, "node1", event.Status.Primary)
+}
@@ -0,0 +1,112 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+	"github.com/LINBIT/linstor-gateway/pkg/manifest"
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+func (s *server) Export() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		show, ok := showSecrets(w, r)
+		if !ok {
+			return
+		}
+		redact := !show
+
+		archive := &manifest.Archive{
+			Version:  manifest.ArchiveVersion,
+			Created:  time.Now().UTC(),
+			Redacted: redact,
+		}
+
+		var err error
+		archive.ISCSI, err = s.iscsi.List(ctx)
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to list iSCSI targets: %v", err)
+			return
+		}
+
+		archive.NFS, err = s.nfs.List(ctx)
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to list NFS exports: %v", err)
+			return
+		}
+
+		archive.NVMeoF, err = s.nvmeof.List(ctx)
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to list NVMe-oF targets: %v", err)
+			return
+		}
+
+		files, err := reactor.ListConfigFiles(ctx, s.linstor.Client)
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to list promoter configs: %v", err)
+			return
+		}
+
+		for _, file := range files {
+			content := file.Content
+			if redact {
+				cfg, err := reactor.DecodeConfig(content)
+				if err != nil {
+					MustError(http.StatusInternalServerError, w, "failed to parse promoter config %s: %v", file.Path, err)
+					return
+				}
+
+				redactedISCSI := iscsi.RedactPromoter(cfg)
+				redactedNVMeoF := nvmeof.RedactPromoter(cfg)
+				if redactedISCSI || redactedNVMeoF {
+					content, err = reactor.EncodeConfig(cfg)
+					if err != nil {
+						MustError(http.StatusInternalServerError, w, "failed to encode promoter config %s: %v", file.Path, err)
+						return
+					}
+				}
+			}
+
+			archive.Configs = append(archive.Configs, manifest.ConfigFile{Path: file.Path, Content: string(content)})
+		}
+
+		if redact {
+			for _, target := range archive.ISCSI {
+				target.Redact()
+			}
+			for _, target := range archive.NVMeoF {
+				target.Redact()
+			}
+		}
+
+		// the cluster private volume is created along with every resource
+		archive.StripClusterPrivateVolumes()
+
+		// the status is not part of the configuration
+		for _, rsc := range archive.ISCSI {
+			rsc.Status = common.ResourceStatus{}
+		}
+		for _, rsc := range archive.NFS {
+			rsc.Status = common.ResourceStatus{}
+		}
+		for _, rsc := range archive.NVMeoF {
+			rsc.Status = common.ResourceStatus{}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+
+		err = enc.Encode(archive)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
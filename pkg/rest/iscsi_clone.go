@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+)
+
+// ISCSIClone creates a new iSCSI target from a snapshot of an existing one.
+// The snapshot is selected via the "snapshot" query parameter; if it is not
+// given, a new snapshot is taken.
+func (s *server) ISCSIClone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		var rsc iscsi.ResourceConfig
+		err = json.NewDecoder(r.Body).Decode(&rsc)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		snapshot := r.URL.Query().Get("snapshot")
+
+		result, err := s.iscsi.Clone(r.Context(), iqn, snapshot, &rsc)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for iqn %s", snapshot, iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to clone iscsi resource: %v", err)
+			return
+		}
+
+		if result == nil {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+
+		result.Redact()
+
+		w.Header().Add("Location", fmt.Sprintf("../%s", result.IQN))
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
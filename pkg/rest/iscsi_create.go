@@ -27,6 +27,8 @@ func (s *server) ISCSICreate() http.HandlerFunc {
 			return
 		}
 
+		result.Redact()
+
 		writer.Header().Add("Location", fmt.Sprintf("./iscsi/%s", result.IQN))
 		writer.WriteHeader(http.StatusCreated)
 		encoder := json.NewEncoder(writer)
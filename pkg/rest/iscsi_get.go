@@ -19,6 +19,12 @@ func (s *server) ISCSIGet(all bool) http.HandlerFunc {
 			return
 		}
 
+		// secrets are only returned on explicit request
+		show, ok := showSecrets(w, r)
+		if !ok {
+			return
+		}
+
 		cfg, err := s.iscsi.Get(r.Context(), iqn)
 		if err != nil {
 			MustError(http.StatusInternalServerError, w, "failed to fetch resource status: %v", err)
@@ -31,6 +37,10 @@ func (s *server) ISCSIGet(all bool) http.HandlerFunc {
 		}
 
 		if all {
+			if !show {
+				cfg.Redact()
+			}
+
 			w.WriteHeader(http.StatusOK)
 			enc := json.NewEncoder(w)
 
@@ -16,8 +16,10 @@ func (s *server) ISCSIList() http.HandlerFunc {
 		}
 
 		for i := range targets {
+			targets[i].Redact()
 			targets[i].Username = ""
-			targets[i].Password = ""
+			targets[i].MutualUsername = ""
+			targets[i].InitiatorCredentials = nil
 		}
 
 		w.WriteHeader(http.StatusOK)
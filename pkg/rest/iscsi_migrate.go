@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+)
+
+func (s *server) ISCSIMigrate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		resourceTimeout, err := parseResourceTimeout(r)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid resource_timeout: %v", err)
+			return
+		}
+
+		var migration common.Migration
+		err = json.NewDecoder(r.Body).Decode(&migration)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		if migration.Node == "" {
+			MustError(http.StatusBadRequest, w, "missing node")
+			return
+		}
+
+		cfg, err := s.iscsi.Migrate(r.Context(), iqn, migration.Node, resourceTimeout)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to migrate target: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+
+		cfg.Redact()
+
+		w.Header().Add("Location", "./")
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
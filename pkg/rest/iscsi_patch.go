@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+)
+
+func (s *server) ISCSIPatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "malformed iqn: %v", err)
+			return
+		}
+
+		var patch iscsi.ResourcePatch
+		err = json.NewDecoder(r.Body).Decode(&patch)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		cfg, err := s.iscsi.Patch(r.Context(), iqn, &patch)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to update target: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, w, "no resource found for iqn %s", iqn)
+			return
+		}
+
+		cfg.Redact()
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
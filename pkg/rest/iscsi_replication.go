@@ -0,0 +1,188 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+)
+
+func (s *server) ISCSIGetReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		replication, err := s.iscsi.Replication(r.Context(), iqn)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to get replication settings: %v", err)
+			return
+		}
+
+		if replication == nil {
+			MustError(http.StatusNotFound, w, "no replication set up for iqn %s", iqn)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(replication)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) ISCSISetReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		var replication common.Replication
+		err = json.NewDecoder(r.Body).Decode(&replication)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		err = s.iscsi.SetReplication(r.Context(), iqn, replication)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to set up replication: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(replication)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) ISCSIDeleteReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		err = s.iscsi.DeleteReplication(r.Context(), iqn)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to delete replication: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(struct{}{})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) ISCSIShipSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		snapshot, err := s.iscsi.ShipSnapshot(r.Context(), iqn)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to ship snapshot: %v", err)
+			return
+		}
+
+		// shipping happens in the background
+		w.WriteHeader(http.StatusAccepted)
+		err = json.NewEncoder(w).Encode(common.Snapshot{Name: snapshot})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+// ISCSIPromoteDR brings up an iSCSI target from a replica that was shipped to
+// this cluster. The snapshot is selected via the "snapshot" query parameter;
+// if it is not given, the latest one is used.
+func (s *server) ISCSIPromoteDR() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		var rsc iscsi.ResourceConfig
+		err = json.NewDecoder(r.Body).Decode(&rsc)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		snapshot := r.URL.Query().Get("snapshot")
+
+		result, err := s.iscsi.PromoteDR(r.Context(), iqn, snapshot, &rsc)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for iqn %s", snapshot, iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to promote iscsi resource: %v", err)
+			return
+		}
+
+		if result == nil {
+			MustError(http.StatusNotFound, w, "no replica of iqn %s found", iqn)
+			return
+		}
+
+		result.Redact()
+
+		w.Header().Add("Location", fmt.Sprintf("../%s", result.IQN))
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+)
+
+func (s *server) ISCSIGetSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		schedule, err := s.iscsi.SnapshotSchedule(r.Context(), iqn)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to get snapshot schedule: %v", err)
+			return
+		}
+
+		if schedule == nil {
+			MustError(http.StatusNotFound, w, "no snapshot schedule set for iqn %s", iqn)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(schedule)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) ISCSISetSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		var schedule common.SnapshotSchedule
+		err = json.NewDecoder(r.Body).Decode(&schedule)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		err = s.iscsi.SetSnapshotSchedule(r.Context(), iqn, schedule)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to set snapshot schedule: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(schedule)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) ISCSIDeleteSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		err = s.iscsi.DeleteSnapshotSchedule(r.Context(), iqn)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to delete snapshot schedule: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(struct{}{})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+)
+
+func (s *server) ISCSIListSnapshots() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		snaps, err := s.iscsi.ListSnapshots(r.Context(), iqn)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to list snapshots: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(snaps)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) ISCSICreateSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		var snap common.Snapshot
+		err = json.NewDecoder(r.Body).Decode(&snap)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		created, err := s.iscsi.CreateSnapshot(r.Context(), iqn, snap.Name)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to create snapshot: %v", err)
+			return
+		}
+
+		w.Header().Add("Location", r.RequestURI+"/"+created.Name)
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(created)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) ISCSIDeleteSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		snapshot := mux.Vars(r)["snapshot"]
+
+		err = s.iscsi.DeleteSnapshot(r.Context(), iqn, snapshot)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for iqn %s", snapshot, iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to delete snapshot: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(struct{}{})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) ISCSIRollbackSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid iqn: %v", err)
+			return
+		}
+
+		snapshot := mux.Vars(r)["snapshot"]
+
+		resourceTimeout, err := parseResourceTimeout(r)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid resource_timeout: %v", err)
+			return
+		}
+
+		cfg, err := s.iscsi.RollbackSnapshot(r.Context(), iqn, snapshot, resourceTimeout)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for iqn %s", snapshot, iqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to roll back snapshot: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, w, "no resource with iqn %s found", iqn)
+			return
+		}
+
+		cfg.Redact()
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -36,6 +36,8 @@ func (s *server) ISCSIStart() http.HandlerFunc {
 			return
 		}
 
+		cfg.Redact()
+
 		w.Header().Add("Location", "./")
 		w.WriteHeader(http.StatusOK)
 		err = json.NewEncoder(w).Encode(cfg)
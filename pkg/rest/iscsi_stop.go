@@ -37,6 +37,8 @@ func (s *server) ISCSIStop() http.HandlerFunc {
 			return
 		}
 
+		cfg.Redact()
+
 		w.Header().Add("Location", "./")
 		w.WriteHeader(http.StatusOK)
 		err = json.NewEncoder(w).Encode(cfg)
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+)
+
+func (s *server) ISCSIUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iqn, err := iscsi.NewIqn(mux.Vars(r)["iqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "malformed iqn: %v", err)
+			return
+		}
+
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid dry_run: %v", err)
+			return
+		}
+
+		var rsc iscsi.ResourceConfig
+		err = json.NewDecoder(r.Body).Decode(&rsc)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		if rsc.IQN.String() != iqn.String() {
+			MustError(http.StatusBadRequest, w, "iqn %s in request body does not match %s", rsc.IQN, iqn)
+			return
+		}
+
+		result, err := s.iscsi.Update(r.Context(), &rsc, dryRun)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to update target: %v", err)
+			return
+		}
+
+		if result == nil {
+			MustError(http.StatusNotFound, w, "no resource found for iqn %s", iqn)
+			return
+		}
+
+		result.Config.Redact()
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+// parseDryRun returns the value of the "dry_run" query parameter, which
+// defaults to false.
+func parseDryRun(r *http.Request) (bool, error) {
+	dryRunStr := r.URL.Query().Get("dry_run")
+	if dryRunStr == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(dryRunStr)
+}
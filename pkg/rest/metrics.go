@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/metrics"
+)
+
+// metricsTimeout is the time the resource metrics may take to collect.
+const metricsTimeout = 10 * time.Second
+
+var (
+	resourceStates = []common.ResourceState{common.Unknown, common.ResourceStateOK, common.ResourceStateDegraded, common.ResourceStateBad}
+	serviceStates  = []common.ServiceState{common.ServiceStateStopped, common.ServiceStateStarted}
+
+	resourceStateDesc = prometheus.NewDesc("linstor_gateway_resource_state",
+		"State of the LINSTOR resource behind a gateway resource, 1 for the current state.",
+		[]string{"type", "name", "state"}, nil)
+	serviceStateDesc = prometheus.NewDesc("linstor_gateway_service_state",
+		"State of the service of a gateway resource, 1 for the current state.",
+		[]string{"type", "name", "state"}, nil)
+	primaryDesc = prometheus.NewDesc("linstor_gateway_resource_primary",
+		"Node a gateway resource is currently running on.",
+		[]string{"type", "name", "node"}, nil)
+	volumeStateDesc = prometheus.NewDesc("linstor_gateway_volume_state",
+		"State of a volume of a gateway resource, 1 for the current state.",
+		[]string{"type", "name", "volume", "state"}, nil)
+)
+
+// resourceCollector reports the status of all gateway resources. The status
+// is shared with the event watcher, so scrapes that come in quicker than the
+// watcher interval do not list all resources again.
+type resourceCollector struct {
+	s *server
+}
+
+func (c resourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resourceStateDesc
+	ch <- serviceStateDesc
+	ch <- primaryDesc
+	ch <- volumeStateDesc
+}
+
+func (c resourceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsTimeout)
+	defer cancel()
+
+	current, err := c.s.events.Current(ctx)
+	if err != nil {
+		log.WithError(err).Warn("failed to collect resource metrics")
+		return
+	}
+
+	for key, status := range current {
+		status := status
+		collectStatus(ch, key.Kind, key.Name, &status)
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func collectStatus(ch chan<- prometheus.Metric, kind, name string, status *common.ResourceStatus) {
+	for _, state := range resourceStates {
+		ch <- prometheus.MustNewConstMetric(resourceStateDesc, prometheus.GaugeValue, boolValue(status.State == state), kind, name, state.String())
+	}
+
+	for _, state := range serviceStates {
+		ch <- prometheus.MustNewConstMetric(serviceStateDesc, prometheus.GaugeValue, boolValue(status.Service == state), kind, name, state.String())
+	}
+
+	if status.Primary != "" {
+		ch <- prometheus.MustNewConstMetric(primaryDesc, prometheus.GaugeValue, 1, kind, name, status.Primary)
+	}
+
+	for _, vol := range status.Volumes {
+		for _, state := range resourceStates {
+			ch <- prometheus.MustNewConstMetric(volumeStateDesc, prometheus.GaugeValue, boolValue(vol.State == state), kind, name, strconv.Itoa(vol.Number), state.String())
+		}
+	}
+}
+
+// metricsMiddleware counts the requests to every route and measures how long
+// they take.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(status)).Inc()
+		metrics.HTTPDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
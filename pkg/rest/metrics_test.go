@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+// statusCollector reports a fixed status, in place of resourceCollector.
+type statusCollector struct {
+	status common.ResourceStatus
+}
+
+func (c statusCollector) Describe(ch chan<- *prometheus.Desc) {
+	resourceCollector{}.Describe(ch)
+}
+
+func (c statusCollector) Collect(ch chan<- prometheus.Metric) {
+	collectStatus(ch, "iscsi", "iqn.2019-08.com.linbit:example", &c.status)
+}
+
+func TestCollectStatus(t *testing.T) {
+	t.Parallel()
+
+	c := statusCollector{status: common.ResourceStatus{
+		State:   common.ResourceStateDegraded,
+		Service: common.ServiceStateStarted,
+		Primary: "node1",
+		Volumes: []common.VolumeState{{Number: 1, State: common.ResourceStateOK}},
+	}}
+
+	expected := `
+# HELP linstor_gateway_resource_primary Node a gateway resource is currently running on.
+# TYPE linstor_gateway_resource_primary gauge
+linstor_gateway_resource_primary{name="iqn.2019-08.com.linbit:example",node="node1",type="iscsi"} 1
+# HELP linstor_gateway_resource_state State of the LINSTOR resource behind a gateway resource, 1 for the current state.
+# TYPE linstor_gateway_resource_state gauge
+linstor_gateway_resource_state{name="iqn.2019-08.com.linbit:example",state="Bad",type="iscsi"} 0
+linstor_gateway_resource_state{name="iqn.2019-08.com.linbit:example",state="Degraded",type="iscsi"} 1
+linstor_gateway_resource_state{name="iqn.2019-08.com.linbit:example",state="OK",type="iscsi"} 0
+linstor_gateway_resource_state{name="iqn.2019-08.com.linbit:example",state="Unknown",type="iscsi"} 0
+# HELP linstor_gateway_service_state State of the service of a gateway resource, 1 for the current state.
+# TYPE linstor_gateway_service_state gauge
+linstor_gateway_service_state{name="iqn.2019-08.com.linbit:example",state="Started",type="iscsi"} 1
+linstor_gateway_service_state{name="iqn.2019-08.com.linbit:example",state="Stopped",type="iscsi"} 0
+# HELP linstor_gateway_volume_state State of a volume of a gateway resource, 1 for the current state.
+# TYPE linstor_gateway_volume_state gauge
+linstor_gateway_volume_state{name="iqn.2019-08.com.linbit:example",state="Bad",type="iscsi",volume="1"} 0
+linstor_gateway_volume_state{name="iqn.2019-08.com.linbit:example",state="Degraded",type="iscsi",volume="1"} 0
+linstor_gateway_volume_state{name="iqn.2019-08.com.linbit:example",state="OK",type="iscsi",volume="1"} 1
+linstor_gateway_volume_state{name="iqn.2019-08.com.linbit:example",state="Unknown",type="iscsi",volume="1"} 0
+`
+
+	assert.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected)))
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+)
+
+// NFSAddVolume adds a volume to a highly-available NFS export, including the
+// filesystem on it.
+func (s *server) NFSAddVolume() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		ctx := request.Context()
+
+		resource := mux.Vars(request)["resource"]
+
+		var vCfg nfs.VolumeConfig
+		decoder := json.NewDecoder(request.Body)
+		err := decoder.Decode(&vCfg)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "failed to parse request body: %v", err)
+			return
+		}
+
+		if vCfg.Number < 1 {
+			MustError(http.StatusBadRequest, writer, "volume number must be positive, is %d", vCfg.Number)
+			return
+		}
+
+		if vCfg.SizeKiB == 0 {
+			MustError(http.StatusBadRequest, writer, "missing volume size")
+			return
+		}
+
+		cfg, err := s.nfs.AddVolume(ctx, resource, &vCfg)
+		if errors.Is(err, common.ErrNotActiveHere) {
+			MustError(http.StatusConflict, writer, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, writer, "failed to add volume to resource: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, writer, "no resource found")
+			return
+		}
+
+		writer.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(writer).Encode(cfg.VolumeConfig(vCfg.Number))
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
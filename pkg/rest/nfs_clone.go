@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+)
+
+// NFSClone creates a new NFS export from a snapshot of an existing one.
+// The snapshot is selected via the "snapshot" query parameter; if it is not
+// given, a new snapshot is taken.
+func (s *server) NFSClone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		var rsc nfs.ResourceConfig
+		err := json.NewDecoder(r.Body).Decode(&rsc)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		snapshot := r.URL.Query().Get("snapshot")
+
+		result, err := s.nfs.Clone(r.Context(), resource, snapshot, &rsc)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for resource %s", snapshot, resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to clone nfs resource: %v", err)
+			return
+		}
+
+		if result == nil {
+			MustError(http.StatusNotFound, w, "no resource %s found", resource)
+			return
+		}
+
+		w.Header().Add("Location", fmt.Sprintf("../%s", result.Name))
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func (s *server) NFSMigrate() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		resource := mux.Vars(request)["resource"]
+
+		resourceTimeout, err := parseResourceTimeout(request)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "invalid resource_timeout: %v", err)
+			return
+		}
+
+		var migration common.Migration
+		err = json.NewDecoder(request.Body).Decode(&migration)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "failed to parse request body: %v", err)
+			return
+		}
+
+		if migration.Node == "" {
+			MustError(http.StatusBadRequest, writer, "missing node")
+			return
+		}
+
+		cfg, err := s.nfs.Migrate(request.Context(), resource, migration.Node, resourceTimeout)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, writer, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, writer, "failed to migrate export: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, writer, "no resource found")
+			return
+		}
+
+		writer.Header().Add("Location", "./")
+		writer.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(writer).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+)
+
+func (s *server) NFSPatch() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		resource := mux.Vars(request)["resource"]
+
+		var patch nfs.ResourcePatch
+		err := json.NewDecoder(request.Body).Decode(&patch)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "failed to parse request body: %v", err)
+			return
+		}
+
+		cfg, err := s.nfs.Patch(request.Context(), resource, &patch)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, writer, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, writer, "failed to update export: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, writer, "no resource found")
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(writer).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
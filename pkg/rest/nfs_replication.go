@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+)
+
+func (s *server) NFSGetReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		replication, err := s.nfs.Replication(r.Context(), resource)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource %s found", resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to get replication settings: %v", err)
+			return
+		}
+
+		if replication == nil {
+			MustError(http.StatusNotFound, w, "no replication set up for resource %s", resource)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(replication)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NFSSetReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		var replication common.Replication
+		err := json.NewDecoder(r.Body).Decode(&replication)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		err = s.nfs.SetReplication(r.Context(), resource, replication)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource %s found", resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to set up replication: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(replication)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NFSDeleteReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		err := s.nfs.DeleteReplication(r.Context(), resource)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource %s found", resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to delete replication: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(struct{}{})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NFSShipSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		snapshot, err := s.nfs.ShipSnapshot(r.Context(), resource)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource %s found", resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to ship snapshot: %v", err)
+			return
+		}
+
+		// shipping happens in the background
+		w.WriteHeader(http.StatusAccepted)
+		err = json.NewEncoder(w).Encode(common.Snapshot{Name: snapshot})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+// NFSPromoteDR brings up an NFS export from a replica that was shipped to
+// this cluster. The snapshot is selected via the "snapshot" query parameter;
+// if it is not given, the latest one is used.
+func (s *server) NFSPromoteDR() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		var rsc nfs.ResourceConfig
+		err := json.NewDecoder(r.Body).Decode(&rsc)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		snapshot := r.URL.Query().Get("snapshot")
+
+		result, err := s.nfs.PromoteDR(r.Context(), resource, snapshot, &rsc)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for resource %s", snapshot, resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to promote nfs resource: %v", err)
+			return
+		}
+
+		if result == nil {
+			MustError(http.StatusNotFound, w, "no replica of resource %s found", resource)
+			return
+		}
+
+		w.Header().Add("Location", fmt.Sprintf("../%s", result.Name))
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+)
+
+// NFSResizeVolume grows a volume of a highly-available NFS export, including the filesystem on it.
+func (s *server) NFSResizeVolume() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		ctx := request.Context()
+
+		resource := mux.Vars(request)["resource"]
+
+		id, err := strconv.Atoi(mux.Vars(request)["id"])
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "invalid volume number %q: %v", mux.Vars(request)["id"], err)
+			return
+		}
+
+		var vCfg nfs.VolumeConfig
+		decoder := json.NewDecoder(request.Body)
+		err = decoder.Decode(&vCfg)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "failed to parse request body: %v", err)
+			return
+		}
+
+		if vCfg.Number != 0 && vCfg.Number != id {
+			MustError(http.StatusBadRequest, writer, "expected volume number to be %d, but request body has %d", id, vCfg.Number)
+			return
+		}
+
+		if vCfg.SizeKiB == 0 {
+			MustError(http.StatusBadRequest, writer, "missing new volume size")
+			return
+		}
+
+		cfg, err := s.nfs.ResizeVolume(ctx, resource, id, vCfg.SizeKiB)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, writer, "%v", err)
+			return
+		}
+		if errors.Is(err, common.ErrVolumeNotFound) {
+			MustError(http.StatusNotFound, writer, "%v", err)
+			return
+		}
+		if errors.Is(err, common.ErrNotActiveHere) {
+			MustError(http.StatusConflict, writer, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, writer, "failed to resize volume: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, writer, "no resource found")
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(writer).Encode(cfg.VolumeConfig(id))
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
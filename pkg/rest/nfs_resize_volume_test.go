@@ -0,0 +1,176 @@
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiconsts "github.com/LINBIT/golinstor"
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	"github.com/icza/gog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+	"github.com/LINBIT/linstor-gateway/pkg/reactor"
+)
+
+// fakeNFSLinstor serves the given export from a fake LINSTOR controller,
+// started and active on node.
+func fakeNFSLinstor(t *testing.T, cfg *nfs.ResourceConfig, node string) *httptest.Server {
+	var deployed []client.Volume
+	var definitions []client.VolumeDefinition
+	for _, vol := range cfg.Volumes {
+		deployed = append(deployed, client.Volume{
+			VolumeNumber: int32(vol.Number),
+			DevicePath:   fmt.Sprintf("/dev/drbd%d", 1000+vol.Number),
+			State:        client.VolumeState{DiskState: "UpToDate"},
+		})
+		definitions = append(definitions, client.VolumeDefinition{
+			VolumeNumber: gog.Ptr(int32(vol.Number)),
+			SizeKib:      vol.SizeKiB,
+			Props: map[string]string{
+				apiconsts.NamespcFilesystem + "/Type":              vol.FileSystem,
+				apiconsts.NamespcFilesystem + apiconsts.KeyFsUser:  vol.FileSystemRootOwner.User,
+				apiconsts.NamespcFilesystem + apiconsts.KeyFsGroup: vol.FileSystemRootOwner.Group,
+			},
+		})
+	}
+	resources := []client.ResourceWithVolumes{{
+		Resource: client.Resource{
+			Name:     cfg.Name,
+			NodeName: node,
+			State:    &client.ResourceState{InUse: gog.Ptr(true)},
+		},
+		Volumes: deployed,
+	}}
+
+	promoter, err := cfg.ToPromoter(resources)
+	require.NoError(t, err)
+	content, err := reactor.EncodeConfig(promoter)
+	require.NoError(t, err)
+	path := reactor.ConfigPath(cfg.ID())
+
+	reply := func(w http.ResponseWriter, v interface{}) {
+		require.NoError(t, json.NewEncoder(w).Encode(v))
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/files":
+			reply(w, []map[string]string{{"path": path, "content": base64.StdEncoding.EncodeToString(content)}})
+		case "/v1/resource-definitions/" + cfg.Name:
+			reply(w, client.ResourceDefinition{
+				Name:              cfg.Name,
+				ResourceGroupName: cfg.ResourceGroup,
+				Props:             map[string]string{"files" + path: "True"},
+			})
+		case "/v1/resource-groups/" + cfg.ResourceGroup:
+			reply(w, client.ResourceGroup{Name: cfg.ResourceGroup})
+		case "/v1/resource-definitions/" + cfg.Name + "/volume-definitions":
+			reply(w, definitions)
+		case "/v1/view/resources":
+			reply(w, resources)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+}
+
+func TestNFSVolumeHandlers(t *testing.T) {
+	t.Parallel()
+
+	cfg := &nfs.ResourceConfig{
+		Name:          "example",
+		ServiceIP:     common.ServiceIPFromParts(net.IP{192, 168, 127, 1}, 24),
+		ResourceGroup: "rg1",
+		Volumes: []nfs.VolumeConfig{
+			{VolumeConfig: common.ClusterPrivateVolume()},
+			{
+				VolumeConfig: common.VolumeConfig{
+					Number:              1,
+					SizeKiB:             1024 * 1024,
+					FileSystem:          "ext4",
+					FileSystemRootOwner: common.UserGroup{User: "nobody", Group: "nobody"},
+				},
+				ExportPath: "/",
+			},
+		},
+		Implementation: nfs.ImplementationKernel,
+	}
+
+	// the export is active on another node than the one running the test
+	linstor := fakeNFSLinstor(t, cfg, "some-other-node")
+	t.Cleanup(linstor.Close)
+
+	n, err := nfs.New([]string{linstor.URL})
+	require.NoError(t, err)
+
+	s := &server{router: mux.NewRouter(), nfs: n}
+	s.router.HandleFunc("/api/v2/nfs/{resource}/volumes", s.NFSAddVolume()).Methods("POST")
+	s.router.HandleFunc("/api/v2/nfs/{resource}/{id}", s.NFSResizeVolume()).Methods("PUT")
+
+	testcases := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		expectedCode int
+		expectedErr  string
+	}{{
+		name:         "resize not on the active node",
+		method:       "PUT",
+		path:         "/api/v2/nfs/example/1",
+		body:         `{"size_kib":2097152}`,
+		expectedCode: http.StatusConflict,
+		expectedErr:  `the filesystem is mounted on node some-other-node`,
+	}, {
+		name:         "shrink",
+		method:       "PUT",
+		path:         "/api/v2/nfs/example/1",
+		body:         `{"size_kib":1024}`,
+		expectedCode: http.StatusBadRequest,
+		expectedErr:  `cannot shrink volume`,
+	}, {
+		name:         "resize unknown volume",
+		method:       "PUT",
+		path:         "/api/v2/nfs/example/2",
+		body:         `{"size_kib":2097152}`,
+		expectedCode: http.StatusNotFound,
+		expectedErr:  `volume not found`,
+	}, {
+		name:         "add not on the active node",
+		method:       "POST",
+		path:         "/api/v2/nfs/example/volumes",
+		body:         `{"number":2,"size_kib":1048576,"export_path":"/vol2"}`,
+		expectedCode: http.StatusConflict,
+		expectedErr:  `the resource is active on node some-other-node`,
+	}, {
+		name:         "add without volume number",
+		method:       "POST",
+		path:         "/api/v2/nfs/example/volumes",
+		body:         `{"size_kib":1048576}`,
+		expectedCode: http.StatusBadRequest,
+		expectedErr:  `volume number must be positive`,
+	}}
+
+	for i := range testcases {
+		tcase := &testcases[i]
+		t.Run(tcase.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(tcase.method, tcase.path, strings.NewReader(tcase.body)))
+			assert.Equal(t, tcase.expectedCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tcase.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func (s *server) NFSGetSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		schedule, err := s.nfs.SnapshotSchedule(r.Context(), resource)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource %s found", resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to get snapshot schedule: %v", err)
+			return
+		}
+
+		if schedule == nil {
+			MustError(http.StatusNotFound, w, "no snapshot schedule set for resource %s", resource)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(schedule)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NFSSetSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		var schedule common.SnapshotSchedule
+		err := json.NewDecoder(r.Body).Decode(&schedule)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		err = s.nfs.SetSnapshotSchedule(r.Context(), resource, schedule)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource %s found", resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to set snapshot schedule: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(schedule)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NFSDeleteSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		err := s.nfs.DeleteSnapshotSchedule(r.Context(), resource)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource %s found", resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to delete snapshot schedule: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(struct{}{})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func (s *server) NFSListSnapshots() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		snaps, err := s.nfs.ListSnapshots(r.Context(), resource)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource found")
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to list snapshots: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(snaps)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NFSCreateSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		var snap common.Snapshot
+		err := json.NewDecoder(r.Body).Decode(&snap)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		created, err := s.nfs.CreateSnapshot(r.Context(), resource, snap.Name)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource found")
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to create snapshot: %v", err)
+			return
+		}
+
+		w.Header().Add("Location", r.RequestURI+"/"+created.Name)
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(created)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NFSDeleteSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		snapshot := mux.Vars(r)["snapshot"]
+
+		err := s.nfs.DeleteSnapshot(r.Context(), resource, snapshot)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for resource %s", snapshot, resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to delete snapshot: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(struct{}{})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NFSRollbackSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource := mux.Vars(r)["resource"]
+
+		snapshot := mux.Vars(r)["snapshot"]
+
+		resourceTimeout, err := parseResourceTimeout(r)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid resource_timeout: %v", err)
+			return
+		}
+
+		cfg, err := s.nfs.RollbackSnapshot(r.Context(), resource, snapshot, resourceTimeout)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for resource %s", snapshot, resource)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to roll back snapshot: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, w, "no resource found")
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nfs"
+)
+
+func (s *server) NFSUpdate() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		resource := mux.Vars(request)["resource"]
+
+		dryRun, err := parseDryRun(request)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "invalid dry_run: %v", err)
+			return
+		}
+
+		var rsc nfs.ResourceConfig
+		err = json.NewDecoder(request.Body).Decode(&rsc)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "failed to parse request body: %v", err)
+			return
+		}
+
+		if rsc.Name != resource {
+			MustError(http.StatusBadRequest, writer, "name %s in request body does not match %s", rsc.Name, resource)
+			return
+		}
+
+		result, err := s.nfs.Update(request.Context(), &rsc, dryRun)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, writer, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, writer, "failed to update export: %v", err)
+			return
+		}
+
+		if result == nil {
+			MustError(http.StatusNotFound, writer, "no resource found")
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(writer).Encode(result)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
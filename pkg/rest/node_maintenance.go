@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+)
+
+func (s *server) NodeList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodes, err := s.maintenance.Nodes(r.Context())
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to list nodes: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(nodes)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NodeEvacuate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		node := mux.Vars(r)["node"]
+
+		resourceTimeout, err := parseResourceTimeout(r)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid resource_timeout: %v", err)
+			return
+		}
+
+		report, err := s.maintenance.Evacuate(r.Context(), node, resourceTimeout)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to evacuate node: %v", err)
+			return
+		}
+
+		if report == nil {
+			MustError(http.StatusNotFound, w, "no node %s found", node)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(report)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NodeResume() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		node := mux.Vars(r)["node"]
+
+		report, err := s.maintenance.Resume(r.Context(), node)
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to resume node: %v", err)
+			return
+		}
+
+		if report == nil {
+			MustError(http.StatusNotFound, w, "no node %s found", node)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(report)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+)
+
+// NVMeoFClone creates a new NVMe-oF target from a snapshot of an existing one.
+// The snapshot is selected via the "snapshot" query parameter; if it is not
+// given, a new snapshot is taken.
+func (s *server) NVMeoFClone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nqn, err := nvmeof.NewNqn(mux.Vars(r)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid nqn: %v", err)
+			return
+		}
+
+		var rsc nvmeof.ResourceConfig
+		err = json.NewDecoder(r.Body).Decode(&rsc)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		snapshot := r.URL.Query().Get("snapshot")
+
+		result, err := s.nvmeof.Clone(r.Context(), nqn, snapshot, &rsc)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for nqn %s", snapshot, nqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to clone nvmeof resource: %v", err)
+			return
+		}
+
+		if result == nil {
+			MustError(http.StatusNotFound, w, "no resource with nqn %s found", nqn)
+			return
+		}
+
+		result.Redact()
+
+		w.Header().Add("Location", fmt.Sprintf("../%s", result.NQN))
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -26,6 +26,8 @@ func (s *server) NVMeoFCreate() func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		result.Redact()
+
 		writer.Header().Add("Location", fmt.Sprintf("./nvme-of/%s", result.NQN))
 		writer.WriteHeader(http.StatusCreated)
 		encoder := json.NewEncoder(writer)
@@ -21,6 +21,12 @@ func (s *server) NVMeoFGet(all bool) func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		// secrets are only returned on explicit request
+		show, ok := showSecrets(writer, request)
+		if !ok {
+			return
+		}
+
 		cfg, err := s.nvmeof.Get(ctx, nqn)
 		if err != nil {
 			MustError(http.StatusInternalServerError, writer, "failed to fetch resource status: %v", err)
@@ -33,6 +39,10 @@ func (s *server) NVMeoFGet(all bool) func(http.ResponseWriter, *http.Request) {
 		}
 
 		if all {
+			if !show {
+				cfg.Redact()
+			}
+
 			writer.WriteHeader(http.StatusOK)
 			err = json.NewEncoder(writer).Encode(cfg)
 			if err != nil {
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+)
+
+func (s *server) NVMeoFSetHostKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nqn, err := nvmeof.NewNqn(mux.Vars(r)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid nqn: %v", err)
+			return
+		}
+
+		host, err := nvmeof.NewHostNqn(mux.Vars(r)["host"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid host nqn: %v", err)
+			return
+		}
+
+		var key nvmeof.HostKey
+		err = json.NewDecoder(r.Body).Decode(&key)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		cfg, err := s.nvmeof.SetHostKey(r.Context(), nqn, host, key)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to set host key: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, w, "no resource found for nqn %s", nqn)
+			return
+		}
+
+		cfg.Redact()
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NVMeoFDeleteHostKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nqn, err := nvmeof.NewNqn(mux.Vars(r)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid nqn: %v", err)
+			return
+		}
+
+		host, err := nvmeof.NewHostNqn(mux.Vars(r)["host"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid host nqn: %v", err)
+			return
+		}
+
+		cfg, err := s.nvmeof.DeleteHostKey(r.Context(), nqn, host)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to delete host key: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, w, "no resource found for nqn %s", nqn)
+			return
+		}
+
+		cfg.Redact()
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -20,6 +20,11 @@ func (s *server) NVMeoFList() func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		for i := range cfgs {
+			// secrets are only shown for single targets on explicit request
+			cfgs[i].Redact()
+		}
+
 		writer.WriteHeader(http.StatusOK)
 		enc := json.NewEncoder(writer)
 
@@ -0,0 +1,68 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+)
+
+func (s *server) NVMeoFMigrate() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		ctx := request.Context()
+
+		nqn, err := nvmeof.NewNqn(mux.Vars(request)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "malformed nqn: %v", err)
+			return
+		}
+
+		resourceTimeout, err := parseResourceTimeout(request)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "invalid resource_timeout: %v", err)
+			return
+		}
+
+		var migration common.Migration
+		err = json.NewDecoder(request.Body).Decode(&migration)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "failed to parse request body: %v", err)
+			return
+		}
+
+		if migration.Node == "" {
+			MustError(http.StatusBadRequest, writer, "missing node")
+			return
+		}
+
+		cfg, err := s.nvmeof.Migrate(ctx, nqn, migration.Node, resourceTimeout)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, writer, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, writer, "failed to migrate resource: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, writer, "no resource found for nqn %s", nqn)
+			return
+		}
+
+		cfg.Redact()
+
+		writer.Header().Add("Location", "./")
+		writer.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(writer).Encode(cfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/LINBIT/golinstor/client"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+)
+
+func (s *server) NVMeoFGetReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nqn, err := nvmeof.NewNqn(mux.Vars(r)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid nqn: %v", err)
+			return
+		}
+
+		replication, err := s.nvmeof.Replication(r.Context(), nqn)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with nqn %s found", nqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to get replication settings: %v", err)
+			return
+		}
+
+		if replication == nil {
+			MustError(http.StatusNotFound, w, "no replication set up for nqn %s", nqn)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(replication)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NVMeoFSetReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nqn, err := nvmeof.NewNqn(mux.Vars(r)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid nqn: %v", err)
+			return
+		}
+
+		var replication common.Replication
+		err = json.NewDecoder(r.Body).Decode(&replication)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		err = s.nvmeof.SetReplication(r.Context(), nqn, replication)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with nqn %s found", nqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to set up replication: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(replication)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NVMeoFDeleteReplication() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nqn, err := nvmeof.NewNqn(mux.Vars(r)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid nqn: %v", err)
+			return
+		}
+
+		err = s.nvmeof.DeleteReplication(r.Context(), nqn)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with nqn %s found", nqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to delete replication: %v", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(w).Encode(struct{}{})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+func (s *server) NVMeoFShipSnapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nqn, err := nvmeof.NewNqn(mux.Vars(r)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid nqn: %v", err)
+			return
+		}
+
+		snapshot, err := s.nvmeof.ShipSnapshot(r.Context(), nqn)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, w, "%v", err)
+			return
+		}
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no resource with nqn %s found", nqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, w, "failed to ship snapshot: %v", err)
+			return
+		}
+
+		// shipping happens in the background
+		w.WriteHeader(http.StatusAccepted)
+		err = json.NewEncoder(w).Encode(common.Snapshot{Name: snapshot})
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
+
+// NVMeoFPromoteDR brings up an NVMe-oF target from a replica that was shipped to
+// this cluster. The snapshot is selected via the "snapshot" query parameter;
+// if it is not given, the latest one is used.
+func (s *server) NVMeoFPromoteDR() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nqn, err := nvmeof.NewNqn(mux.Vars(r)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "invalid nqn: %v", err)
+			return
+		}
+
+		var rsc nvmeof.ResourceConfig
+		err = json.NewDecoder(r.Body).Decode(&rsc)
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to parse request body: %v", err)
+			return
+		}
+
+		snapshot := r.URL.Query().Get("snapshot")
+
+		result, err := s.nvmeof.PromoteDR(r.Context(), nqn, snapshot, &rsc)
+		if errors.Is(err, client.NotFoundError) {
+			MustError(http.StatusNotFound, w, "no snapshot %s found for nqn %s", snapshot, nqn)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusBadRequest, w, "failed to promote nvmeof resource: %v", err)
+			return
+		}
+
+		if result == nil {
+			MustError(http.StatusNotFound, w, "no replica of nqn %s found", nqn)
+			return
+		}
+
+		result.Redact()
+
+		w.Header().Add("Location", fmt.Sprintf("../%s", result.NQN))
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(result)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
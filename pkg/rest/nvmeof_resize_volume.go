@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/LINBIT/linstor-gateway/pkg/common"
+	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
+)
+
+func (s *server) NVMeoFResizeVolume() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		ctx := request.Context()
+
+		nqn, err := nvmeof.NewNqn(mux.Vars(request)["nqn"])
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "malformed nqn: %v", err)
+			return
+		}
+
+		nsid, err := strconv.Atoi(mux.Vars(request)["nsid"])
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "wrong namespace id format: %v", err)
+			return
+		}
+
+		var vCfg common.VolumeConfig
+		decoder := json.NewDecoder(request.Body)
+		err = decoder.Decode(&vCfg)
+		if err != nil {
+			MustError(http.StatusBadRequest, writer, "failed to parse request body: %v", err)
+			return
+		}
+
+		if vCfg.Number != 0 && vCfg.Number != nsid {
+			MustError(http.StatusBadRequest, writer, "expected volume number to be %d, but request body has %d", nsid, vCfg.Number)
+			return
+		}
+
+		if vCfg.SizeKiB == 0 {
+			MustError(http.StatusBadRequest, writer, "missing new volume size")
+			return
+		}
+
+		cfg, err := s.nvmeof.ResizeVolume(ctx, nqn, nsid, vCfg.SizeKiB)
+		var validationErr common.ValidationError
+		if errors.As(err, &validationErr) {
+			MustError(http.StatusBadRequest, writer, "%v", err)
+			return
+		}
+		if errors.Is(err, common.ErrVolumeNotFound) {
+			MustError(http.StatusNotFound, writer, "%v", err)
+			return
+		}
+		if errors.Is(err, common.ErrNotActiveHere) {
+			MustError(http.StatusConflict, writer, "%v", err)
+			return
+		}
+		if err != nil {
+			MustError(http.StatusInternalServerError, writer, "failed to resize volume: %v", err)
+			return
+		}
+
+		if cfg == nil {
+			MustError(http.StatusNotFound, writer, "no resource found for nqn %s", nqn)
+			return
+		}
+
+		volCfg := cfg.VolumeConfig(nsid)
+
+		writer.WriteHeader(http.StatusOK)
+		err = json.NewEncoder(writer).Encode(volCfg)
+		if err != nil {
+			log.WithError(err).Warn("failed to write response")
+		}
+	}
+}
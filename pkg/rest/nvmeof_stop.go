@@ -37,6 +37,8 @@ func (s *server) NVMeoFStop() func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		cfg.Redact()
+
 		writer.Header().Add("Location", "./")
 		writer.WriteHeader(http.StatusOK)
 		err = json.NewEncoder(writer).Encode(cfg)
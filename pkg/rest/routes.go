@@ -3,6 +3,9 @@ package rest
 import (
 	"net/http"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/LINBIT/linstor-gateway/pkg/metrics"
 	"github.com/LINBIT/linstor-gateway/pkg/version"
 )
 
@@ -16,6 +19,13 @@ func serverNameMiddleware(next http.Handler) http.Handler {
 
 func (s *server) routes() {
 	s.router.Use(serverNameMiddleware)
+	s.router.Use(metricsMiddleware)
+
+	var metricsHandler http.Handler = promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+	if s.auth != nil {
+		metricsHandler = s.auth.middleware(metricsHandler)
+	}
+	s.router.Handle("/metrics", metricsHandler).Methods("GET")
 
 	apiv2 := s.router.PathPrefix("/api/v2").Subrouter()
 	apiv2.Use(func(handler http.Handler) http.Handler {
@@ -24,41 +34,105 @@ func (s *server) routes() {
 			handler.ServeHTTP(w, r)
 		})
 	})
+	// the audit log comes first, so that it also records failed logins
+	apiv2.Use(s.auditMiddleware)
+	if s.auth != nil {
+		apiv2.Use(s.auth.middleware)
+	}
 
-	apiv2.HandleFunc("/status", s.APIStatus()).Methods("GET")
+	apiv2.HandleFunc("/status", withRole(RoleReadOnly, s.APIStatus())).Methods("GET")
+	apiv2.HandleFunc("/export", withRole(RoleAdmin, s.Export())).Methods("GET")
+	apiv2.HandleFunc("/audit", withRole(RoleAdmin, s.AuditList())).Methods("GET")
+	apiv2.HandleFunc("/events", withRole(RoleReadOnly, s.Events())).Methods("GET")
+	apiv2.HandleFunc("/nodes", withRole(RoleReadOnly, s.NodeList())).Methods("GET")
+	apiv2.HandleFunc("/nodes/{node}/evacuate", withRole(RoleAdmin, s.NodeEvacuate())).Methods("POST")
+	apiv2.HandleFunc("/nodes/{node}/resume", withRole(RoleAdmin, s.NodeResume())).Methods("POST")
 
 	iscsiv2 := apiv2.PathPrefix("/iscsi").Subrouter()
-	iscsiv2.HandleFunc("", s.ISCSIList()).Methods("GET")
-	iscsiv2.HandleFunc("", s.ISCSICreate()).Methods("POST")
-	iscsiv2.HandleFunc("/{iqn}", s.ISCSIGet(true)).Methods("GET")
-	iscsiv2.HandleFunc("/{iqn}", s.ISCSIDelete(true)).Methods("DELETE")
-	iscsiv2.HandleFunc("/{iqn}/start", s.ISCSIStart()).Methods("POST")
-	iscsiv2.HandleFunc("/{iqn}/stop", s.ISCSIStop()).Methods("POST")
-	iscsiv2.HandleFunc("/{iqn}/{lun}", s.ISCSIGet(false)).Methods("GET")
-	iscsiv2.HandleFunc("/{iqn}/{lun}", s.ISCSIAddVolume()).Methods("PUT")
-	iscsiv2.HandleFunc("/{iqn}/{lun}", s.ISCSIDelete(false)).Methods("DELETE")
+	iscsiv2.HandleFunc("", withRole(RoleReadOnly, s.ISCSIList())).Methods("GET")
+	iscsiv2.HandleFunc("", withRole(RoleOperator, s.ISCSICreate())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}", withRole(RoleReadOnly, s.ISCSIGet(true))).Methods("GET")
+	iscsiv2.HandleFunc("/{iqn}", withRole(RoleAdmin, s.ISCSIDelete(true))).Methods("DELETE")
+	iscsiv2.HandleFunc("/{iqn}", withRole(RoleOperator, s.ISCSIUpdate())).Methods("PUT")
+	iscsiv2.HandleFunc("/{iqn}", withRole(RoleOperator, s.ISCSIPatch())).Methods("PATCH")
+	iscsiv2.HandleFunc("/{iqn}/start", withRole(RoleOperator, s.ISCSIStart())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}/stop", withRole(RoleOperator, s.ISCSIStop())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}/migrate", withRole(RoleOperator, s.ISCSIMigrate())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}/clone", withRole(RoleOperator, s.ISCSIClone())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}/schedule", withRole(RoleReadOnly, s.ISCSIGetSchedule())).Methods("GET")
+	iscsiv2.HandleFunc("/{iqn}/schedule", withRole(RoleOperator, s.ISCSISetSchedule())).Methods("PUT")
+	iscsiv2.HandleFunc("/{iqn}/schedule", withRole(RoleOperator, s.ISCSIDeleteSchedule())).Methods("DELETE")
+	iscsiv2.HandleFunc("/{iqn}/replication", withRole(RoleReadOnly, s.ISCSIGetReplication())).Methods("GET")
+	iscsiv2.HandleFunc("/{iqn}/replication", withRole(RoleOperator, s.ISCSISetReplication())).Methods("PUT")
+	iscsiv2.HandleFunc("/{iqn}/replication", withRole(RoleOperator, s.ISCSIDeleteReplication())).Methods("DELETE")
+	iscsiv2.HandleFunc("/{iqn}/replication/ship", withRole(RoleOperator, s.ISCSIShipSnapshot())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}/promote-dr", withRole(RoleAdmin, s.ISCSIPromoteDR())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}/snapshots", withRole(RoleReadOnly, s.ISCSIListSnapshots())).Methods("GET")
+	iscsiv2.HandleFunc("/{iqn}/snapshots", withRole(RoleOperator, s.ISCSICreateSnapshot())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}/snapshots/{snapshot}", withRole(RoleOperator, s.ISCSIDeleteSnapshot())).Methods("DELETE")
+	iscsiv2.HandleFunc("/{iqn}/snapshots/{snapshot}/rollback", withRole(RoleOperator, s.ISCSIRollbackSnapshot())).Methods("POST")
+	iscsiv2.HandleFunc("/{iqn}/{lun}", withRole(RoleReadOnly, s.ISCSIGet(false))).Methods("GET")
+	iscsiv2.HandleFunc("/{iqn}/{lun}", withRole(RoleOperator, s.ISCSIAddVolume())).Methods("PUT")
+	iscsiv2.HandleFunc("/{iqn}/{lun}", withRole(RoleOperator, s.ISCSIResizeVolume())).Methods("PATCH")
+	iscsiv2.HandleFunc("/{iqn}/{lun}", withRole(RoleAdmin, s.ISCSIDelete(false))).Methods("DELETE")
 
 	nfsv2 := apiv2.PathPrefix("/nfs").Subrouter()
-	nfsv2.HandleFunc("", s.NFSList()).Methods("GET")
-	nfsv2.HandleFunc("", s.NFSCreate()).Methods("POST")
-	nfsv2.HandleFunc("/{resource}", s.NFSGet(true)).Methods("GET")
-	nfsv2.HandleFunc("/{resource}", s.NFSDelete(true)).Methods("DELETE")
-	nfsv2.HandleFunc("/{resource}/start", s.NFSStart()).Methods("POST")
-	nfsv2.HandleFunc("/{resource}/stop", s.NFSStop()).Methods("POST")
-	nfsv2.HandleFunc("/{resource}/{id}", s.NFSGet(false)).Methods("GET")
-	// No add volume: LINSTOR refuses to create a filesystem on volume that are added after the resource is deployed.
-	nfsv2.HandleFunc("/{resource}/{id}", s.NFSDelete(false)).Methods("DELETE")
+	nfsv2.HandleFunc("", withRole(RoleReadOnly, s.NFSList())).Methods("GET")
+	nfsv2.HandleFunc("", withRole(RoleOperator, s.NFSCreate())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}", withRole(RoleReadOnly, s.NFSGet(true))).Methods("GET")
+	nfsv2.HandleFunc("/{resource}", withRole(RoleAdmin, s.NFSDelete(true))).Methods("DELETE")
+	nfsv2.HandleFunc("/{resource}", withRole(RoleOperator, s.NFSUpdate())).Methods("PUT")
+	nfsv2.HandleFunc("/{resource}", withRole(RoleOperator, s.NFSPatch())).Methods("PATCH")
+	nfsv2.HandleFunc("/{resource}/start", withRole(RoleOperator, s.NFSStart())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/stop", withRole(RoleOperator, s.NFSStop())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/migrate", withRole(RoleOperator, s.NFSMigrate())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/clone", withRole(RoleOperator, s.NFSClone())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/volumes", withRole(RoleOperator, s.NFSAddVolume())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/schedule", withRole(RoleReadOnly, s.NFSGetSchedule())).Methods("GET")
+	nfsv2.HandleFunc("/{resource}/schedule", withRole(RoleOperator, s.NFSSetSchedule())).Methods("PUT")
+	nfsv2.HandleFunc("/{resource}/schedule", withRole(RoleOperator, s.NFSDeleteSchedule())).Methods("DELETE")
+	nfsv2.HandleFunc("/{resource}/replication", withRole(RoleReadOnly, s.NFSGetReplication())).Methods("GET")
+	nfsv2.HandleFunc("/{resource}/replication", withRole(RoleOperator, s.NFSSetReplication())).Methods("PUT")
+	nfsv2.HandleFunc("/{resource}/replication", withRole(RoleOperator, s.NFSDeleteReplication())).Methods("DELETE")
+	nfsv2.HandleFunc("/{resource}/replication/ship", withRole(RoleOperator, s.NFSShipSnapshot())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/promote-dr", withRole(RoleAdmin, s.NFSPromoteDR())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/snapshots", withRole(RoleReadOnly, s.NFSListSnapshots())).Methods("GET")
+	nfsv2.HandleFunc("/{resource}/snapshots", withRole(RoleOperator, s.NFSCreateSnapshot())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/snapshots/{snapshot}", withRole(RoleOperator, s.NFSDeleteSnapshot())).Methods("DELETE")
+	nfsv2.HandleFunc("/{resource}/snapshots/{snapshot}/rollback", withRole(RoleOperator, s.NFSRollbackSnapshot())).Methods("POST")
+	nfsv2.HandleFunc("/{resource}/{id}", withRole(RoleReadOnly, s.NFSGet(false))).Methods("GET")
+	nfsv2.HandleFunc("/{resource}/{id}", withRole(RoleOperator, s.NFSResizeVolume())).Methods("PUT")
+	nfsv2.HandleFunc("/{resource}/{id}", withRole(RoleAdmin, s.NFSDelete(false))).Methods("DELETE")
 
 	nvmeofv2 := apiv2.PathPrefix("/nvme-of").Subrouter()
-	nvmeofv2.HandleFunc("", s.NVMeoFList()).Methods("GET")
-	nvmeofv2.HandleFunc("", s.NVMeoFCreate()).Methods("POST")
-	nvmeofv2.HandleFunc("/{nqn}", s.NVMeoFGet(true)).Methods("GET")
-	nvmeofv2.HandleFunc("/{nqn}", s.NVMeoFDelete(true)).Methods("DELETE")
-	nvmeofv2.HandleFunc("/{nqn}/start", s.NVMeoFStart()).Methods("POST")
-	nvmeofv2.HandleFunc("/{nqn}/stop", s.NVMeoFStop()).Methods("POST")
-	nvmeofv2.HandleFunc("/{nqn}/{nsid}", s.NVMeoFGet(false)).Methods("GET")
-	nvmeofv2.HandleFunc("/{nqn}/{nsid}", s.NVMeoFAddVolume()).Methods("PUT")
-	nvmeofv2.HandleFunc("/{nqn}/{nsid}", s.NVMeoFDelete(false)).Methods("DELETE")
+	nvmeofv2.HandleFunc("", withRole(RoleReadOnly, s.NVMeoFList())).Methods("GET")
+	nvmeofv2.HandleFunc("", withRole(RoleOperator, s.NVMeoFCreate())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}", withRole(RoleReadOnly, s.NVMeoFGet(true))).Methods("GET")
+	nvmeofv2.HandleFunc("/{nqn}", withRole(RoleAdmin, s.NVMeoFDelete(true))).Methods("DELETE")
+	nvmeofv2.HandleFunc("/{nqn}", withRole(RoleOperator, s.NVMeoFUpdate())).Methods("PUT")
+	nvmeofv2.HandleFunc("/{nqn}/start", withRole(RoleOperator, s.NVMeoFStart())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}/stop", withRole(RoleOperator, s.NVMeoFStop())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}/migrate", withRole(RoleOperator, s.NVMeoFMigrate())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}/clone", withRole(RoleOperator, s.NVMeoFClone())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}/allowed-hosts", withRole(RoleOperator, s.NVMeoFSetAllowedHosts())).Methods("PUT")
+	nvmeofv2.HandleFunc("/{nqn}/host-keys/{host}", withRole(RoleOperator, s.NVMeoFSetHostKey())).Methods("PUT")
+	nvmeofv2.HandleFunc("/{nqn}/host-keys/{host}", withRole(RoleOperator, s.NVMeoFDeleteHostKey())).Methods("DELETE")
+	nvmeofv2.HandleFunc("/{nqn}/schedule", withRole(RoleReadOnly, s.NVMeoFGetSchedule())).Methods("GET")
+	nvmeofv2.HandleFunc("/{nqn}/schedule", withRole(RoleOperator, s.NVMeoFSetSchedule())).Methods("PUT")
+	nvmeofv2.HandleFunc("/{nqn}/schedule", withRole(RoleOperator, s.NVMeoFDeleteSchedule())).Methods("DELETE")
+	nvmeofv2.HandleFunc("/{nqn}/replication", withRole(RoleReadOnly, s.NVMeoFGetReplication())).Methods("GET")
+	nvmeofv2.HandleFunc("/{nqn}/replication", withRole(RoleOperator, s.NVMeoFSetReplication())).Methods("PUT")
+	nvmeofv2.HandleFunc("/{nqn}/replication", withRole(RoleOperator, s.NVMeoFDeleteReplication())).Methods("DELETE")
+	nvmeofv2.HandleFunc("/{nqn}/replication/ship", withRole(RoleOperator, s.NVMeoFShipSnapshot())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}/promote-dr", withRole(RoleAdmin, s.NVMeoFPromoteDR())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}/snapshots", withRole(RoleReadOnly, s.NVMeoFListSnapshots())).Methods("GET")
+	nvmeofv2.HandleFunc("/{nqn}/snapshots", withRole(RoleOperator, s.NVMeoFCreateSnapshot())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}/snapshots/{snapshot}", withRole(RoleOperator, s.NVMeoFDeleteSnapshot())).Methods("DELETE")
+	nvmeofv2.HandleFunc("/{nqn}/snapshots/{snapshot}/rollback", withRole(RoleOperator, s.NVMeoFRollbackSnapshot())).Methods("POST")
+	nvmeofv2.HandleFunc("/{nqn}/{nsid}", withRole(RoleReadOnly, s.NVMeoFGet(false))).Methods("GET")
+	nvmeofv2.HandleFunc("/{nqn}/{nsid}", withRole(RoleOperator, s.NVMeoFAddVolume())).Methods("PUT")
+	nvmeofv2.HandleFunc("/{nqn}/{nsid}", withRole(RoleOperator, s.NVMeoFResizeVolume())).Methods("PATCH")
+	nvmeofv2.HandleFunc("/{nqn}/{nsid}", withRole(RoleAdmin, s.NVMeoFDelete(false))).Methods("DELETE")
 
 	// gorilla/mux usually does not apply middlewares to the NotFoundHandler. To apply the serverNameMiddleware,
 	// overwrite the NotFoundHandler with a new route that has the middleware applied.
@@ -2,6 +2,7 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,7 +10,12 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/LINBIT/linstor-gateway/pkg/audit"
+	"github.com/LINBIT/linstor-gateway/pkg/events"
 	"github.com/LINBIT/linstor-gateway/pkg/iscsi"
+	"github.com/LINBIT/linstor-gateway/pkg/linstorcontrol"
+	"github.com/LINBIT/linstor-gateway/pkg/maintenance"
+	"github.com/LINBIT/linstor-gateway/pkg/metrics"
 	"github.com/LINBIT/linstor-gateway/pkg/nfs"
 	"github.com/LINBIT/linstor-gateway/pkg/nvmeof"
 
@@ -18,10 +24,16 @@ import (
 )
 
 type server struct {
-	router *mux.Router
-	iscsi  *iscsi.ISCSI
-	nfs    *nfs.NFS
-	nvmeof *nvmeof.NVMeoF
+	router  *mux.Router
+	iscsi   *iscsi.ISCSI
+	nfs     *nfs.NFS
+	nvmeof  *nvmeof.NVMeoF
+	linstor *linstorcontrol.Linstor
+
+	maintenance *maintenance.Maintenance
+	auth        *authenticator
+	audit       *audit.Log
+	events      *events.Watcher
 	sync.Mutex
 }
 
@@ -61,7 +73,7 @@ func MustError(code int, w http.ResponseWriter, format string, a ...interface{})
 }
 
 // ListenAndServe is the entry point for the REST API
-func ListenAndServe(addr string, controllers []string, allowedOrigins []string) {
+func ListenAndServe(addr string, controllers []string, allowedOrigins []string, authConfig AuthConfig, tlsConfig TLSConfig, auditConfig audit.Config) {
 	iscsi, err := iscsi.New(controllers)
 	if err != nil {
 		log.Fatalf("Failed to initialize ISCSI: %v", err)
@@ -74,25 +86,57 @@ func ListenAndServe(addr string, controllers []string, allowedOrigins []string)
 	if err != nil {
 		log.Fatalf("Failed to initialize NVMeoF: %v", err)
 	}
+	maintenance, err := maintenance.New(controllers)
+	if err != nil {
+		log.Fatalf("Failed to initialize maintenance: %v", err)
+	}
+	scheduler, err := linstorcontrol.Default(controllers)
+	if err != nil {
+		log.Fatalf("Failed to initialize scheduler: %v", err)
+	}
+	auth, err := newAuthenticator(authConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize authentication: %v", err)
+	}
+	if auth == nil {
+		log.Warn("No authentication configured, the REST API is open to everyone who can reach it")
+	}
+	auditLog, err := audit.New(auditConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit log: %v", err)
+	}
+	go scheduler.RunScheduler(context.Background())
+
 	s := &server{
-		router: mux.NewRouter(),
-		iscsi:  iscsi,
-		nfs:    nfs,
-		nvmeof: nvmeof,
+		router:  mux.NewRouter(),
+		iscsi:   iscsi,
+		nfs:     nfs,
+		nvmeof:  nvmeof,
+		linstor: scheduler,
+
+		maintenance: maintenance,
+		auth:        auth,
+		audit:       auditLog,
 	}
 
+	s.events = events.NewWatcher(s.resourceStatus, events.DefaultInterval)
+	go s.events.Run(context.Background())
+
 	s.routes()
+	metrics.Registry.MustRegister(resourceCollector{s})
 
 	opts := cors.Options{
 		AllowedMethods: []string{
 			http.MethodGet,
 			http.MethodPost,
 			http.MethodPut,
+			http.MethodPatch,
 			http.MethodDelete,
 		},
 		AllowedHeaders: []string{
 			"Origin",
 			"Content-Type",
+			"Authorization",
 			"Access-Control-Request-Private-Network",
 		},
 		AllowPrivateNetwork: true,
@@ -113,5 +157,24 @@ func ListenAndServe(addr string, controllers []string, allowedOrigins []string)
 	} else {
 		log.Debugf("CORS: No origins allowed")
 	}
-	log.Fatal(http.ListenAndServe(addr, c.Handler(s.router)))
+
+	if !tlsConfig.Enabled() {
+		if tlsConfig.Key != "" || tlsConfig.ClientCA != "" {
+			log.Fatal("tls_key and tls_client_ca require tls_cert to be set")
+		}
+		log.Warn("No TLS certificate configured, serving the REST API over plain HTTP")
+		log.Fatal(http.ListenAndServe(addr, c.Handler(s.router)))
+	}
+
+	serverTLS, err := tlsConfig.serverTLSConfig()
+	if err != nil {
+		log.Fatalf("Failed to initialize TLS: %v", err)
+	}
+
+	srv := &http.Server{
+		Addr:      addr,
+		Handler:   c.Handler(s.router),
+		TLSConfig: serverTLS,
+	}
+	log.Fatal(srv.ListenAndServeTLS("", ""))
 }
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSConfig holds the TLS settings of the "[server]" section of the config
+// file. If Cert is empty, the API is served over plain HTTP.
+type TLSConfig struct {
+	Cert string `mapstructure:"tls_cert"`
+	Key  string `mapstructure:"tls_key"`
+	// ClientCA is a PEM bundle of the certificate authorities that sign
+	// client certificates. If set, clients must present a certificate
+	// (mutual TLS).
+	ClientCA string `mapstructure:"tls_client_ca"`
+}
+
+// Enabled reports whether the API should be served over HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.Cert != ""
+}
+
+// serverTLSConfig loads the certificates and returns the configuration for
+// the HTTPS server.
+func (c TLSConfig) serverTLSConfig() (*tls.Config, error) {
+	if c.Cert == "" || c.Key == "" {
+		return nil, errors.New("tls_cert and tls_key must be set together")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.ClientCA != "" {
+		pool, err := LoadCertPool(c.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client CA: %w", err)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return cfg, nil
+}
+
+// LoadCertPool reads a PEM bundle of certificates.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,137 @@
+package rest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert creates a certificate for cn, signed by parent (or self-signed if
+// parent is nil), and writes it and its key as PEM files to dir.
+func writeCert(t *testing.T, dir, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, cn+".crt")
+	keyFile := filepath.Join(dir, cn+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return cert, key, certFile, keyFile
+}
+
+func TestMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca, caKey, caFile, _ := writeCert(t, dir, "ca", nil, nil)
+	_, _, serverCert, serverKey := writeCert(t, dir, "server", ca, caKey)
+	_, _, adminCert, adminKey := writeCert(t, dir, "admin", ca, caKey)
+	_, _, otherCert, otherKey := writeCert(t, dir, "other", ca, caKey)
+
+	serverTLS, err := TLSConfig{Cert: serverCert, Key: serverKey, ClientCA: caFile}.serverTLSConfig()
+	require.NoError(t, err)
+
+	auth, err := newAuthenticator(AuthConfig{ClientCertRoles: map[string]string{"admin": "admin"}})
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(auth.middleware(withRole(RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	srv.TLS = serverTLS
+	srv.StartTLS()
+	defer srv.Close()
+
+	pool, err := LoadCertPool(caFile)
+	require.NoError(t, err)
+
+	get := func(certFile, keyFile string) (int, error) {
+		cfg := &tls.Config{RootCAs: pool}
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			require.NoError(t, err)
+			cfg.Certificates = []tls.Certificate{cert}
+		}
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	code, err := get(adminCert, adminKey)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, err = get(otherCert, otherKey)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	_, err = get("", "")
+	assert.Error(t, err, "connection without client certificate must fail")
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca, caKey, _, caKeyFile := writeCert(t, dir, "ca", nil, nil)
+	_, _, serverCert, serverKey := writeCert(t, dir, "server", ca, caKey)
+
+	cfg, err := TLSConfig{Cert: serverCert, Key: serverKey}.serverTLSConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+
+	_, err = TLSConfig{Cert: serverCert}.serverTLSConfig()
+	assert.Error(t, err)
+
+	_, err = TLSConfig{Cert: serverCert, Key: caKeyFile}.serverTLSConfig()
+	assert.Error(t, err, "key does not match certificate")
+
+	_, err = TLSConfig{Cert: serverCert, Key: serverKey, ClientCA: serverKey}.serverTLSConfig()
+	assert.Error(t, err, "client CA is not a certificate")
+}
@@ -13,6 +13,35 @@ import (
 // index "n" migrates from version "n" to version "n+1".
 var nvmeOfMigrations = []func(cfg *reactor.PromoterConfig) error{
 	0: removeID,
+	1: numberServiceIPAgents,
+}
+
+// numberServiceIPAgents renames the agents that exist once per service IP
+// (portblock, IPaddr2, nvmet-port and portunblock) so that their names end
+// in the index of the service IP, the way it is done for iSCSI. Version 1
+// only supported a single service IP and used fixed names for these agents.
+func numberServiceIPAgents(cfg *reactor.PromoterConfig) error {
+	renames := map[string]string{
+		"portblock":   "pblock0",
+		"service_ip":  "service_ip0",
+		"port":        "port0",
+		"portunblock": "portunblock0",
+	}
+
+	id := firstResourceId(cfg)
+	firstResource := cfg.Resources[id]
+	for _, entry := range firstResource.Start {
+		agent, ok := entry.(*reactor.ResourceAgent)
+		if !ok {
+			continue
+		}
+
+		if newName, ok := renames[agent.Name]; ok {
+			agent.Name = newName
+		}
+	}
+	cfg.Resources[id] = firstResource
+	return nil
 }
 
 func upgradeNvmeOf(ctx context.Context, linstor *client.Client, name string, forceYes, dryRun bool) (bool, error) {
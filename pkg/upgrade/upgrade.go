@@ -10,23 +10,9 @@ import (
 	"github.com/LINBIT/golinstor/client"
 	"github.com/LINBIT/linstor-gateway/pkg/prompt"
 	"github.com/LINBIT/linstor-gateway/pkg/reactor"
-	"github.com/google/go-cmp/cmp"
 	"github.com/pelletier/go-toml"
-	"github.com/sergi/go-diff/diffmatchpatch"
-	"strings"
 )
 
-func encode(cfg *reactor.PromoterConfig) (string, error) {
-	buffer := strings.Builder{}
-	encoder := toml.NewEncoder(&buffer).ArraysWithOneElementPerLine(true)
-
-	err := encoder.Encode(&reactor.Config{Promoter: []reactor.PromoterConfig{*cfg}})
-	if err != nil {
-		return "", fmt.Errorf("error encoding toml: %w", err)
-	}
-	return buffer.String(), nil
-}
-
 func parseExistingConfig(ctx context.Context, linstor *client.Client, path string) (*reactor.PromoterConfig, *client.ResourceDefinition, []client.VolumeDefinition, []client.ResourceWithVolumes, error) {
 	file, err := linstor.Controller.GetExternalFile(ctx, path)
 	if err != nil {
@@ -48,22 +34,16 @@ func parseExistingConfig(ctx context.Context, linstor *client.Client, path strin
 }
 
 func maybeWriteNewConfig(ctx context.Context, linstor *client.Client, oldConfig *reactor.PromoterConfig, newConfig *reactor.PromoterConfig, id string, forceYes, dryRun bool) (bool, error) {
-	if cmp.Equal(oldConfig, newConfig) {
-		// nothing to do
-		return false, nil
-	}
-	oldToml, err := encode(oldConfig)
+	diff, err := reactor.Diff(oldConfig, newConfig)
 	if err != nil {
-		return false, fmt.Errorf("failed to encode old promoter config: %w", err)
+		return false, err
 	}
-	newToml, err := encode(newConfig)
-	if err != nil {
-		return false, fmt.Errorf("failed to marshal new config to toml: %w", err)
+	if diff == "" {
+		// nothing to do
+		return false, nil
 	}
 	fmt.Println("The following configuration changes are necessary:")
-	dmp := diffmatchpatch.New()
-	diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(oldToml, newToml, false))
-	fmt.Println(dmp.DiffPrettyText(diffs))
+	fmt.Println(prompt.ColorDiff(diff))
 	fmt.Println()
 	if dryRun {
 		return true, nil